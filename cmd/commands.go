@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// commandDeps holds the dependencies shared by CLI commands
+type commandDeps struct {
+	Config         *viper.Viper
+	Log            *zap.Logger
+	DB             *gorm.DB
+	UserRepository domain.UserRepository
+	Validate       *validator.Validate
+}
+
+// runCommand executes the named CLI command instead of starting the servers
+func runCommand(name string, args []string, deps *commandDeps) error {
+	switch name {
+	case "import-users":
+		return runImportUsers(args, deps)
+	case "reencrypt-users":
+		return runReencryptUsers(args, deps)
+	case "seed":
+		return runSeed(args, deps)
+	default:
+		return fmt.Errorf("unknown command: %s", name)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"app-hexagonal/internal/usecase"
+
+	"go.uber.org/zap"
+)
+
+// runImportUsers imports users from a CSV or NDJSON file and prints the report as JSON.
+//
+//	app-hexagonal import-users [-format csv|ndjson] [-errors-only] <file>
+func runImportUsers(args []string, deps *commandDeps) error {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	formatFlag := flags.String("format", "", "input format: csv or ndjson (defaults to the file extension)")
+	errorsOnly := flags.Bool("errors-only", false, "only include skipped and failed rows in the report")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import-users [-format csv|ndjson] [-errors-only] <file>")
+	}
+	path := flags.Arg(0)
+
+	formatValue := *formatFlag
+	if formatValue == "" {
+		formatValue = filepath.Ext(path)
+	}
+	format, err := usecase.ParseImportFormat(formatValue)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	importUsecase := usecase.NewUserImportUsecase(
+		deps.UserRepository,
+		deps.Validate,
+		deps.Config.GetInt("BATCH_PROCESSING_SIZE"),
+	)
+
+	deps.Log.Info("Importing users", zap.String("file", path), zap.String("format", string(format)))
+
+	report, importErr := importUsecase.Import(ctx, format, file)
+	if report != nil {
+		deps.Log.Info("User import finished",
+			zap.Int("created", report.Created),
+			zap.Int("skipped", report.Skipped),
+			zap.Int("failed", report.Failed),
+		)
+
+		if *errorsOnly {
+			rows := report.Rows[:0]
+			for _, row := range report.Rows {
+				if row.Error != "" {
+					rows = append(rows, row)
+				}
+			}
+			report.Rows = rows
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	}
+
+	return importErr
+}
@@ -4,11 +4,13 @@ import (
 	"app-hexagonal/config"
 	"app-hexagonal/internal/application"
 	"app-hexagonal/internal/delivery/grpc"
-	"app-hexagonal/internal/repository"
 	"app-hexagonal/internal/usecase"
 	"fmt"
+	"os"
 
+	"github.com/streadway/amqp"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // @title SuperFood Panel Service API
@@ -40,23 +42,66 @@ func main() {
 	}
 	defer log.Sync()
 
-	db, err := config.NewGormDB(cfg, log)
+	// Migrations manage the schema the application depends on, so they run
+	// before it connects and possibly migrates on boot
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], &commandDeps{Config: cfg, Log: log}); err != nil {
+			log.Fatal("Command failed", zap.String("command", "migrate"), zap.Error(err))
+		}
+		return
+	}
+
+	// The in-memory repository adapter runs without a database
+	var db *gorm.DB
+	if config.UsesDatabase(cfg) {
+		db, err = config.NewGormDB(cfg, log)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	userRepository, err := config.NewUserRepository(cfg, db, log)
 	if err != nil {
 		panic(err)
 	}
 
 	validate := config.NewValidator(cfg)
 
-	// Initialize RabbitMQ connection
-	rabbitMq, err := config.NewRabbitMQConnection(cfg, log)
+	// Run a CLI command instead of the servers when one is given
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1], os.Args[2:], &commandDeps{
+			Config:         cfg,
+			Log:            log,
+			DB:             db,
+			UserRepository: userRepository,
+			Validate:       validate,
+		})
+		if err != nil {
+			log.Fatal("Command failed", zap.String("command", os.Args[1]), zap.Error(err))
+		}
+		return
+	}
+
+	// Initialize RabbitMQ connection; it is optional so the service can run standalone
+	var rabbitMq *amqp.Connection
+	if cfg.GetString("RABBITMQ_URL") != "" {
+		rabbitMq, err = config.NewRabbitMQConnection(cfg, log)
+		if err != nil {
+			log.Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+		}
+	} else {
+		log.Warn("RABBITMQ_URL is not set, running without RabbitMQ")
+	}
+
+	// Domain events are written to the outbox and published by the relay
+	events, err := config.NewEventOptions(cfg, db)
 	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+		log.Fatal("Failed to initialize the event outbox", zap.Error(err))
 	}
 
-	// Create repository and usecase
-	userRepository := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepository)
-	authUsecase := usecase.NewAuthUsecase(userRepository)
+	// Create usecases
+	userUsecase := usecase.NewUserUsecase(userRepository, events, config.NewUserOptions(cfg))
+	authUsecase := usecase.NewAuthUsecase(userRepository, events)
 
 	// Create application services
 	userService := application.NewUserService(userUsecase)
@@ -71,17 +116,25 @@ func main() {
 	}()
 	defer grpcServer.Stop()
 
+	objectStorage, err := config.NewStorage(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to initialize storage", zap.Error(err))
+	}
+
 	// Set up Fiber app
 	app := config.NewFiberConfig(cfg)
 
 	config.Boostrap(&config.BoostrapConfig{
-		DB:          db,
-		App:         app,
-		Log:         log,
-		Validate:    validate,
-		Config:      cfg,
-		RabbitMQ:    rabbitMq,
-		UserUsecase: userUsecase,
+		DB:             db,
+		App:            app,
+		Log:            log,
+		Validate:       validate,
+		Config:         cfg,
+		RabbitMQ:       rabbitMq,
+		UserUsecase:    userUsecase,
+		Storage:        objectStorage,
+		UserRepository: userRepository,
+		Events:         events,
 	})
 
 	appPort := cfg.GetInt("APP_PORT")
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"app-hexagonal/config"
+	"app-hexagonal/database/migrations"
+	"app-hexagonal/pkg/postgres"
+
+	"github.com/golang-migrate/migrate/v4"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: migrate up | down [N] | goto VERSION | status | force VERSION | create [-dir DIR] NAME | tenants up | tenants status | tenants provision TENANT"
+
+// migrationName is the form of names accepted by migrate create
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// runMigrate manages the schema with the migrations embedded for
+// DATABASE_DRIVER. It runs before the application connects, so it also works
+// on a database the application could not yet use.
+//
+//	app-hexagonal migrate up
+//	app-hexagonal migrate down [N]         (N defaults to 1)
+//	app-hexagonal migrate goto VERSION
+//	app-hexagonal migrate status
+//	app-hexagonal migrate force VERSION    (clears a dirty version after a manual fix)
+//	app-hexagonal migrate create [-dir database/migrations] NAME
+//	app-hexagonal migrate tenants up|status (with DATABASE_TENANCY=schema)
+//	app-hexagonal migrate tenants provision TENANT
+//
+// The other commands manage the shared schema.
+func runMigrate(args []string, deps *commandDeps) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "create":
+		return runMigrateCreate(args[1:], deps)
+	case "tenants":
+		return runMigrateTenants(args[1:], deps)
+	}
+
+	dbConfig := config.NewDatabaseConfig(deps.Config)
+	m, err := config.NewMigrate(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch command, rest := args[0], args[1:]; command {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(rest) > 0 {
+			if steps, err = strconv.Atoi(rest[0]); err != nil || steps <= 0 {
+				return errors.New("down takes a positive number of migrations")
+			}
+		}
+		err = m.Steps(-steps)
+	case "goto":
+		version, parseErr := migrationVersion(rest)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Migrate(uint(version))
+	case "force":
+		version, parseErr := migrationVersion(rest)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Force(version)
+	case "status":
+		return printMigrationStatus(m, dbConfig.Driver)
+	default:
+		return errors.New(migrateUsage)
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		version, err = 0, nil
+	}
+	deps.Log.Info("Migrations finished",
+		zap.String("command", args[0]),
+		zap.Uint("version", version),
+		zap.Bool("dirty", dirty),
+	)
+	return err
+}
+
+// migrationVersion parses the single version argument of goto and force
+func migrationVersion(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New(migrateUsage)
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid migration version %q", args[0])
+	}
+	return version, nil
+}
+
+// printMigrationStatus lists the embedded migrations and whether each is applied
+func printMigrationStatus(m *migrate.Migrate, driver string) error {
+	current, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		current, err = 0, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	source, err := migrations.Source(driver)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	fmt.Printf("database version %d", current)
+	if dirty {
+		fmt.Print(" (dirty, fix the schema and run migrate force)")
+	}
+	fmt.Println()
+
+	version, err := source.First()
+	for err == nil {
+		state := "pending"
+		switch {
+		case version == current && dirty:
+			state = "dirty"
+		case version <= current:
+			state = "applied"
+		}
+		_, name, readErr := source.ReadUp(version)
+		if readErr != nil {
+			return readErr
+		}
+		fmt.Printf("%-8s %06d_%s\n", state, version, name)
+		version, err = source.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// runMigrateTenants migrates, inspects or provisions tenant schemas and prints
+// the state of each tenant. Every tenant is attempted even when one fails.
+func runMigrateTenants(args []string, deps *commandDeps) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dbConfig := config.NewDatabaseConfig(deps.Config)
+	if config.NewTenancy(dbConfig) == nil {
+		return errors.New("migrate tenants needs DATABASE_TENANCY=schema")
+	}
+	dbConfig.LogEnabled = false
+	db, err := config.NewDatabase(dbConfig, nil)
+	if err != nil {
+		return err
+	}
+	tenancy, _ := postgres.Tenancy(db)
+	defer tenancy.Close()
+
+	ctx := context.Background()
+	var statuses []postgres.TenantStatus
+	switch command, rest := args[0], args[1:]; command {
+	case "up":
+		statuses, err = tenancy.MigrateTenants(ctx)
+	case "status":
+		statuses, err = tenancy.TenantStatuses(ctx)
+	case "provision":
+		if len(rest) != 1 {
+			return errors.New(migrateUsage)
+		}
+		var status postgres.TenantStatus
+		status, _ = tenancy.Provision(ctx, rest[0])
+		statuses = []postgres.TenantStatus{status}
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+	return printTenantStatus(statuses)
+}
+
+// printTenantStatus prints a line per tenant and fails when any tenant failed
+func printTenantStatus(statuses []postgres.TenantStatus) error {
+	failed := 0
+	fmt.Printf("%-24s %-32s %8s %8s  %s\n", "TENANT", "SCHEMA", "VERSION", "PENDING", "STATE")
+	for _, status := range statuses {
+		state := "ok"
+		switch {
+		case status.Err != nil:
+			failed++
+			state = "failed: " + status.Err.Error()
+		case status.Dirty:
+			state = "dirty"
+		case status.Pending > 0:
+			state = "pending"
+		}
+		fmt.Printf("%-24s %-32s %8d %8d  %s\n", status.Tenant, status.Schema, status.Version, status.Pending, state)
+	}
+	fmt.Printf("%d tenants, %d failed\n", len(statuses), failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tenants failed", failed, len(statuses))
+	}
+	return nil
+}
+
+// runMigrateCreate adds an empty up and down migration for every dialect,
+// numbered after the highest existing version
+func runMigrateCreate(args []string, deps *commandDeps) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", "database/migrations", "directory holding a subdirectory per dialect")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || !migrationName.MatchString(flags.Arg(0)) {
+		return errors.New("usage: migrate create [-dir DIR] NAME, where NAME is lower case letters, digits and underscores")
+	}
+
+	next, err := nextMigrationVersion(*dir)
+	if err != nil {
+		return err
+	}
+
+	for _, dialect := range migrations.Dialects {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(*dir, dialect, fmt.Sprintf("%06d_%s.%s.sql", next, flags.Arg(0), direction))
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return err
+			}
+			file.Close()
+			deps.Log.Info("Created migration", zap.String("file", path))
+		}
+	}
+	return nil
+}
+
+// nextMigrationVersion returns one more than the highest version in any dialect directory
+func nextMigrationVersion(dir string) (uint, error) {
+	var highest uint
+	for _, dialect := range migrations.Dialects {
+		paths, err := filepath.Glob(filepath.Join(dir, dialect, "*.sql"))
+		if err != nil {
+			return 0, err
+		}
+		for _, path := range paths {
+			var version uint
+			if _, err := fmt.Sscanf(filepath.Base(path), "%d_", &version); err == nil && version > highest {
+				highest = version
+			}
+		}
+	}
+	return highest + 1, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+
+	"app-hexagonal/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// runReencryptUsers encrypts users stored as plaintext or under a retired key
+// with the current key. Run it after enabling encryption or after changing the
+// current key in the key file; it is safe to run while the servers are up.
+//
+//	app-hexagonal reencrypt-users
+func runReencryptUsers(args []string, deps *commandDeps) error {
+	flags := flag.NewFlagSet("reencrypt-users", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	encrypted, ok := deps.UserRepository.(*repository.EncryptedUserRepository)
+	if !ok {
+		return errors.New("field encryption is disabled, set ENCRYPTION_KEY_FILE")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	deps.Log.Info("Re-encrypting users")
+
+	rewritten, err := encrypted.Reencrypt(ctx)
+	deps.Log.Info("User re-encryption finished", zap.Int("rewritten", rewritten))
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+
+	"app-hexagonal/config"
+	"app-hexagonal/database/seed"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/pkg/seeder"
+
+	"go.uber.org/zap"
+)
+
+// runSeed runs the seeders registered for APP_ENV. Seeders are idempotent, so
+// running it again only adds what is missing; -fresh empties their tables first.
+//
+//	app-hexagonal seed [-fresh [-force]] [-only admin-user,demo-users] [-seed N] [-env ENV]
+func runSeed(args []string, deps *commandDeps) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fresh := flags.Bool("fresh", false, "empty the seeded tables before seeding")
+	force := flags.Bool("force", false, "allow -fresh in production")
+	only := flags.String("only", "", "comma separated seeders to run instead of all")
+	randomSeed := flags.Uint64("seed", deps.Config.GetUint64("SEED_RANDOM_SEED"), "seed of the fake data generator")
+	env := flags.String("env", deps.Config.GetString("APP_ENV"), "environment selecting the seeders")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if deps.DB == nil {
+		return errors.New("seeding needs a database, set REPOSITORY_ADAPTER=gorm")
+	}
+	if *fresh && *env == "production" && !*force {
+		return errors.New("refusing to empty production tables without -force")
+	}
+
+	options := seeder.Options{Environment: *env, Fresh: *fresh, Seed: *randomSeed}
+	if *only != "" {
+		options.Only = strings.Split(*only, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Seeders write around the user cache, as -fresh empties tables with SQL,
+	// so users cached before a fresh run are evicted once it is done
+	users, err := config.NewUncachedUserRepository(deps.Config, deps.DB, deps.Log)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	if *fresh {
+		err := users.Iterate(ctx, domain.UserFilter{}, func(user *domain.User) error {
+			stale = append(stale, user.ID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	registry := seed.NewRegistry(users, seed.Options{
+		AdminEmail:    deps.Config.GetString("SEED_ADMIN_EMAIL"),
+		AdminPassword: deps.Config.GetString("SEED_ADMIN_PASSWORD"),
+		DemoUsers:     deps.Config.GetInt("SEED_DEMO_USERS"),
+	})
+
+	deps.Log.Info("Seeding database", zap.String("env", *env), zap.Bool("fresh", *fresh))
+
+	ran, err := registry.Run(ctx, deps.DB, options)
+	if len(stale) > 0 {
+		userCache, cacheErr := config.NewCache(deps.Config, deps.Log)
+		if cacheErr != nil {
+			return errors.Join(err, cacheErr)
+		}
+		if userCache != nil {
+			repository.EvictCachedUsers(ctx, userCache, stale...)
+		}
+	}
+	deps.Log.Info("Seeding finished", zap.Strings("seeders", ran))
+	return err
+}
@@ -3,9 +3,12 @@ package config
 import (
 	"app-hexagonal/internal/delivery/http"
 	"app-hexagonal/internal/delivery/http/route"
+	"app-hexagonal/internal/domain"
 	"app-hexagonal/internal/repository"
 	"app-hexagonal/internal/resilience"
+	"app-hexagonal/internal/storage"
 	"app-hexagonal/internal/usecase"
+	gormpkg "app-hexagonal/pkg/gorm"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,18 +19,27 @@ import (
 )
 
 type BoostrapConfig struct {
-	DB          *gorm.DB
-	App         *fiber.App
-	Log         *zap.Logger
-	Validate    *validator.Validate
-	Config      *viper.Viper
-	RabbitMQ    *amqp.Connection
-	UserUsecase usecase.UserUsecaseInterface
+	DB             *gorm.DB
+	App            *fiber.App
+	Log            *zap.Logger
+	Validate       *validator.Validate
+	Config         *viper.Viper
+	RabbitMQ       *amqp.Connection
+	UserUsecase    usecase.UserUsecaseInterface
+	Storage        domain.Storage
+	UserRepository domain.UserRepository
+	// Events is where the usecases record domain events for the outbox relay
+	Events usecase.EventOptions
 }
 
 func Boostrap(config *BoostrapConfig) {
 	// Repository
-	userRepository := repository.NewUserRepository(config.DB)
+	var userRepository domain.UserRepository
+	if config.UserRepository != nil {
+		userRepository = config.UserRepository
+	} else {
+		userRepository = repository.NewUserRepository(config.DB)
+	}
 	// No need for authRepository since we're using JWT
 
 	// UseCase
@@ -35,11 +47,11 @@ func Boostrap(config *BoostrapConfig) {
 	if config.UserUsecase != nil {
 		userUseCase = config.UserUsecase
 	} else {
-		userUseCase = usecase.NewUserUsecase(userRepository)
+		userUseCase = usecase.NewUserUsecase(userRepository, config.Events, NewUserOptions(config.Config))
 	}
 
 	// Create auth usecase (no need for auth repository with JWT)
-	authUseCase := usecase.NewAuthUsecase(userRepository)
+	authUseCase := usecase.NewAuthUsecase(userRepository, config.Events)
 
 	// Resilience Handler
 	resilienceConfig := resilience.DefaultResilienceConfig()
@@ -49,11 +61,94 @@ func Boostrap(config *BoostrapConfig) {
 	userHandler := http.NewUserHandler(userUseCase, config.Log, resilienceHandler)
 	authHandler := http.NewAuthHandler(authUseCase, config.Log, resilienceHandler)
 
+	userImportUseCase := usecase.NewUserImportUsecase(userRepository, config.Validate, config.Config.GetInt("BATCH_PROCESSING_SIZE"))
+	userImportHandler := http.NewUserImportHandler(userImportUseCase, config.Log, config.Config.GetInt64("IMPORT_MAX_SIZE"))
+
+	userExportUseCase := usecase.NewUserExportUsecase(userRepository, usecase.ExportOptions{
+		Dir:       config.Config.GetString("EXPORT_DIR"),
+		Retention: config.Config.GetDuration("EXPORT_RETENTION"),
+	})
+	userExportHandler := http.NewUserExportHandler(userExportUseCase, config.Log)
+
+	userPreferencesUseCase := usecase.NewUserPreferencesUsecase(userRepository, usecase.PreferencesOptions{
+		MaxSize: config.Config.GetInt("USER_PREFERENCES_MAX_SIZE"),
+	})
+	userPreferencesHandler := http.NewUserPreferencesHandler(userPreferencesUseCase, config.Log)
+
+	privacyRepository, err := NewPrivacyRepository(config.Config, config.DB)
+	if err != nil {
+		config.Log.Fatal("Failed to initialize privacy repository", zap.Error(err))
+	}
+	privacyUseCase := usecase.NewPrivacyUsecase(userRepository, privacyRepository, usecase.PrivacyOptions{
+		ErasureGracePeriod: config.Config.GetDuration("PRIVACY_ERASURE_GRACE_PERIOD"),
+		Tenants:            NewTenantLister(config.Config, config.DB),
+	})
+
+	var userAvatarHandler *http.UserAvatarHandler
+	var fileHandler *http.FileHandler
+	if config.Storage != nil {
+		userAvatarUseCase := usecase.NewUserAvatarUsecase(userRepository, config.Storage, usecase.AvatarOptions{
+			MaxSize:       config.Config.GetInt64("AVATAR_MAX_SIZE"),
+			MaxDimension:  config.Config.GetInt("AVATAR_MAX_DIMENSION"),
+			ThumbnailSize: config.Config.GetInt("AVATAR_THUMBNAIL_SIZE"),
+			URLTTL:        config.Config.GetDuration("STORAGE_SIGNED_URL_TTL"),
+			OnOrphan: func(key string, err error) {
+				config.Log.Warn("Failed to delete unreferenced avatar image", zap.String("key", key), zap.Error(err))
+			},
+		})
+		userAvatarHandler = http.NewUserAvatarHandler(userAvatarUseCase, config.Log)
+		privacyUseCase.RegisterContributor(userAvatarUseCase.PersonalDataContributor())
+
+		// Local signed URLs are served by the application itself
+		if localStorage, ok := config.Storage.(*storage.LocalStorage); ok {
+			fileHandler = http.NewFileHandler(localStorage, config.Log)
+		}
+	}
+
+	// The audit trail is written by the GORM plugin, so it needs the database.
+	// Its contributor comes last to also erase the entries of the changes
+	// other contributors make to the user.
+	var auditHandler *http.AuditHandler
+	if config.DB != nil {
+		auditUseCase := usecase.NewAuditUsecase(repository.NewAuditRepository(config.DB))
+		auditHandler = http.NewAuditHandler(auditUseCase, config.Log)
+		privacyUseCase.RegisterContributor(auditUseCase.PersonalDataContributor())
+	}
+
+	if config.RabbitMQ != nil && config.Events.Outbox != nil {
+		StartOutboxRelay(config.Config, config.Log, config.DB, config.Events.Outbox, config.RabbitMQ)
+	}
+
+	privacyHandler := http.NewPrivacyHandler(privacyUseCase, config.Log)
+	StartErasureWorker(config.Config, config.Log, privacyUseCase)
+
 	routeConfig := route.RouteConfig{
-		App:         config.App,
-		UserHandler: userHandler,
-		AuthHandler: authHandler,
-		Logger:      config.Log,
+		App:                config.App,
+		UserHandler:        userHandler,
+		UserImportHandler:  userImportHandler,
+		UserExportHandler:  userExportHandler,
+		UserAvatarHandler:  userAvatarHandler,
+		PreferencesHandler: userPreferencesHandler,
+		FileHandler:        fileHandler,
+		PrivacyHandler:     privacyHandler,
+		AuditHandler:       auditHandler,
+		AuthHandler:        authHandler,
+		TokenValidator:     authUseCase,
+		BodyLimit:          config.Config.GetInt("SERVER_BODY_LIMIT"),
+		Logger:             config.Log,
+	}
+	if config.DB != nil {
+		routeConfig.QueryMetrics = gormpkg.QueryMetrics(config.DB)
 	}
 	routeConfig.Setup()
 }
+
+// NewUserOptions reads the user usecase settings. Cursors are signed with
+// CURSOR_SIGNING_KEY, falling back to the JWT secret like signed file links.
+func NewUserOptions(cfg *viper.Viper) usecase.UserOptions {
+	key := cfg.GetString("CURSOR_SIGNING_KEY")
+	if key == "" {
+		key = cfg.GetString("JWT_SECRET")
+	}
+	return usecase.UserOptions{CursorKey: []byte(key)}
+}
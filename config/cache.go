@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"app-hexagonal/pkg/cache"
+	"app-hexagonal/pkg/redis"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	CacheDriverNone   = "none"
+	CacheDriverMemory = "memory"
+	CacheDriverRedis  = "redis"
+)
+
+// NewCache creates the cache selected by CACHE_DRIVER, or nil when caching is
+// disabled. The redis driver falls back to an in-process LRU while Redis is down.
+func NewCache(cfg *viper.Viper, log *zap.Logger) (cache.Cache, error) {
+	driver := cfg.GetString("CACHE_DRIVER")
+
+	switch driver {
+	case CacheDriverNone, "":
+		return nil, nil
+	case CacheDriverMemory:
+		// Entries are not shared between instances, so keep the TTL short
+		log.Info("Using in-process cache", zap.Int("size", cfg.GetInt("CACHE_LRU_SIZE")))
+		return cache.NewLRUCache(cfg.GetInt("CACHE_LRU_SIZE")), nil
+	case CacheDriverRedis:
+		// The pool connects lazily so the service starts while Redis is down
+		pool := redis.NewPool(
+			cfg.GetString("REDIS_HOST"),
+			cfg.GetString("REDIS_PORT"),
+			cfg.GetString("REDIS_PASSWORD"),
+		)
+
+		redisCache := cache.NewRedisCache(pool, cfg.GetString("REDIS_PREFIX"))
+		log.Info("Using redis cache",
+			zap.String("host", cfg.GetString("REDIS_HOST")),
+			zap.String("prefix", cfg.GetString("REDIS_PREFIX")),
+		)
+
+		return cache.NewFallbackCache(
+			redisCache,
+			cache.NewLRUCache(cfg.GetInt("CACHE_LRU_SIZE")),
+			cfg.GetDuration("CACHE_FALLBACK_COOLDOWN"),
+			func(err error) {
+				log.Warn("Redis cache unavailable, using in-process cache", zap.Error(err))
+			},
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", driver)
+	}
+}
+
+// cacheTTL returns the entry TTL for the cache driver. REDIS_TTL may be given in
+// seconds, as in .env.example, or as a duration such as 1h. The in-process cache
+// is not shared between instances, so its entries expire after CACHE_LRU_TTL.
+func cacheTTL(cfg *viper.Viper) time.Duration {
+	if cfg.GetString("CACHE_DRIVER") == CacheDriverMemory {
+		return cfg.GetDuration("CACHE_LRU_TTL")
+	}
+	return secondsOrDuration(cfg, "REDIS_TTL")
+}
+
+// secondsOrDuration reads a duration setting that may also be a plain number of seconds
+func secondsOrDuration(cfg *viper.Viper, key string) time.Duration {
+	if seconds, err := strconv.Atoi(cfg.GetString(key)); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	return cfg.GetDuration(key)
+}
@@ -21,35 +21,75 @@ func LoadConfig() (*viper.Viper, error) {
 	v.SetDefault("APP_TIMEZONE", "+07:00")
 	v.SetDefault("APP_PREFORK", false)
 
+	v.SetDefault("DATABASE_DRIVER", "mysql")
 	v.SetDefault("DATABASE_PORT", 3306)
 	v.SetDefault("DATABASE_HOST", "localhost")
 	v.SetDefault("DATABASE_USER", "root")
 	v.SetDefault("DATABASE_PASSWORD", "")
 	v.SetDefault("DATABASE_NAME", "superfood")
 	v.SetDefault("DATABASE_SSL_MODE", "disable")
+	v.SetDefault("DATABASE_TIMEZONE", "UTC")
+	v.SetDefault("DATABASE_CONNECTION_LIMIT", 10)
+	v.SetDefault("DATABASE_MAX_IDLE_CONNECTIONS", 5)
+	v.SetDefault("DATABASE_CONN_MAX_LIFETIME", 60*time.Second)
 	v.SetDefault("DATABASE_LOG_ENABLED", true)
 	v.SetDefault("DATABASE_LOG_LEVEL", 3)
 	v.SetDefault("DATABASE_LOG_THRESHOLD", 200)
+	v.SetDefault("DATABASE_LOG_REDACT_COLUMNS", "password,email,encrypted_email,name,preferences")
+	v.SetDefault("DATABASE_REPLICAS", "")
+	v.SetDefault("DATABASE_REPLICA_HEALTH_CHECK_INTERVAL", 10*time.Second)
+	v.SetDefault("DATABASE_READ_YOUR_WRITES_WINDOW", 5*time.Second)
+	v.SetDefault("DATABASE_TENANCY", "")
+	v.SetDefault("DATABASE_TENANT_SCHEMA_PREFIX", "tenant_")
+	v.SetDefault("DATABASE_TENANT_REQUIRED", false)
+	v.SetDefault("DATABASE_TENANT_AUTO_PROVISION", false)
+	v.SetDefault("DATABASE_TENANT_CONNECTION_LIMIT", 5)
+	v.SetDefault("DATABASE_TENANT_IDLE_CONNECTIONS", 1)
+	v.SetDefault("DATABASE_TENANT_CONN_MAX_IDLE_TIME", time.Minute)
+	v.SetDefault("DATABASE_TENANT_POOL_IDLE_TIMEOUT", 10*time.Minute)
+
+	v.SetDefault("REPOSITORY_ADAPTER", "gorm")
+
+	v.SetDefault("ENCRYPTION_KEY_FILE", "")
+	v.SetDefault("ENCRYPTION_BLIND_INDEX_KEY", "")
+	v.SetDefault("ENCRYPTION_DATA_KEY_TTL", 5*time.Minute)
+	v.SetDefault("ENCRYPTION_MAX_SORTED_MATCHES", 10000)
+
+	v.SetDefault("PRIVACY_ERASURE_GRACE_PERIOD", 7*24*time.Hour)
+	v.SetDefault("PRIVACY_ERASURE_INTERVAL", time.Hour)
+
+	v.SetDefault("DATABASE_AUTO_MIGRATE", false)
+	v.SetDefault("DATABASE_AUTO_MIGRATE_TIMEOUT", 5*time.Minute)
+	v.SetDefault("SQLITE_AUTO_MIGRATE", true)
+
+	v.SetDefault("SEED_ADMIN_EMAIL", "")
+	v.SetDefault("SEED_ADMIN_PASSWORD", "")
+	v.SetDefault("SEED_DEMO_USERS", 50)
+	v.SetDefault("SEED_RANDOM_SEED", 1)
 
 	v.SetDefault("REDIS_PORT", 6379)
 	v.SetDefault("REDIS_HOST", "localhost")
 	v.SetDefault("REDIS_PASSWORD", "")
 	v.SetDefault("REDIS_DB", 0)
 	v.SetDefault("REDIS_TTL", 3600*time.Second)
+	v.SetDefault("REDIS_PREFIX", "")
+
+	v.SetDefault("CACHE_DRIVER", "none")
+	v.SetDefault("CACHE_LRU_SIZE", 10000)
+	v.SetDefault("CACHE_LRU_TTL", 30*time.Second)
+	v.SetDefault("CACHE_FALLBACK_COOLDOWN", 10*time.Second)
+
+	v.SetDefault("RABBITMQ_EVENTS_EXCHANGE", "app.events")
+	v.SetDefault("OUTBOX_RELAY_INTERVAL", time.Second)
+	v.SetDefault("OUTBOX_RELAY_BATCH_SIZE", 100)
+	v.SetDefault("OUTBOX_RELAY_MAX_BACKOFF", time.Hour)
+	v.SetDefault("OUTBOX_RETENTION", 7*24*time.Hour)
 
 	v.SetDefault("SERVER_PORT", 4001)
 	v.SetDefault("SERVER_READ_TIMEOUT", 5*time.Second)
 	v.SetDefault("SERVER_WRITE_TIMEOUT", 10*time.Second)
 	v.SetDefault("SERVER_IDLE_TIMEOUT", 60*time.Second)
-
-	v.SetDefault("PG_PORT", 5432)
-	v.SetDefault("PG_HOST", "localhost")
-	v.SetDefault("PG_USER", "postgres")
-	v.SetDefault("PG_PASSWORD", "")
-	v.SetDefault("PG_NAME", "superfood")
-	v.SetDefault("PG_LOG_ENABLED", true)
-	v.SetDefault("PG_LOG_LEVEL", 3)
-	v.SetDefault("PG_LOG_THRESHOLD", 200)
+	v.SetDefault("SERVER_BODY_LIMIT", 8*1024*1024)
 
 	v.SetDefault("RATE_LIMIT_ENABLED", false)
 	v.SetDefault("RATE_LIMIT_WINDOWMS", 2*time.Second)
@@ -59,6 +99,25 @@ func LoadConfig() (*viper.Viper, error) {
 	v.SetDefault("HTTP_MAX_REDIRECTS", 5)
 
 	v.SetDefault("BATCH_PROCESSING_SIZE", 500)
+	v.SetDefault("IMPORT_MAX_SIZE", 100*1024*1024)
+
+	v.SetDefault("EXPORT_DIR", "storage/exports")
+	v.SetDefault("EXPORT_RETENTION", 24*time.Hour)
+
+	v.SetDefault("STORAGE_DRIVER", "local")
+	v.SetDefault("STORAGE_LOCAL_ROOT", "storage/files")
+	v.SetDefault("STORAGE_LOCAL_BASE_URL", "http://localhost:4001/files")
+	v.SetDefault("STORAGE_SIGNING_KEY", "")
+	v.SetDefault("STORAGE_SIGNED_URL_TTL", 15*time.Minute)
+	v.SetDefault("AWS_S3_REGION", "ap-southeast-1")
+	v.SetDefault("AWS_S3_USE_PATH_STYLE", false)
+
+	v.SetDefault("AVATAR_MAX_SIZE", 5*1024*1024)
+	v.SetDefault("AVATAR_MAX_DIMENSION", 4096)
+	v.SetDefault("AVATAR_THUMBNAIL_SIZE", 128)
+
+	v.SetDefault("USER_PREFERENCES_MAX_SIZE", 16*1024)
+	v.SetDefault("CURSOR_SIGNING_KEY", "")
 
 	// Set up to read from .env file
 	v.SetConfigType("env")
@@ -95,18 +154,13 @@ func LoadStructuredConfig() (*Config, error) {
 	config.App.Debug = v.GetBool("APP_DEBUG")
 	config.App.GRPCPort = v.GetInt("GRPC_PORT")
 
-	config.Database.Host = v.GetString("DATABASE_HOST")
-	config.Database.Port = v.GetInt("DATABASE_PORT")
-	config.Database.User = v.GetString("DATABASE_USER")
-	config.Database.Password = v.GetString("DATABASE_PASSWORD")
-	config.Database.Name = v.GetString("DATABASE_NAME")
-	config.Database.SSLMode = v.GetString("DATABASE_SSL_MODE")
+	config.Database = NewDatabaseConfig(v)
 
 	config.Redis.Host = v.GetString("REDIS_HOST")
 	config.Redis.Port = v.GetInt("REDIS_PORT")
 	config.Redis.Password = v.GetString("REDIS_PASSWORD")
 	config.Redis.DB = v.GetInt("REDIS_DB")
-	config.Redis.TTL = v.GetDuration("REDIS_TTL")
+	config.Redis.TTL = secondsOrDuration(v, "REDIS_TTL")
 
 	config.Server.Port = v.GetInt("SERVER_PORT")
 	config.Server.ReadTimeout = v.GetDuration("SERVER_READ_TIMEOUT")
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"app-hexagonal/internal/encryption"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// EncryptionEnabled reports whether personal data is encrypted at rest
+func EncryptionEnabled(cfg *viper.Viper) bool {
+	return cfg.GetString("ENCRYPTION_KEY_FILE") != ""
+}
+
+// NewFieldEncryption loads the key encryption keys from ENCRYPTION_KEY_FILE and
+// the blind index key from ENCRYPTION_BLIND_INDEX_KEY
+func NewFieldEncryption(cfg *viper.Viper, log *zap.Logger) (*encryption.Envelope, *encryption.BlindIndex, error) {
+	keys, err := encryption.LoadLocalKeyManager(cfg.GetString("ENCRYPTION_KEY_FILE"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encodedIndexKey := cfg.GetString("ENCRYPTION_BLIND_INDEX_KEY")
+	if encodedIndexKey == "" {
+		return nil, nil, errors.New("ENCRYPTION_BLIND_INDEX_KEY is required when ENCRYPTION_KEY_FILE is set")
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(encodedIndexKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ENCRYPTION_BLIND_INDEX_KEY must be base64 encoded: %w", err)
+	}
+	index, err := encryption.NewBlindIndex(indexKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Info("Encrypting personal data at rest", zap.String("key_id", keys.CurrentKeyID()))
+	return encryption.NewEnvelope(keys, encryption.EnvelopeOptions{
+		DataKeyTTL: cfg.GetDuration("ENCRYPTION_DATA_KEY_TTL"),
+	}), index, nil
+}
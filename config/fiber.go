@@ -13,6 +13,10 @@ func NewFiberConfig(cfg *viper.Viper) *fiber.App {
 		AppName:      cfg.GetString("APP_NAME"),
 		Prefork:      cfg.GetBool("APP_PREFORK"),
 		ErrorHandler: NewErrorHandler(),
+		BodyLimit:    cfg.GetInt("SERVER_BODY_LIMIT"),
+		// Larger bodies are streamed to the handler rather than refused, see
+		// middleware.BodyLimitMiddleware
+		StreamRequestBody: true,
 	})
 
 	app.Use(recover.New())
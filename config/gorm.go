@@ -1,43 +1,275 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"app-hexagonal/database/migrations"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	gormpkg "app-hexagonal/pkg/gorm"
 	"app-hexagonal/pkg/mysql"
 	"app-hexagonal/pkg/postgres"
-	"time"
+	"app-hexagonal/pkg/sqlite"
 
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	DatabaseDriverMySQL    = "mysql"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
+// NewDatabaseConfig reads the DATABASE_* settings shared by the application and migrations
+func NewDatabaseConfig(cfg *viper.Viper) DatabaseConfig {
+	return DatabaseConfig{
+		Driver:   cfg.GetString("DATABASE_DRIVER"),
+		Host:     cfg.GetString("DATABASE_HOST"),
+		Port:     cfg.GetInt("DATABASE_PORT"),
+		User:     cfg.GetString("DATABASE_USER"),
+		Password: cfg.GetString("DATABASE_PASSWORD"),
+		Name:     cfg.GetString("DATABASE_NAME"),
+		SSLMode:  cfg.GetString("DATABASE_SSL_MODE"),
+		Timezone: cfg.GetString("DATABASE_TIMEZONE"),
+
+		MaxOpenConns:    cfg.GetInt("DATABASE_CONNECTION_LIMIT"),
+		MaxIdleConns:    cfg.GetInt("DATABASE_MAX_IDLE_CONNECTIONS"),
+		ConnMaxLifetime: cfg.GetDuration("DATABASE_CONN_MAX_LIFETIME"),
+
+		LogEnabled:   cfg.GetBool("DATABASE_LOG_ENABLED"),
+		LogLevel:     cfg.GetInt("DATABASE_LOG_LEVEL"),
+		LogThreshold: time.Duration(cfg.GetInt("DATABASE_LOG_THRESHOLD")) * time.Millisecond,
+
+		LogRedactColumns: splitList(cfg.GetString("DATABASE_LOG_REDACT_COLUMNS")),
+
+		Replicas:                   splitList(cfg.GetString("DATABASE_REPLICAS")),
+		ReplicaHealthCheckInterval: cfg.GetDuration("DATABASE_REPLICA_HEALTH_CHECK_INTERVAL"),
+		ReadYourWritesWindow:       cfg.GetDuration("DATABASE_READ_YOUR_WRITES_WINDOW"),
+
+		Tenancy:             cfg.GetString("DATABASE_TENANCY"),
+		TenantSchemaPrefix:  cfg.GetString("DATABASE_TENANT_SCHEMA_PREFIX"),
+		TenantRequired:      cfg.GetBool("DATABASE_TENANT_REQUIRED"),
+		TenantAutoProvision: cfg.GetBool("DATABASE_TENANT_AUTO_PROVISION"),
+		TenantMaxOpenConns:  cfg.GetInt("DATABASE_TENANT_CONNECTION_LIMIT"),
+		TenantMaxIdleConns:  cfg.GetInt("DATABASE_TENANT_IDLE_CONNECTIONS"),
+
+		TenantConnMaxIdleTime: cfg.GetDuration("DATABASE_TENANT_CONN_MAX_IDLE_TIME"),
+		TenantPoolIdleTimeout: cfg.GetDuration("DATABASE_TENANT_POOL_IDLE_TIMEOUT"),
+	}
+}
+
+// NewGormDB connects to the database selected by DATABASE_DRIVER
 func NewGormDB(cfg *viper.Viper, log *zap.Logger) (*gorm.DB, error) {
-	db, err := mysql.Connect(
-		cfg.GetString("DATABASE_HOST"),
-		cfg.GetInt("DATABASE_PORT"),
-		cfg.GetString("DATABASE_USER"),
-		cfg.GetString("DATABASE_PASSWORD"),
-		cfg.GetString("DATABASE_NAME"),
-		mysql.SetPrintLog(
-			cfg.GetBool("DATABASE_LOG_ENABLED"),
-			logger.LogLevel(cfg.GetInt("DATABASE_LOG_LEVEL")),
-			time.Duration(cfg.GetInt("DATABASE_LOG_THRESHOLD"))*time.Millisecond,
-		),
-	)
+	dbConfig := NewDatabaseConfig(cfg)
+
+	db, err := NewDatabase(dbConfig, NewQueryLogger(dbConfig, log))
 	if err != nil {
-		log.Fatal("Failed to initialize mysql database connection", zap.Error(err))
+		log.Error("Failed to initialize database connection",
+			zap.String("driver", dbConfig.Driver),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	_, err = postgres.Connect(
-		cfg.GetString("PG_HOST"),
-		cfg.GetInt("PG_PORT"),
-		cfg.GetString("PG_USER"),
-		cfg.GetString("PG_PASSWORD"),
-		cfg.GetString("PG_NAME"),
-	)
-	if err != nil {
-		log.Fatal("Failed to initialize postgres database connection", zap.Error(err))
+	// Record who creates and changes rows from the principal of the request
+	if err := db.Use(gormpkg.NewAuditColumns(domain.PrincipalFromContext)); err != nil {
+		return nil, fmt.Errorf("failed to register audit columns: %w", err)
+	}
+	if err := db.Use(NewAuditLog(cfg)); err != nil {
+		return nil, fmt.Errorf("failed to register audit log: %w", err)
 	}
 
+	// SQLITE_AUTO_MIGRATE predates DATABASE_AUTO_MIGRATE and still applies to SQLite
+	autoMigrate := cfg.GetBool("DATABASE_AUTO_MIGRATE") ||
+		(dbConfig.Driver == DatabaseDriverSQLite && cfg.GetBool("SQLITE_AUTO_MIGRATE"))
+	if autoMigrate {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.GetDuration("DATABASE_AUTO_MIGRATE_TIMEOUT"))
+		defer cancel()
+		if err := AutoMigrate(ctx, db, dbConfig); err != nil {
+			log.Error("Failed to migrate database", zap.String("driver", dbConfig.Driver), zap.Error(err))
+			return nil, err
+		}
+		if err := MigrateTenants(ctx, db, log); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Connected to database",
+		zap.String("driver", dbConfig.Driver),
+		zap.String("database", dbConfig.Name),
+		zap.Int("max_open_connections", dbConfig.MaxOpenConns),
+		zap.Int("replicas", len(dbConfig.Replicas)),
+	)
 	return db, nil
 }
+
+// NewAuditLog creates the GORM plugin recording changes to the audited models
+// in the audit_log table, attributed to the principal and request of the change.
+// With encryption enabled the encrypted columns are recorded without values.
+func NewAuditLog(cfg *viper.Viper) *gormpkg.AuditLog {
+	var redact []string
+	if EncryptionEnabled(cfg) {
+		redact = repository.EncryptedUserColumns
+	}
+	return gormpkg.NewAuditLog(gormpkg.AuditLogConfig{
+		Principal:     domain.PrincipalFromContext,
+		RequestID:     domain.RequestIDFromContext,
+		RedactColumns: redact,
+	}).Register(repository.AuditedModels()...)
+}
+
+// NewQueryLogger builds the GORM logger writing queries to log with the
+// request and trace IDs of their context. Query durations are recorded for
+// /metrics even when DATABASE_LOG_ENABLED is off.
+func NewQueryLogger(dbConfig DatabaseConfig, log *zap.Logger) *gormpkg.ZapLogger {
+	level := logger.LogLevel(dbConfig.LogLevel)
+	if !dbConfig.LogEnabled {
+		level = logger.Silent
+	}
+	return gormpkg.NewZapLogger(log, gormpkg.ZapLoggerConfig{
+		LogLevel:                  level,
+		SlowThreshold:             dbConfig.LogThreshold,
+		IgnoreRecordNotFoundError: true,
+		RedactColumns:             dbConfig.LogRedactColumns,
+		ContextFields:             queryLogFields,
+		Metrics:                   gormpkg.NewQueryHistogram(),
+	})
+}
+
+// queryLogFields identifies the request a query was run for
+func queryLogFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := domain.RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if id := domain.TraceIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String("trace_id", id))
+	}
+	return fields
+}
+
+// NewTenancy configures the PostgreSQL schema per tenant strategy, which takes
+// the tenant of every statement from its context and applies the PostgreSQL
+// migrations to tenant schemas. It returns nil unless DATABASE_TENANCY is schema.
+func NewTenancy(dbConfig DatabaseConfig) *postgres.TenancyConfig {
+	if dbConfig.Tenancy != "schema" {
+		return nil
+	}
+	return &postgres.TenancyConfig{
+		Tenant:        domain.TenantFromContext,
+		SchemaPrefix:  dbConfig.TenantSchemaPrefix,
+		RequireTenant: dbConfig.TenantRequired,
+		AutoProvision: dbConfig.TenantAutoProvision,
+		Migrations: func() (source.Driver, error) {
+			return migrations.Source(DatabaseDriverPostgres)
+		},
+		MaxOpenConns:    dbConfig.TenantMaxOpenConns,
+		MaxIdleConns:    dbConfig.TenantMaxIdleConns,
+		ConnMaxIdleTime: dbConfig.TenantConnMaxIdleTime,
+		PoolIdleTimeout: dbConfig.TenantPoolIdleTimeout,
+	}
+}
+
+// NewTenantLister returns what lists the tenants the background workers go
+// through: every provisioned tenant, and the shared schema unless
+// DATABASE_TENANT_REQUIRED refuses work without a tenant. It returns nil, for
+// the shared schema alone, when db does not keep a schema per tenant.
+func NewTenantLister(cfg *viper.Viper, db *gorm.DB) func(ctx context.Context) ([]string, error) {
+	if db == nil {
+		return nil
+	}
+	tenancy, ok := postgres.Tenancy(db)
+	if !ok {
+		return nil
+	}
+
+	shared := !cfg.GetBool("DATABASE_TENANT_REQUIRED")
+	return func(ctx context.Context) ([]string, error) {
+		tenants, err := tenancy.Tenants(ctx)
+		if err != nil || !shared {
+			return tenants, err
+		}
+		return append([]string{""}, tenants...), nil
+	}
+}
+
+// NewDatabase builds a *gorm.DB for the configured driver and applies the pool
+// limits. Queries are logged through queryLogger, or by the driver's own text
+// logger when it is nil.
+func NewDatabase(dbConfig DatabaseConfig, queryLogger logger.Interface) (*gorm.DB, error) {
+	if dbConfig.Tenancy != "" && dbConfig.Driver != DatabaseDriverPostgres {
+		return nil, fmt.Errorf("database tenancy %q requires the postgres driver", dbConfig.Tenancy)
+	}
+
+	lifetime := int(dbConfig.ConnMaxLifetime / time.Second)
+	logLevel := logger.LogLevel(dbConfig.LogLevel)
+	replicaConfig := gormpkg.DefaultReplicaConfig()
+	replicaConfig.HealthCheckInterval = dbConfig.ReplicaHealthCheckInterval
+	replicaConfig.StickyWindow = dbConfig.ReadYourWritesWindow
+
+	switch dbConfig.Driver {
+	case DatabaseDriverMySQL:
+		return mysql.Connect(
+			dbConfig.Host,
+			dbConfig.Port,
+			dbConfig.User,
+			dbConfig.Password,
+			dbConfig.Name,
+			mysql.SetMaxOpenConns(dbConfig.MaxOpenConns),
+			mysql.SetMaxIdleConns(dbConfig.MaxIdleConns),
+			mysql.SetConnMaxLifetime(lifetime),
+			mysql.SetTimezone(dbConfig.Timezone),
+			mysql.SetPrintLog(dbConfig.LogEnabled, logLevel, dbConfig.LogThreshold),
+			mysql.SetLogger(queryLogger),
+			mysql.SetReplicas(dbConfig.Replicas...),
+			mysql.SetReplicaConfig(replicaConfig),
+		)
+	case DatabaseDriverPostgres:
+		return postgres.Connect(
+			dbConfig.Host,
+			dbConfig.Port,
+			dbConfig.User,
+			dbConfig.Password,
+			dbConfig.Name,
+			postgres.SetMaxOpenConns(dbConfig.MaxOpenConns),
+			postgres.SetMaxIdleConns(dbConfig.MaxIdleConns),
+			postgres.SetConnMaxLifetime(lifetime),
+			postgres.SetTimezone(dbConfig.Timezone),
+			postgres.SetSSLMode(dbConfig.SSLMode),
+			postgres.SetPrintLog(dbConfig.LogEnabled, logLevel, dbConfig.LogThreshold),
+			postgres.SetLogger(queryLogger),
+			postgres.SetReplicas(dbConfig.Replicas...),
+			postgres.SetReplicaConfig(replicaConfig),
+			postgres.SetTenancy(NewTenancy(dbConfig)),
+		)
+	case DatabaseDriverSQLite:
+		return sqlite.Connect(
+			dbConfig.Name,
+			sqlite.SetMaxOpenConns(dbConfig.MaxOpenConns),
+			sqlite.SetMaxIdleConns(dbConfig.MaxIdleConns),
+			sqlite.SetConnMaxLifetime(lifetime),
+			sqlite.SetPrintLog(dbConfig.LogEnabled, logLevel, dbConfig.LogThreshold),
+			sqlite.SetLogger(queryLogger),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", dbConfig.Driver)
+	}
+}
+
+// splitList splits a comma separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
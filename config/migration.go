@@ -1,47 +1,38 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 
+	"app-hexagonal/database/migrations"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/postgres"
+	"app-hexagonal/pkg/sqlite"
+
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database"
+	migrateMysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	migratePostgres "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// migrationLock is the advisory lock held while migrating on boot
+const migrationLock = "app-hexagonal:migrations"
+
 // RunMigrations runs database migrations
 func RunMigrations(cfg *viper.Viper) error {
-	// Get database configuration
-	dbHost := cfg.GetString("DB_HOST")
-	dbPort := cfg.GetInt("DB_PORT")
-	dbUser := cfg.GetString("DB_USER")
-	dbPassword := cfg.GetString("DB_PASSWORD")
-	dbName := cfg.GetString("DB_NAME")
-	dbType := cfg.GetString("DB_TYPE")
-
-	var dbURL string
-	switch dbType {
-	case "mysql":
-		dbURL = fmt.Sprintf("mysql://%s:%s@tcp(%s:%d)/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
-	case "postgres":
-		dbURL = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-	default:
-		return fmt.Errorf("unsupported database type: %s", dbType)
-	}
-
-	// Create migration instance
-	m, err := migrate.New(
-		"file://database/migrations",
-		dbURL,
-	)
+	m, err := NewMigrate(NewDatabaseConfig(cfg))
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		return err
 	}
+	defer m.Close()
 
 	// Run migrations
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -51,38 +42,144 @@ func RunMigrations(cfg *viper.Viper) error {
 
 // RollbackMigrations rolls back the last migration
 func RollbackMigrations(cfg *viper.Viper) error {
-	// Get database configuration
-	dbHost := cfg.GetString("DB_HOST")
-	dbPort := cfg.GetInt("DB_PORT")
-	dbUser := cfg.GetString("DB_USER")
-	dbPassword := cfg.GetString("DB_PASSWORD")
-	dbName := cfg.GetString("DB_NAME")
-	dbType := cfg.GetString("DB_TYPE")
-
-	var dbURL string
-	switch dbType {
-	case "mysql":
-		dbURL = fmt.Sprintf("mysql://%s:%s@tcp(%s:%d)/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
-	case "postgres":
-		dbURL = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-	default:
-		return fmt.Errorf("unsupported database type: %s", dbType)
-	}
-
-	// Create migration instance
-	m, err := migrate.New(
-		"file://database/migrations",
-		dbURL,
-	)
+	m, err := NewMigrate(NewDatabaseConfig(cfg))
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		return err
 	}
+	defer m.Close()
 
 	// Rollback migrations
-	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
+	if err := m.Steps(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return fmt.Errorf("failed to rollback migrations: %w", err)
 	}
 
 	log.Println("Migrations rolled back successfully")
 	return nil
 }
+
+// AutoMigrate applies pending migrations to db on boot. It holds an advisory
+// lock meanwhile, so when several instances start together one migrates and
+// the others wait for it and then find nothing left to do.
+func AutoMigrate(ctx context.Context, db *gorm.DB, dbConfig DatabaseConfig) error {
+	return gormpkg.WithAdvisoryLock(ctx, db, migrationLock, func() error {
+		var m *migrate.Migrate
+		var err error
+		if dbConfig.Driver == DatabaseDriverSQLite {
+			// An in-memory database only exists on the connections of db
+			m, err = newSQLiteMigrate(db)
+		} else {
+			m, err = NewMigrate(dbConfig)
+		}
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		return nil
+	})
+}
+
+// MigrateTenants applies pending migrations to every tenant schema when db
+// keeps a schema per tenant, logging the state each is left in. Every tenant
+// is attempted; the error reports how many failed.
+func MigrateTenants(ctx context.Context, db *gorm.DB, log *zap.Logger) error {
+	tenancy, ok := postgres.Tenancy(db)
+	if !ok {
+		return nil
+	}
+
+	statuses, err := tenancy.MigrateTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tenants: %w", err)
+	}
+
+	failed := 0
+	for _, status := range statuses {
+		fields := []zap.Field{
+			zap.String("tenant", status.Tenant),
+			zap.String("schema", status.Schema),
+			zap.Uint("version", status.Version),
+			zap.Bool("dirty", status.Dirty),
+		}
+		if status.Err != nil {
+			failed++
+			log.Error("Failed to migrate tenant", append(fields, zap.Error(status.Err))...)
+			continue
+		}
+		log.Info("Migrated tenant", fields...)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to migrate %d of %d tenants", failed, len(statuses))
+	}
+	return nil
+}
+
+// NewMigrate creates a migration instance with the embedded migrations of the
+// configured dialect on a connection built by NewDatabase, so migrations use
+// exactly the same DATABASE_* settings as the application
+func NewMigrate(dbConfig DatabaseConfig) (*migrate.Migrate, error) {
+	// Migrations run one statement at a time on a single primary connection
+	dbConfig.MaxOpenConns = 1
+	dbConfig.LogEnabled = false
+	dbConfig.Replicas = nil
+	// The shared schema is migrated here, tenant schemas by MigrateTenants
+	dbConfig.Tenancy = ""
+
+	db, err := NewDatabase(dbConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbConfig.Driver == DatabaseDriverSQLite {
+		return newSQLiteMigrate(db)
+	}
+
+	source, err := migrations.Source(dbConfig.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := migrationDriver(db, dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("embedded", source, dbConfig.Driver, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+	return m, nil
+}
+
+// newSQLiteMigrate creates a migration instance on the connections of db
+func newSQLiteMigrate(db *gorm.DB) (*migrate.Migrate, error) {
+	source, err := migrations.Source(DatabaseDriverSQLite)
+	if err != nil {
+		return nil, err
+	}
+	m, err := sqlite.NewMigrateFromSource(db, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+	return m, nil
+}
+
+// migrationDriver wraps the connection in the golang-migrate driver for its dialect
+func migrationDriver(db *gorm.DB, dbConfig DatabaseConfig) (database.Driver, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	switch dbConfig.Driver {
+	case DatabaseDriverMySQL:
+		return migrateMysql.WithInstance(sqlDB, &migrateMysql.Config{DatabaseName: dbConfig.Name})
+	case DatabaseDriverPostgres:
+		return migratePostgres.WithInstance(sqlDB, &migratePostgres.Config{DatabaseName: dbConfig.Name})
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", dbConfig.Driver)
+	}
+}
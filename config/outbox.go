@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/messaging"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"github.com/spf13/viper"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewEventOptions returns where the usecases record domain events, matching
+// REPOSITORY_ADAPTER. Events are only recorded when RABBITMQ_URL is set, since
+// nothing would publish them otherwise.
+func NewEventOptions(cfg *viper.Viper, db *gorm.DB) (usecase.EventOptions, error) {
+	if cfg.GetString("RABBITMQ_URL") == "" {
+		return usecase.EventOptions{}, nil
+	}
+
+	switch adapter := cfg.GetString("REPOSITORY_ADAPTER"); adapter {
+	case RepositoryAdapterGorm:
+		return usecase.EventOptions{
+			Transactor: gormpkg.NewTransactionManager(db),
+			Outbox:     repository.NewOutboxRepository(db),
+		}, nil
+	case RepositoryAdapterMemory:
+		return usecase.EventOptions{
+			Transactor: repository.MemoryTransactor{},
+			Outbox:     repository.NewMemoryOutboxRepository(),
+		}, nil
+	default:
+		return usecase.EventOptions{}, fmt.Errorf("unknown repository adapter %q", adapter)
+	}
+}
+
+// StartOutboxRelay publishes the outbox to the RABBITMQ_EVENTS_EXCHANGE exchange
+// every OUTBOX_RELAY_INTERVAL in the background, going through the outbox of
+// every tenant when db keeps them apart. Messages are claimed before they are
+// published, so every instance may do this.
+func StartOutboxRelay(cfg *viper.Viper, log *zap.Logger, db *gorm.DB, outbox domain.OutboxRepository, conn *amqp.Connection) {
+	interval := cfg.GetDuration("OUTBOX_RELAY_INTERVAL")
+	if interval <= 0 {
+		log.Warn("OUTBOX_RELAY_INTERVAL is not set, domain events will not be published")
+		return
+	}
+
+	exchange := cfg.GetString("RABBITMQ_EVENTS_EXCHANGE")
+	relay := usecase.NewOutboxRelay(outbox, messaging.NewRabbitMQPublisher(conn, exchange), usecase.RelayOptions{
+		BatchSize:  cfg.GetInt("OUTBOX_RELAY_BATCH_SIZE"),
+		MaxBackoff: cfg.GetDuration("OUTBOX_RELAY_MAX_BACKOFF"),
+		Retention:  cfg.GetDuration("OUTBOX_RETENTION"),
+		Tenants:    NewTenantLister(cfg, db),
+	})
+
+	log.Info("Publishing domain events", zap.String("exchange", exchange), zap.Duration("interval", interval))
+	go relay.Run(context.Background(), interval, func(err error) {
+		log.Error("Failed to relay outbox messages", zap.Error(err))
+	})
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewPrivacyRepository creates the privacy repository matching REPOSITORY_ADAPTER
+func NewPrivacyRepository(cfg *viper.Viper, db *gorm.DB) (domain.PrivacyRepository, error) {
+	switch adapter := cfg.GetString("REPOSITORY_ADAPTER"); adapter {
+	case RepositoryAdapterGorm:
+		return repository.NewPrivacyRepository(db), nil
+	case RepositoryAdapterMemory:
+		return repository.NewMemoryPrivacyRepository(), nil
+	default:
+		return nil, fmt.Errorf("unknown repository adapter %q", adapter)
+	}
+}
+
+// StartErasureWorker processes due erasures every PRIVACY_ERASURE_INTERVAL in the
+// background. Requests are claimed before they run, so every instance may do this.
+func StartErasureWorker(cfg *viper.Viper, log *zap.Logger, privacyUseCase *usecase.PrivacyUsecase) {
+	interval := cfg.GetDuration("PRIVACY_ERASURE_INTERVAL")
+	if interval <= 0 {
+		log.Warn("PRIVACY_ERASURE_INTERVAL is not set, scheduled erasures will not run")
+		return
+	}
+
+	go privacyUseCase.RunErasures(context.Background(), interval, func(err error) {
+		log.Error("Failed to process due erasures", zap.Error(err))
+	})
+}
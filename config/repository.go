@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// RepositoryAdapterGorm persists data in the configured database
+	RepositoryAdapterGorm = "gorm"
+	// RepositoryAdapterMemory keeps data in process memory and needs no database
+	RepositoryAdapterMemory = "memory"
+)
+
+// UsesDatabase reports whether the configured repository adapter needs a database connection
+func UsesDatabase(cfg *viper.Viper) bool {
+	return cfg.GetString("REPOSITORY_ADAPTER") != RepositoryAdapterMemory
+}
+
+// NewUserRepository creates the user repository selected by REPOSITORY_ADAPTER.
+// db may be nil when the memory adapter is selected.
+// The repository is wrapped in a cache-aside decorator unless CACHE_DRIVER is none,
+// and names and emails are encrypted before they reach the cache when
+// ENCRYPTION_KEY_FILE is set.
+func NewUserRepository(cfg *viper.Viper, db *gorm.DB, log *zap.Logger) (domain.UserRepository, error) {
+	return newUserRepository(cfg, db, log, true)
+}
+
+// NewUncachedUserRepository creates the user repository of NewUserRepository
+// without the cache, for commands such as seed that also change the users
+// table directly and evict what they changed afterwards.
+func NewUncachedUserRepository(cfg *viper.Viper, db *gorm.DB, log *zap.Logger) (domain.UserRepository, error) {
+	return newUserRepository(cfg, db, log, false)
+}
+
+func newUserRepository(cfg *viper.Viper, db *gorm.DB, log *zap.Logger, cached bool) (domain.UserRepository, error) {
+	adapter := cfg.GetString("REPOSITORY_ADAPTER")
+
+	var userRepository domain.UserRepository
+	switch adapter {
+	case RepositoryAdapterGorm:
+		userRepository = repository.NewUserRepository(db)
+	case RepositoryAdapterMemory:
+		log.Warn("Using in-memory user repository, data is lost on restart")
+		userRepository = repository.NewMemoryUserRepository()
+	default:
+		return nil, fmt.Errorf("unknown repository adapter %q", adapter)
+	}
+
+	if cached {
+		userCache, err := NewCache(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		if userCache != nil {
+			userRepository = repository.NewCachedUserRepository(userRepository, userCache, cacheTTL(cfg))
+		}
+	}
+
+	if EncryptionEnabled(cfg) {
+		envelope, index, err := NewFieldEncryption(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		userRepository = repository.NewEncryptedUserRepository(userRepository, envelope, index, cfg.GetInt("ENCRYPTION_MAX_SORTED_MATCHES"))
+	}
+
+	return userRepository, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/storage"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// NewStorage creates the object storage selected by STORAGE_DRIVER (local or s3)
+func NewStorage(cfg *viper.Viper, log *zap.Logger) (domain.Storage, error) {
+	driver := cfg.GetString("STORAGE_DRIVER")
+
+	switch driver {
+	case "local":
+		// Fall back to the JWT secret so local development works without extra setup
+		signingKey := cfg.GetString("STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			signingKey = cfg.GetString("JWT_SECRET")
+		}
+
+		localStorage, err := storage.NewLocalStorage(
+			cfg.GetString("STORAGE_LOCAL_ROOT"),
+			cfg.GetString("STORAGE_LOCAL_BASE_URL"),
+			[]byte(signingKey),
+		)
+		if err != nil {
+			log.Error("Failed to initialize local storage", zap.Error(err))
+			return nil, err
+		}
+		log.Info("Using local storage", zap.String("root", cfg.GetString("STORAGE_LOCAL_ROOT")))
+		return localStorage, nil
+	case "s3":
+		s3Storage, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.GetString("AWS_S3_ENDPOINT"),
+			Region:    cfg.GetString("AWS_S3_REGION"),
+			Bucket:    cfg.GetString("AWS_S3_BUCKET_NAME"),
+			AccessKey: cfg.GetString("AWS_S3_ACCESS_KEY_ID"),
+			SecretKey: cfg.GetString("AWS_S3_SECRET_ACCESS_KEY"),
+			PathStyle: cfg.GetBool("AWS_S3_USE_PATH_STYLE"),
+		}, &http.Client{Timeout: cfg.GetDuration("HTTP_TIMEOUT")})
+		if err != nil {
+			log.Error("Failed to initialize S3 storage", zap.Error(err))
+			return nil, err
+		}
+		log.Info("Using S3 storage", zap.String("bucket", cfg.GetString("AWS_S3_BUCKET_NAME")))
+		return s3Storage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -23,14 +23,46 @@ type AppConfig struct {
 	GRPCPort    int    `mapstructure:"grpc_port" validate:"required,min=1,max=65535"`
 }
 
-// DatabaseConfig holds database configuration
+// DatabaseConfig holds database configuration. For SQLite, Name is the database
+// file path and the server settings are ignored.
 type DatabaseConfig struct {
-	Host     string `mapstructure:"host" validate:"required"`
-	Port     int    `mapstructure:"port" validate:"required,min=1,max=65535"`
-	User     string `mapstructure:"user" validate:"required"`
+	Driver   string `mapstructure:"driver" validate:"required,oneof=mysql postgres sqlite"`
+	Host     string `mapstructure:"host" validate:"required_unless=Driver sqlite"`
+	Port     int    `mapstructure:"port" validate:"required_unless=Driver sqlite,max=65535"`
+	User     string `mapstructure:"user" validate:"required_unless=Driver sqlite"`
 	Password string `mapstructure:"password"`
 	Name     string `mapstructure:"name" validate:"required"`
 	SSLMode  string `mapstructure:"ssl_mode"`
+	Timezone string `mapstructure:"timezone"`
+
+	MaxOpenConns    int           `mapstructure:"connection_limit" validate:"min=0"`
+	MaxIdleConns    int           `mapstructure:"max_idle_connections" validate:"min=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+
+	LogEnabled   bool          `mapstructure:"log_enabled"`
+	LogLevel     int           `mapstructure:"log_level"`
+	LogThreshold time.Duration `mapstructure:"log_threshold"`
+	// LogRedactColumns are the columns whose bound values never appear in the query log
+	LogRedactColumns []string `mapstructure:"log_redact_columns"`
+
+	// Replicas are read replicas of a MySQL or PostgreSQL primary, given as
+	// full DSNs or as host[:port] sharing the primary's credentials
+	Replicas                   []string      `mapstructure:"replicas"`
+	ReplicaHealthCheckInterval time.Duration `mapstructure:"replica_health_check_interval"`
+	ReadYourWritesWindow       time.Duration `mapstructure:"read_your_writes_window"`
+
+	// Tenancy is empty for a single schema, or schema to keep every tenant in
+	// a PostgreSQL schema of its own
+	Tenancy             string `mapstructure:"tenancy" validate:"omitempty,oneof=schema"`
+	TenantSchemaPrefix  string `mapstructure:"tenant_schema_prefix"`
+	TenantRequired      bool   `mapstructure:"tenant_required"`
+	TenantAutoProvision bool   `mapstructure:"tenant_auto_provision"`
+	TenantMaxOpenConns  int    `mapstructure:"tenant_connection_limit" validate:"min=0"`
+	TenantMaxIdleConns  int    `mapstructure:"tenant_idle_connections" validate:"min=0"`
+	// TenantConnMaxIdleTime closes idle tenant connections and
+	// TenantPoolIdleTimeout the pools of tenants not used for that long
+	TenantConnMaxIdleTime time.Duration `mapstructure:"tenant_conn_max_idle_time"`
+	TenantPoolIdleTimeout time.Duration `mapstructure:"tenant_pool_idle_timeout"`
 }
 
 // RedisConfig holds Redis configuration
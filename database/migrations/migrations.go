@@ -0,0 +1,39 @@
+// Package migrations embeds the SQL migrations of every supported database
+// dialect, one directory per DATABASE_DRIVER value. MySQL migrations hold one
+// statement per file because the MySQL driver runs them without multi
+// statement support; the PostgreSQL and SQLite ones may hold several.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Dialects lists the dialects with a migration directory
+var Dialects = []string{"mysql", "postgres", "sqlite"}
+
+// FS returns the migrations of dialect
+func FS(dialect string) (fs.FS, error) {
+	for _, known := range Dialects {
+		if known == dialect {
+			return fs.Sub(files, dialect)
+		}
+	}
+	return nil, fmt.Errorf("no migrations for database driver %q", dialect)
+}
+
+// Source returns the migrations of dialect as a golang-migrate source
+func Source(dialect string) (source.Driver, error) {
+	fsys, err := FS(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return iofs.New(fsys, ".")
+}
@@ -0,0 +1,12 @@
+-- Erasure records are append-only; each row carries the hash of the previous one
+CREATE TABLE IF NOT EXISTS erasure_records (
+    sequence BIGINT PRIMARY KEY,
+    id VARCHAR(36) UNIQUE NOT NULL,
+    request_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    contributors TEXT NOT NULL,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    erased_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    previous_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN encrypted_email,
+    MODIFY COLUMN name VARCHAR(255) NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    MODIFY COLUMN name VARCHAR(1024) NOT NULL,
+    ADD COLUMN encrypted_email VARCHAR(1024) NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id VARCHAR(36) PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_id VARCHAR(36) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    next_attempt_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    sent_at TIMESTAMP(6) NULL DEFAULT NULL,
+    INDEX idx_outbox_messages_due (sent_at, next_attempt_at)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP INDEX idx_users_updated_at,
+    DROP INDEX idx_users_created_at,
+    DROP COLUMN updated_by,
+    DROP COLUMN created_by;
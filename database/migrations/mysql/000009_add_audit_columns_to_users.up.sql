@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN created_by VARCHAR(36) NOT NULL DEFAULT '',
+    ADD COLUMN updated_by VARCHAR(36) NOT NULL DEFAULT '',
+    ADD INDEX idx_users_created_at (created_at),
+    ADD INDEX idx_users_updated_at (updated_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    entity_type VARCHAR(100) NOT NULL,
+    entity_id VARCHAR(36) NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    changes TEXT NOT NULL,
+    actor_id VARCHAR(36) NOT NULL DEFAULT '',
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_audit_log_entity (entity_type, entity_id, created_at),
+    INDEX idx_audit_log_actor (actor_id, created_at),
+    INDEX idx_audit_log_created_at (created_at)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN avatar_key VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_thumbnail_key VARCHAR(255) NOT NULL DEFAULT '';
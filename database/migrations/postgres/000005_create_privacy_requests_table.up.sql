@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS privacy_requests (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error TEXT,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    cancelled_at TIMESTAMP NULL DEFAULT NULL
+);
+CREATE INDEX idx_privacy_requests_user ON privacy_requests (user_id, type, status);
+CREATE INDEX idx_privacy_requests_due ON privacy_requests (type, status, scheduled_for);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN encrypted_email,
+    ALTER COLUMN name TYPE VARCHAR(255);
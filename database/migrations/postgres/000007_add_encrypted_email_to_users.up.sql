@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ALTER COLUMN name TYPE VARCHAR(1024),
+    ADD COLUMN encrypted_email VARCHAR(1024) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_updated_at;
+DROP INDEX IF EXISTS idx_users_created_at;
+ALTER TABLE users
+    DROP COLUMN updated_by,
+    DROP COLUMN created_by;
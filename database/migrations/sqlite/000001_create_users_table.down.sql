@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS users_updated_at;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN avatar_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_thumbnail_key VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS privacy_requests (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error TEXT,
+    requested_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME NULL DEFAULT NULL,
+    cancelled_at DATETIME NULL DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_privacy_requests_user ON privacy_requests (user_id, type, status);
+CREATE INDEX IF NOT EXISTS idx_privacy_requests_due ON privacy_requests (type, status, scheduled_for);
@@ -0,0 +1,25 @@
+-- Erasure records are append-only; each row carries the hash of the previous one
+CREATE TABLE IF NOT EXISTS erasure_records (
+    sequence INTEGER PRIMARY KEY,
+    id VARCHAR(36) UNIQUE NOT NULL,
+    request_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    contributors TEXT NOT NULL,
+    requested_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    erased_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    previous_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL
+);
+
+-- SQLite can enforce the append-only rule itself
+CREATE TRIGGER IF NOT EXISTS erasure_records_no_update
+BEFORE UPDATE ON erasure_records
+BEGIN
+    SELECT RAISE(ABORT, 'erasure records are immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS erasure_records_no_delete
+BEFORE DELETE ON erasure_records
+BEGIN
+    SELECT RAISE(ABORT, 'erasure records are immutable');
+END;
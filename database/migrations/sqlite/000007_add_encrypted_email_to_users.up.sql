@@ -0,0 +1,2 @@
+-- SQLite does not enforce VARCHAR lengths, so the encrypted name fits without changes
+ALTER TABLE users ADD COLUMN encrypted_email VARCHAR(1024) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN created_by VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN updated_by VARCHAR(36) NOT NULL DEFAULT '';
+CREATE INDEX idx_users_created_at ON users (created_at);
+CREATE INDEX idx_users_updated_at ON users (updated_at);
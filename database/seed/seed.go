@@ -0,0 +1,28 @@
+// Package seed registers the seeders of every module. Each module adds its
+// seeders in its own file; they run in the order registered here.
+package seed
+
+import (
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/seeder"
+)
+
+// development lists the environments that get fake data
+var development = []string{"development", "local", "test"}
+
+// Options configures the seeders
+type Options struct {
+	// AdminEmail and AdminPassword describe the administrator account; it is
+	// not seeded while either is empty
+	AdminEmail    string
+	AdminPassword string
+	// DemoUsers is the number of fake users seeded outside production
+	DemoUsers int
+}
+
+// NewRegistry returns the seeders of all modules
+func NewRegistry(users domain.UserRepository, options Options) *seeder.Registry {
+	registry := seeder.NewRegistry()
+	registerUsers(registry, users, options)
+	return registry
+}
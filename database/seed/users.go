@@ -0,0 +1,95 @@
+package seed
+
+import (
+	"context"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/seeder"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// demoPassword is the password of every demo user
+const demoPassword = "password"
+
+// registerUsers registers the administrator account and the demo users
+func registerUsers(registry *seeder.Registry, users domain.UserRepository, options Options) {
+	registry.Register(
+		seeder.Seeder{
+			Name:   "admin-user",
+			Tables: []string{"users"},
+			Run: func(ctx context.Context, fake *seeder.Faker) error {
+				return seedAdmin(ctx, users, options)
+			},
+		},
+		seeder.Seeder{
+			Name:         "demo-users",
+			Environments: development,
+			Tables:       []string{"users"},
+			Run: func(ctx context.Context, fake *seeder.Faker) error {
+				return seedDemoUsers(ctx, users, fake, options.DemoUsers)
+			},
+		},
+	)
+}
+
+// seedAdmin creates the administrator unless a user with its email exists. A
+// soft deleted administrator keeps the email taken and is not recreated; purge
+// it to seed a new one.
+func seedAdmin(ctx context.Context, users domain.UserRepository, options Options) error {
+	if options.AdminEmail == "" || options.AdminPassword == "" {
+		return nil
+	}
+
+	existing, err := users.ExistingEmails(ctx, []string{options.AdminEmail})
+	if err != nil || existing[options.AdminEmail] {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(options.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return users.Store(ctx, &domain.User{
+		ID:       uuid.NewString(),
+		Name:     "Administrator",
+		Email:    options.AdminEmail,
+		Password: string(hashed),
+	})
+}
+
+// seedDemoUsers creates count fake users. The same seed produces the same
+// users, so a rerun only adds the ones that are missing.
+func seedDemoUsers(ctx context.Context, users domain.UserRepository, fake *seeder.Faker, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	// Demo accounts share a password hashed at the lowest cost to seed quickly
+	hashed, err := bcrypt.GenerateFromPassword([]byte(demoPassword), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+
+	demo := make([]*domain.User, 0, count)
+	emails := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		name := fake.Name()
+		user := &domain.User{ID: fake.UUID(), Name: name, Email: fake.Email(name), Password: string(hashed)}
+		demo = append(demo, user)
+		emails = append(emails, user.Email)
+	}
+
+	existing, err := users.ExistingEmails(ctx, emails)
+	if err != nil {
+		return err
+	}
+	var missing []*domain.User
+	for _, user := range demo {
+		if !existing[user.Email] {
+			missing = append(missing, user)
+		}
+	}
+	return users.StoreBatch(ctx, missing)
+}
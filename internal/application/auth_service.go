@@ -0,0 +1,40 @@
+package application
+
+import (
+	"context"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/usecase"
+)
+
+// AuthService provides application-level operations for authentication
+type AuthService struct {
+	authUsecase usecase.AuthUsecaseInterface
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(authUsecase usecase.AuthUsecaseInterface) *AuthService {
+	return &AuthService{
+		authUsecase: authUsecase,
+	}
+}
+
+// Login authenticates a user and returns tokens
+func (s *AuthService) Login(ctx context.Context, credentials *domain.Credentials) (*domain.TokenResponse, error) {
+	return s.authUsecase.Login(ctx, credentials)
+}
+
+// RefreshToken issues new tokens from a refresh token
+func (s *AuthService) RefreshToken(refreshToken string) (*domain.TokenResponse, error) {
+	return s.authUsecase.RefreshToken(refreshToken)
+}
+
+// Logout invalidates the given access token
+func (s *AuthService) Logout(accessToken string) error {
+	return s.authUsecase.Logout(accessToken)
+}
+
+// ValidateToken checks an access token and returns its claims
+func (s *AuthService) ValidateToken(accessToken string) (*domain.JWTClaims, error) {
+	return s.authUsecase.ValidateToken(accessToken)
+}
@@ -0,0 +1,60 @@
+package application
+
+import (
+	"context"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/usecase"
+)
+
+// UserService provides application-level operations for users
+type UserService struct {
+	userUsecase usecase.UserUsecaseInterface
+}
+
+// NewUserService creates a new user service
+func NewUserService(userUsecase usecase.UserUsecaseInterface) *UserService {
+	return &UserService{
+		userUsecase: userUsecase,
+	}
+}
+
+// GetUserByID retrieves a user by their ID
+func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return s.userUsecase.GetUserByID(ctx, id)
+}
+
+// GetUserByEmail retrieves a user by their email
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return s.userUsecase.GetUserByEmail(ctx, email)
+}
+
+// CreateUser creates a new user
+func (s *UserService) CreateUser(ctx context.Context, user *domain.User) error {
+	return s.userUsecase.CreateUser(ctx, user)
+}
+
+// UpdateUser updates an existing user
+func (s *UserService) UpdateUser(ctx context.Context, user *domain.User) error {
+	return s.userUsecase.UpdateUser(ctx, user)
+}
+
+// DeleteUser deletes a user by their ID
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	return s.userUsecase.DeleteUser(ctx, id)
+}
+
+// ListUsers returns a page of users matching the filter
+func (s *UserService) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	return s.userUsecase.ListUsers(ctx, filter)
+}
+
+// ListUsersByCursor returns the keyset page identified by cursor
+func (s *UserService) ListUsersByCursor(ctx context.Context, filter domain.UserFilter, cursor string) (*domain.UserList, error) {
+	return s.userUsecase.ListUsersByCursor(ctx, filter, cursor)
+}
+
+// WatchUsers streams user changes until the context is cancelled
+func (s *UserService) WatchUsers(ctx context.Context) <-chan domain.UserChange {
+	return s.userUsecase.WatchUsers(ctx)
+}
@@ -37,7 +37,7 @@ func (s *AuthServiceServer) Login(ctx context.Context, req *v1.LoginRequest) (*v
 	}
 
 	// Authenticate user
-	tokenResponse, err := s.authService.Login(credentials)
+	tokenResponse, err := s.authService.Login(ctx, credentials)
 	if err != nil {
 		s.logger.Error("gRPC: Login failed", zap.String("email", req.GetCredentials().GetEmail()), zap.Error(err))
 		return &v1.LoginResponse{
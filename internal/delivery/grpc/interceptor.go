@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	v1 "app-hexagonal/api/v1"
+	"app-hexagonal/internal/application"
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// principalMethods are the methods that only an authenticated principal may call
+var principalMethods = map[string]bool{
+	v1.UserService_UpdateUser_FullMethodName: true,
+	v1.UserService_DeleteUser_FullMethodName: true,
+	v1.UserService_ListUsers_FullMethodName:  true,
+	v1.UserService_WatchUsers_FullMethodName: true,
+}
+
+// contextStream is a server stream whose context is replaced by an interceptor
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// readYourWritesInterceptor gives every unary call its own read-your-writes
+// session, so reads that follow a write in the same call use the primary database
+func readYourWritesInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(gormpkg.WithReadYourWrites(ctx), req)
+}
+
+// readYourWritesStreamInterceptor gives every streaming call its own
+// read-your-writes session
+func readYourWritesStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: gormpkg.WithReadYourWrites(ss.Context())})
+}
+
+// requestContextInterceptor carries the request ID, trace ID and tenant of a
+// call, from its x-request-id, traceparent and x-tenant-id metadata, in its
+// context. A call without a request ID is given a new one.
+func requestContextInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestContext(ctx), req)
+}
+
+// requestContextStreamInterceptor is requestContextInterceptor for streaming calls
+func requestContextStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestContext(ss.Context())})
+}
+
+// withRequestContext returns ctx with the request ID, trace ID and tenant of
+// the incoming metadata
+func withRequestContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := uuid.NewString()
+	if values := md.Get("x-request-id"); len(values) > 0 && values[0] != "" {
+		requestID = values[0]
+	}
+	ctx = domain.WithRequestID(ctx, requestID)
+
+	if values := md.Get("traceparent"); len(values) > 0 {
+		if traceID := domain.TraceIDFromTraceparent(values[0]); traceID != "" {
+			ctx = domain.WithTraceID(ctx, traceID)
+		}
+	}
+	if values := md.Get("x-tenant-id"); len(values) > 0 && values[0] != "" {
+		ctx = domain.WithTenant(ctx, values[0])
+	}
+	return ctx
+}
+
+// principalInterceptor makes the user a bearer token in the authorization
+// metadata was issued to the principal of the call, and the tenant it was
+// issued for its tenant. Calls without a token run without a principal, but an
+// invalid token, or one used with the x-tenant-id of another tenant, is rejected.
+// Only the auth service, which issues the tokens, takes the tenant of a call
+// without a token from x-tenant-id; any other such call naming a tenant is
+// rejected, so the tenant of its data always comes from a token. The methods
+// in principalMethods require a token.
+func principalInterceptor(authService *application.AuthService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, authService)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// principalStreamInterceptor is principalInterceptor for streaming calls
+func principalStreamInterceptor(authService *application.AuthService) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod, authService)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate returns ctx with the principal and tenant of the bearer token
+// in the authorization metadata of a call to method
+func authenticate(ctx context.Context, method string, authService *application.AuthService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		if principalMethods[method] {
+			return nil, status.Error(codes.Unauthenticated, "missing access token")
+		}
+		if domain.TenantFromContext(ctx) != "" && !strings.HasPrefix(method, "/"+v1.AuthService_ServiceDesc.ServiceName+"/") {
+			return nil, status.Error(codes.Unauthenticated, "x-tenant-id requires an access token")
+		}
+		return ctx, nil
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+	claims, err := authService.ValidateToken(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired access token")
+	}
+	if tenant := domain.TenantFromContext(ctx); tenant != "" && tenant != claims.TenantID {
+		return nil, status.Error(codes.PermissionDenied, "access token was not issued for this tenant")
+	}
+	ctx = domain.WithTenant(ctx, claims.TenantID)
+
+	return domain.WithPrincipal(ctx, claims.UserID), nil
+}
@@ -28,8 +28,25 @@ func NewServer(logger *zap.Logger, port string) *Server {
 
 // Start starts the gRPC server
 func (s *Server) Start(userService *application.UserService, authService *application.AuthService) error {
+	// Listen on the specified port
+	lis, err := net.Listen("tcp", ":"+s.port)
+	if err != nil {
+		s.logger.Error("Failed to listen", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Starting gRPC server", zap.String("port", s.port))
+
+	return s.Serve(lis, userService, authService)
+}
+
+// Serve serves the user and auth services on lis until the server is stopped
+func (s *Server) Serve(lis net.Listener, userService *application.UserService, authService *application.AuthService) error {
 	// Create a new gRPC server
-	s.server = grpc.NewServer()
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(requestContextInterceptor, readYourWritesInterceptor, principalInterceptor(authService)),
+		grpc.ChainStreamInterceptor(requestContextStreamInterceptor, readYourWritesStreamInterceptor, principalStreamInterceptor(authService)),
+	)
 
 	// Register the user service
 	userServiceServer := NewUserServiceServer(userService, s.logger)
@@ -42,15 +59,6 @@ func (s *Server) Start(userService *application.UserService, authService *applic
 	// Enable reflection for debugging
 	reflection.Register(s.server)
 
-	// Listen on the specified port
-	lis, err := net.Listen("tcp", ":"+s.port)
-	if err != nil {
-		s.logger.Error("Failed to listen", zap.Error(err))
-		return err
-	}
-
-	s.logger.Info("Starting gRPC server", zap.String("port", s.port))
-
 	// Start serving
 	if err := s.server.Serve(lis); err != nil {
 		s.logger.Error("Failed to serve", zap.Error(err))
@@ -2,13 +2,18 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	v1 "app-hexagonal/api/v1"
 	"app-hexagonal/internal/application"
 	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/queryspec"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // UserServiceServer implements the UserService gRPC service
@@ -30,7 +35,7 @@ func NewUserServiceServer(userService *application.UserService, logger *zap.Logg
 func (s *UserServiceServer) GetUser(ctx context.Context, req *v1.GetUserRequest) (*v1.GetUserResponse, error) {
 	s.logger.Info("gRPC: Getting user by ID", zap.String("user_id", req.GetId()))
 
-	user, err := s.userService.GetUserByID(req.GetId())
+	user, err := s.userService.GetUserByID(ctx, req.GetId())
 	if err != nil {
 		s.logger.Error("gRPC: Failed to get user", zap.String("user_id", req.GetId()), zap.Error(err))
 		return &v1.GetUserResponse{
@@ -40,20 +45,13 @@ func (s *UserServiceServer) GetUser(ctx context.Context, req *v1.GetUserRequest)
 		}, nil
 	}
 
-	// Convert domain user to protobuf user
-	protoUser := &v1.User{
-		Id:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-	}
-
 	s.logger.Info("gRPC: Successfully retrieved user", zap.String("user_id", user.ID))
 
 	return &v1.GetUserResponse{
 		Error:   false,
 		Code:    int32(codes.OK),
 		Message: "User retrieved successfully",
-		Data:    protoUser,
+		Data:    toProtoUser(user),
 	}, nil
 }
 
@@ -61,17 +59,23 @@ func (s *UserServiceServer) GetUser(ctx context.Context, req *v1.GetUserRequest)
 func (s *UserServiceServer) CreateUser(ctx context.Context, req *v1.CreateUserRequest) (*v1.CreateUserResponse, error) {
 	s.logger.Info("gRPC: Creating new user", zap.String("user_name", req.GetName()), zap.String("user_email", req.GetEmail()))
 
-	// Create domain user
+	// Create domain user; the usecase assigns the ID
 	user := &domain.User{
-		ID:    "", // ID will be generated by the repository
 		Name:  req.GetName(),
 		Email: req.GetEmail(),
 	}
 
 	// Save user
-	err := s.userService.CreateUser(user)
+	err := s.userService.CreateUser(ctx, user)
 	if err != nil {
 		s.logger.Error("gRPC: Failed to create user", zap.Error(err))
+		if errors.Is(err, domain.ErrDuplicateEmail) {
+			return &v1.CreateUserResponse{
+				Error:   true,
+				Code:    int32(codes.AlreadyExists),
+				Message: "Email already in use",
+			}, nil
+		}
 		return &v1.CreateUserResponse{
 			Error:   true,
 			Code:    int32(codes.Internal),
@@ -79,19 +83,270 @@ func (s *UserServiceServer) CreateUser(ctx context.Context, req *v1.CreateUserRe
 		}, nil
 	}
 
-	// Convert domain user to protobuf user
-	protoUser := &v1.User{
-		Id:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-	}
-
 	s.logger.Info("gRPC: User created successfully", zap.String("user_id", user.ID))
 
 	return &v1.CreateUserResponse{
 		Error:   false,
 		Code:    int32(codes.OK),
 		Message: "User created successfully",
-		Data:    protoUser,
+		Data:    toProtoUser(user),
+	}, nil
+}
+
+// UpdateUser updates an existing user
+func (s *UserServiceServer) UpdateUser(ctx context.Context, req *v1.UpdateUserRequest) (*v1.UpdateUserResponse, error) {
+	s.logger.Info("gRPC: Updating user", zap.String("user_id", req.GetId()))
+
+	user, err := s.userService.GetUserByID(ctx, req.GetId())
+	if err != nil {
+		s.logger.Error("gRPC: Failed to get user", zap.String("user_id", req.GetId()), zap.Error(err))
+		return &v1.UpdateUserResponse{
+			Error:   true,
+			Code:    int32(codes.NotFound),
+			Message: "User not found",
+		}, nil
+	}
+
+	// A client supplied version turns the update into a conditional one
+	if req.GetVersion() != 0 {
+		user.Version = req.GetVersion()
+	}
+	if req.GetName() != "" {
+		user.Name = req.GetName()
+	}
+	if req.GetEmail() != "" {
+		user.Email = req.GetEmail()
+	}
+
+	if err := s.userService.UpdateUser(ctx, user); err != nil {
+		s.logger.Error("gRPC: Failed to update user", zap.String("user_id", req.GetId()), zap.Error(err))
+
+		switch {
+		case errors.Is(err, domain.ErrVersionConflict):
+			return &v1.UpdateUserResponse{
+				Error:   true,
+				Code:    int32(codes.Aborted),
+				Message: "User has been modified by another request",
+			}, nil
+		case errors.Is(err, domain.ErrUserNotFound):
+			return &v1.UpdateUserResponse{
+				Error:   true,
+				Code:    int32(codes.NotFound),
+				Message: "User not found",
+			}, nil
+		case errors.Is(err, domain.ErrDuplicateEmail):
+			return &v1.UpdateUserResponse{
+				Error:   true,
+				Code:    int32(codes.AlreadyExists),
+				Message: "Email already in use",
+			}, nil
+		default:
+			return &v1.UpdateUserResponse{
+				Error:   true,
+				Code:    int32(codes.Internal),
+				Message: "Failed to update user",
+			}, nil
+		}
+	}
+
+	s.logger.Info("gRPC: User updated successfully", zap.String("user_id", user.ID), zap.Int64("version", user.Version))
+
+	return &v1.UpdateUserResponse{
+		Error:   false,
+		Code:    int32(codes.OK),
+		Message: "User updated successfully",
+		Data:    toProtoUser(user),
+	}, nil
+}
+
+// DeleteUser deletes a user by ID
+func (s *UserServiceServer) DeleteUser(ctx context.Context, req *v1.DeleteUserRequest) (*v1.DeleteUserResponse, error) {
+	s.logger.Info("gRPC: Deleting user", zap.String("user_id", req.GetId()))
+
+	if _, err := s.userService.GetUserByID(ctx, req.GetId()); err != nil {
+		s.logger.Error("gRPC: Failed to get user", zap.String("user_id", req.GetId()), zap.Error(err))
+		return &v1.DeleteUserResponse{
+			Error:   true,
+			Code:    int32(codes.NotFound),
+			Message: "User not found",
+		}, nil
+	}
+
+	if err := s.userService.DeleteUser(ctx, req.GetId()); err != nil {
+		s.logger.Error("gRPC: Failed to delete user", zap.String("user_id", req.GetId()), zap.Error(err))
+		return &v1.DeleteUserResponse{
+			Error:   true,
+			Code:    int32(codes.Internal),
+			Message: "Failed to delete user",
+		}, nil
+	}
+
+	s.logger.Info("gRPC: User deleted successfully", zap.String("user_id", req.GetId()))
+
+	return &v1.DeleteUserResponse{
+		Error:   false,
+		Code:    int32(codes.OK),
+		Message: "User deleted successfully",
 	}, nil
 }
+
+// ListUsers returns a page of users
+func (s *UserServiceServer) ListUsers(ctx context.Context, req *v1.ListUsersRequest) (*v1.ListUsersResponse, error) {
+	s.logger.Info("gRPC: Listing users", zap.Int32("page", req.GetPage()), zap.Int32("page_size", req.GetPageSize()))
+
+	var filters []queryspec.RawFilter
+	for _, f := range req.GetFilters() {
+		filters = append(filters, queryspec.RawFilter{Field: f.GetField(), Operator: f.GetOperator(), Value: f.GetValue()})
+	}
+	query, err := domain.UserQuerySchema.Parse(req.GetSort(), filters)
+	if err != nil {
+		return &v1.ListUsersResponse{
+			Error:   true,
+			Code:    int32(codes.InvalidArgument),
+			Message: err.Error(),
+		}, nil
+	}
+
+	filter := domain.UserFilter{
+		Page:          int(req.GetPage()),
+		PageSize:      int(req.GetPageSize()),
+		Search:        req.GetSearch(),
+		CreatedAfter:  fromProtoTimestamp(req.GetCreatedAfter()),
+		CreatedBefore: fromProtoTimestamp(req.GetCreatedBefore()),
+		UpdatedAfter:  fromProtoTimestamp(req.GetUpdatedAfter()),
+		UpdatedBefore: fromProtoTimestamp(req.GetUpdatedBefore()),
+		Query:         query,
+	}
+
+	var list *domain.UserList
+	if req.Cursor != nil {
+		list, err = s.userService.ListUsersByCursor(ctx, filter, req.GetCursor())
+	} else {
+		list, err = s.userService.ListUsers(ctx, filter)
+	}
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return &v1.ListUsersResponse{
+			Error:   true,
+			Code:    int32(codes.InvalidArgument),
+			Message: "Invalid cursor",
+		}, nil
+	}
+	if errors.Is(err, queryspec.ErrInvalid) {
+		return &v1.ListUsersResponse{
+			Error:   true,
+			Code:    int32(codes.InvalidArgument),
+			Message: err.Error(),
+		}, nil
+	}
+	if err != nil {
+		s.logger.Error("gRPC: Failed to list users", zap.Error(err))
+		return &v1.ListUsersResponse{
+			Error:   true,
+			Code:    int32(codes.Internal),
+			Message: "Failed to list users",
+		}, nil
+	}
+
+	users := make([]*v1.User, 0, len(list.Users))
+	for i := range list.Users {
+		users = append(users, toProtoUser(&list.Users[i]))
+	}
+
+	page := &v1.PageInfo{
+		PageSize:       int32(list.PageSize),
+		HasNext:        list.HasNext,
+		HasPrevious:    list.HasPrevious,
+		NextCursor:     list.NextCursor,
+		PreviousCursor: list.PreviousCursor,
+	}
+	if req.Cursor == nil {
+		totalPages := int32(0)
+		if list.PageSize > 0 {
+			totalPages = int32((list.Total + int64(list.PageSize) - 1) / int64(list.PageSize))
+		}
+		page.CurrentPage = int32(list.Page)
+		page.TotalRecords = list.Total
+		page.TotalPages = totalPages
+		page.HasNext = int32(list.Page) < totalPages
+		page.HasPrevious = list.Page > 1
+	}
+
+	return &v1.ListUsersResponse{
+		Error:   false,
+		Code:    int32(codes.OK),
+		Message: "Users retrieved successfully",
+		Data:    users,
+		Page:    page,
+	}, nil
+}
+
+// WatchUsers streams user changes to the client until it disconnects
+func (s *UserServiceServer) WatchUsers(req *v1.WatchUsersRequest, stream v1.UserService_WatchUsersServer) error {
+	s.logger.Info("gRPC: Client started watching users")
+	defer s.logger.Info("gRPC: Client stopped watching users")
+
+	for change := range s.userService.WatchUsers(stream.Context()) {
+		user := change.User
+		err := stream.Send(&v1.WatchUsersResponse{
+			Type:       toProtoUserEventType(change.Type),
+			Data:       toProtoUser(&user),
+			OccurredAt: change.OccurredAt.Unix(),
+		})
+		if err != nil {
+			s.logger.Error("gRPC: Failed to send user change", zap.String("user_id", user.ID), zap.Error(err))
+			return err
+		}
+	}
+
+	// The feed closes watchers that fall behind; tell the client it missed
+	// changes so it can reload and watch again
+	if stream.Context().Err() == nil {
+		s.logger.Warn("gRPC: Closed watcher that fell behind")
+		return status.Error(codes.ResourceExhausted, domain.ErrWatcherTooSlow.Error())
+	}
+	return nil
+}
+
+// toProtoUser converts a domain user to a protobuf user
+func toProtoUser(user *domain.User) *v1.User {
+	return &v1.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Version:   user.Version,
+		CreatedAt: toProtoTimestamp(user.CreatedAt),
+		UpdatedAt: toProtoTimestamp(user.UpdatedAt),
+		CreatedBy: user.CreatedBy,
+		UpdatedBy: user.UpdatedBy,
+	}
+}
+
+// toProtoTimestamp converts a time to a protobuf timestamp, leaving zero times unset
+func toProtoTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromProtoTimestamp converts an optional protobuf timestamp, mapping unset to the zero time
+func fromProtoTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// toProtoUserEventType converts a domain change type to its protobuf enum
+func toProtoUserEventType(changeType domain.UserChangeType) v1.UserEventType {
+	switch changeType {
+	case domain.UserChangeCreated:
+		return v1.UserEventType_USER_EVENT_TYPE_CREATED
+	case domain.UserChangeUpdated:
+		return v1.UserEventType_USER_EVENT_TYPE_UPDATED
+	case domain.UserChangeDeleted:
+		return v1.UserEventType_USER_EVENT_TYPE_DELETED
+	default:
+		return v1.UserEventType_USER_EVENT_TYPE_UNSPECIFIED
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+)
+
+// AuditHandler serves the audit trail of changes to the audited entities
+type AuditHandler struct {
+	uc     usecase.AuditUsecaseInterface
+	logger *zap.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(uc usecase.AuditUsecaseInterface, logger *zap.Logger) *AuditHandler {
+	return &AuditHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+// ListEntries returns a page of audit entries, newest first. Supported query
+// parameters are entity_type, entity_id, actor_id, from, to, page and
+// page_size; from and to are RFC 3339 timestamps bounding the time of the change.
+func (h *AuditHandler) ListEntries(c *fiber.Ctx) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			err.Error()))
+	}
+
+	list, err := h.uc.ListEntries(c.UserContext(), filter)
+	if errors.Is(err, domain.ErrInvalidAuditFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			err.Error()))
+	}
+	if err != nil {
+		h.logger.Error("Failed to list audit entries",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to list audit entries"))
+	}
+
+	totalPages := 0
+	if list.PageSize > 0 {
+		totalPages = int((list.Total + int64(list.PageSize) - 1) / int64(list.PageSize))
+	}
+
+	return c.JSON(helper.SuccessResponseWithMetadata(list.Entries,
+		fiber.StatusOK,
+		"Audit entries retrieved successfully",
+		helper.Metadata{
+			Page: &helper.PageInfo{
+				CurrentPage:  list.Page,
+				PageSize:     list.PageSize,
+				TotalRecords: int(list.Total),
+				TotalPages:   totalPages,
+				HasNext:      list.Page < totalPages,
+				HasPrevious:  list.Page > 1,
+			},
+		}))
+}
+
+// RegisterRoutes registers the audit routes
+func (h *AuditHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/audit", h.ListEntries)
+}
+
+// parseAuditFilter reads the audit entry filters from the query string
+func parseAuditFilter(c *fiber.Ctx) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		ActorID:    c.Query("actor_id"),
+		Page:       c.QueryInt("page", 1),
+		PageSize:   c.QueryInt("page_size", 20),
+	}
+
+	bounds := []struct {
+		param  string
+		target *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	}
+	for _, bound := range bounds {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.param)
+		}
+		*bound.target = parsed
+	}
+	return filter, nil
+}
@@ -78,7 +78,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 			Password: req.Password,
 		}
 
-		return h.authUsecase.Login(credentials)
+		return h.authUsecase.Login(c.UserContext(), credentials)
 	})
 
 	if err != nil {
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/storage"
+)
+
+// FileHandler serves objects from local storage through signed URLs. It is only
+// needed for the local storage driver; S3 signed URLs point at the bucket.
+type FileHandler struct {
+	storage *storage.LocalStorage
+	logger  *zap.Logger
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(storage *storage.LocalStorage, logger *zap.Logger) *FileHandler {
+	return &FileHandler{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// GetFile streams the object if the expires and signature query parameters are valid
+func (h *FileHandler) GetFile(c *fiber.Ctx) error {
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Invalid file path"))
+	}
+
+	if err := h.storage.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(helper.ErrorResponse(nil,
+			fiber.StatusForbidden,
+			"Link is invalid or has expired"))
+	}
+
+	reader, info, err := h.storage.Get(c.UserContext(), key)
+	if err != nil {
+		if errors.Is(err, domain.ErrObjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+				fiber.StatusNotFound,
+				"File Not Found"))
+		}
+		h.logger.Error("Failed to read file",
+			zap.String("key", key),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to read file"))
+	}
+
+	if info.ContentType != "" {
+		c.Set(fiber.HeaderContentType, info.ContentType)
+	}
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+
+	// Fiber closes the reader once the body has been written
+	return c.SendStream(reader, int(info.Size))
+}
+
+// RegisterRoutes registers the file routes
+func (h *FileHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/files/*", h.GetFile)
+}
@@ -3,10 +3,20 @@ package middleware
 import (
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
 )
 
-// AuthMiddleware provides basic authentication middleware
-func AuthMiddleware(logger *zap.Logger) fiber.Handler {
+// TokenValidator checks an access token and returns its claims
+type TokenValidator interface {
+	ValidateToken(token string) (*domain.JWTClaims, error)
+}
+
+// AuthMiddleware provides basic authentication middleware. When validator is set
+// the bearer token must be valid, and the user it was issued to becomes the
+// principal of the request. The tenant of the request is then the one the
+// token was issued for; a request whose X-Tenant-ID names another is rejected.
+func AuthMiddleware(logger *zap.Logger, validator TokenValidator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// In a real implementation, you would check for a valid JWT token or session
 		// For this example, we'll just check for an Authorization header
@@ -38,15 +48,46 @@ func AuthMiddleware(logger *zap.Logger) fiber.Handler {
 			})
 		}
 
+		if validator != nil {
+			claims, err := validator.ValidateToken(authHeader[7:])
+			if err != nil {
+				logger.Warn("Invalid access token",
+					zap.String("path", c.Path()),
+					zap.String("ip", c.IP()),
+					zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+				)
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error":   "Unauthorized",
+					"message": "Invalid or expired access token",
+				})
+			}
+
+			ctx := c.UserContext()
+			if tenant := domain.TenantFromContext(ctx); tenant != "" && tenant != claims.TenantID {
+				logger.Warn("Access token used for another tenant",
+					zap.String("path", c.Path()),
+					zap.String("ip", c.IP()),
+					zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+				)
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":   "Forbidden",
+					"message": "Access token was not issued for this tenant",
+				})
+			}
+			if claims.TenantID != "" {
+				ctx = domain.WithTenant(ctx, claims.TenantID)
+			}
+
+			c.Locals("user_id", claims.UserID)
+			c.SetUserContext(domain.WithPrincipal(ctx, claims.UserID))
+		}
+
 		// Log successful authentication
 		logger.Info("Authentication successful",
 			zap.String("path", c.Path()),
 			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
 		)
 
-		// Add user info to context if needed
-		// c.Locals("user_id", userID)
-
 		return c.Next()
 	}
 }
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimitMiddleware rejects request bodies larger than limit bytes. The
+// server streams request bodies so that uploads such as user imports are not
+// held in memory; every other path is still limited here, and a body of
+// unknown length is read up to the limit. streamed lists the paths that may
+// send more because their handlers read the body as a stream.
+func BodyLimitMiddleware(limit int, streamed ...string) fiber.Handler {
+	unlimited := make(map[string]bool, len(streamed))
+	for _, path := range streamed {
+		unlimited[path] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if limit <= 0 || unlimited[c.Path()] {
+			return c.Next()
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length > limit {
+			return bodyTooLarge(c)
+		}
+		if length < 0 && c.Request().IsBodyStream() {
+			body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+			if err != nil {
+				return err
+			}
+			if len(body) > limit {
+				return bodyTooLarge(c)
+			}
+			c.Request().SetBody(body)
+		}
+		return c.Next()
+	}
+}
+
+// bodyTooLarge refuses the request. The rest of its body is not read, so the
+// connection cannot be reused for another request.
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error":   "Request Entity Too Large",
+		"message": "Request body is too large",
+	})
+}
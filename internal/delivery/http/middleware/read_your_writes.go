@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+)
+
+// ReadYourWritesMiddleware gives every request its own read-your-writes session,
+// so reads that follow a write in the same request are served by the primary
+// database instead of a replica that may lag behind
+func ReadYourWritesMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(gormpkg.WithReadYourWrites(c.UserContext()))
+		return c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"app-hexagonal/internal/domain"
+)
+
+const (
+	// RequestIDHeader carries the ID of a request to and from the client
+	RequestIDHeader = "X-Request-ID"
+	// TenantHeader carries the ID of the tenant a request is made for. Once
+	// authenticated, the tenant comes from the access token instead, which
+	// the header must not contradict.
+	TenantHeader = "X-Tenant-ID"
+)
+
+// RequestContextMiddleware carries the request ID and trace ID of a request in
+// its user context, so they reach the logs written below the handlers. A
+// request without an X-Request-ID header is given a new ID, which is also set
+// on the request so the handlers log the same one, and echoed in the response.
+// The trace ID is read from a W3C traceparent header and the tenant from X-Tenant-ID.
+func RequestContextMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewString()
+			c.Request().Header.Set(RequestIDHeader, requestID)
+		}
+		c.Set(RequestIDHeader, requestID)
+
+		ctx := domain.WithRequestID(c.UserContext(), requestID)
+		if traceID := domain.TraceIDFromTraceparent(c.Get("traceparent")); traceID != "" {
+			ctx = domain.WithTraceID(ctx, traceID)
+		}
+		if tenant := c.Get(TenantHeader); tenant != "" {
+			ctx = domain.WithTenant(ctx, tenant)
+		}
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+)
+
+// PrivacyHandler handles data subject access and erasure requests
+type PrivacyHandler struct {
+	uc     usecase.PrivacyUsecaseInterface
+	logger *zap.Logger
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(uc usecase.PrivacyUsecaseInterface, logger *zap.Logger) *PrivacyHandler {
+	return &PrivacyHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+// ExportPersonalData downloads everything held about the user as a ZIP archive,
+// or as a single JSON document with format=json
+func (h *PrivacyHandler) ExportPersonalData(c *fiber.Ctx) error {
+	id := c.Params("id")
+	requestID := c.Get("X-Request-ID", "unknown")
+
+	format, err := usecase.ParsePrivacyExportFormat(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Unsupported export format, use zip or json"))
+	}
+
+	// The archive is buffered so a failure can still be reported as an error response
+	var archive bytes.Buffer
+	if err := h.uc.ExportPersonalData(c.UserContext(), id, format, &archive); err != nil {
+		h.logger.Error("Failed to export personal data",
+			zap.String("user_id", id),
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return h.errorResponse(c, err, "Failed to export personal data")
+	}
+
+	h.logger.Info("Exported personal data",
+		zap.String("user_id", id),
+		zap.String("request_id", requestID),
+		zap.String("format", string(format)),
+	)
+
+	contentType := "application/zip"
+	if format == domain.PrivacyExportJSON {
+		contentType = fiber.MIMEApplicationJSON
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="personal-data-%s.%s"`, id, format))
+	return c.Send(archive.Bytes())
+}
+
+// RequestErasure schedules the user's data for erasure after the grace period
+func (h *PrivacyHandler) RequestErasure(c *fiber.Ctx) error {
+	id := c.Params("id")
+	requestID := c.Get("X-Request-ID", "unknown")
+
+	request, err := h.uc.RequestErasure(c.UserContext(), id)
+	if err != nil {
+		h.logger.Error("Failed to request erasure",
+			zap.String("user_id", id),
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return h.errorResponse(c, err, "Failed to request erasure")
+	}
+
+	h.logger.Info("Erasure scheduled",
+		zap.String("user_id", id),
+		zap.String("request_id", requestID),
+		zap.String("privacy_request_id", request.ID),
+		zap.Time("scheduled_for", request.ScheduledFor),
+	)
+
+	c.Location("/privacy/requests/" + request.ID)
+	return c.Status(fiber.StatusAccepted).JSON(helper.SuccessResponse(request,
+		fiber.StatusAccepted,
+		"Erasure scheduled"))
+}
+
+// GetRequest returns the status of a privacy request
+func (h *PrivacyHandler) GetRequest(c *fiber.Ctx) error {
+	request, err := h.uc.GetRequest(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.errorResponse(c, err, "Failed to get privacy request")
+	}
+
+	return c.JSON(helper.SuccessResponse(request, fiber.StatusOK, "Privacy request retrieved successfully"))
+}
+
+// CancelRequest cancels an erasure during its grace period
+func (h *PrivacyHandler) CancelRequest(c *fiber.Ctx) error {
+	id := c.Params("id")
+	requestID := c.Get("X-Request-ID", "unknown")
+
+	request, err := h.uc.CancelErasure(c.UserContext(), id)
+	if err != nil {
+		h.logger.Warn("Failed to cancel erasure",
+			zap.String("privacy_request_id", id),
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return h.errorResponse(c, err, "Failed to cancel erasure")
+	}
+
+	h.logger.Info("Erasure cancelled",
+		zap.String("privacy_request_id", id),
+		zap.String("request_id", requestID),
+	)
+
+	return c.JSON(helper.SuccessResponse(request, fiber.StatusOK, "Erasure cancelled"))
+}
+
+// ListErasureRecords returns the verified record of every completed erasure
+func (h *PrivacyHandler) ListErasureRecords(c *fiber.Ctx) error {
+	records, err := h.uc.ErasureRecords(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to list erasure records",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return h.errorResponse(c, err, "Failed to list erasure records")
+	}
+
+	return c.JSON(helper.SuccessResponse(records, fiber.StatusOK, "Erasure records retrieved successfully"))
+}
+
+// RegisterRoutes registers the privacy routes
+func (h *PrivacyHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/users/:id/privacy/export", h.ExportPersonalData)
+	app.Post("/users/:id/privacy/erasure", h.RequestErasure)
+	app.Get("/privacy/requests/:id", h.GetRequest)
+	app.Post("/privacy/requests/:id/cancel", h.CancelRequest)
+	app.Get("/privacy/erasures", h.ListErasureRecords)
+}
+
+// errorResponse maps a privacy error to an HTTP response
+func (h *PrivacyHandler) errorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+			fiber.StatusNotFound,
+			"User Not Found"))
+	case errors.Is(err, domain.ErrPrivacyRequestNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+			fiber.StatusNotFound,
+			"Privacy request not found"))
+	case errors.Is(err, domain.ErrErasureAlreadyRequested):
+		return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(nil,
+			fiber.StatusConflict,
+			"An erasure is already scheduled for this user"))
+	case errors.Is(err, domain.ErrPrivacyRequestState):
+		return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(nil,
+			fiber.StatusConflict,
+			"Privacy request can no longer be cancelled"))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			message))
+	}
+}
@@ -9,19 +9,38 @@ import (
 
 	"app-hexagonal/internal/delivery/http"
 	"app-hexagonal/internal/delivery/http/middleware"
+	gormpkg "app-hexagonal/pkg/gorm"
 )
 
 type RouteConfig struct {
-	App         *fiber.App
-	UserHandler *http.UserHandler
-	AuthHandler *http.AuthHandler
-	Logger      *zap.Logger
+	App                *fiber.App
+	UserHandler        *http.UserHandler
+	UserImportHandler  *http.UserImportHandler
+	UserExportHandler  *http.UserExportHandler
+	UserAvatarHandler  *http.UserAvatarHandler
+	PreferencesHandler *http.UserPreferencesHandler
+	FileHandler        *http.FileHandler
+	PrivacyHandler     *http.PrivacyHandler
+	AuditHandler       *http.AuditHandler
+	AuthHandler        *http.AuthHandler
+	// TokenValidator checks bearer tokens on the authenticated routes and
+	// identifies the principal recorded on the rows they change
+	TokenValidator middleware.TokenValidator
+	// BodyLimit is the largest request body in bytes accepted by every route
+	// but the streamed user import
+	BodyLimit int
+	// QueryMetrics, when set, is served on /metrics for Prometheus to scrape
+	QueryMetrics *gormpkg.QueryHistogram
+	Logger       *zap.Logger
 }
 
 func (c *RouteConfig) Setup() {
 	// Apply global middleware
 	c.App.Use(middleware.CORSMiddleware())
+	c.App.Use(middleware.RequestContextMiddleware())
 	c.App.Use(middleware.LoggingMiddleware(c.Logger))
+	c.App.Use(middleware.ReadYourWritesMiddleware())
+	c.App.Use(middleware.BodyLimitMiddleware(c.BodyLimit, http.UserImportPath))
 
 	c.SetupGuestRoute()
 	c.SetupAuthRoute()
@@ -37,18 +56,46 @@ func (c *RouteConfig) SetupGuestRoute() {
 	// Health check endpoints
 	c.App.Get("/health", c.HealthCheck)
 	c.App.Get("/ready", c.ReadinessCheck)
+	if c.QueryMetrics != nil {
+		c.App.Get("/metrics", c.Metrics)
+	}
 
 	// Authentication routes
 	if c.AuthHandler != nil {
 		c.AuthHandler.RegisterRoutes(c.App)
 	}
+
+	// Signed file links are authorized by their signature, not a token
+	if c.FileHandler != nil {
+		c.FileHandler.RegisterRoutes(c.App)
+	}
 }
 
 func (c *RouteConfig) SetupAuthRoute() {
-	// Protected routes would go here
-	// For example:
-	// c.App.Use(middleware.AuthMiddleware(c.Logger))
-	// c.UserHandler.RegisterRoutes(c.App)
+	if c.UserHandler == nil {
+		return
+	}
+
+	c.App.Use(middleware.AuthMiddleware(c.Logger, c.TokenValidator))
+	if c.UserImportHandler != nil {
+		c.UserImportHandler.RegisterRoutes(c.App)
+	}
+	if c.UserExportHandler != nil {
+		c.UserExportHandler.RegisterRoutes(c.App)
+	}
+	if c.UserAvatarHandler != nil {
+		c.UserAvatarHandler.RegisterRoutes(c.App)
+	}
+	if c.PreferencesHandler != nil {
+		c.PreferencesHandler.RegisterRoutes(c.App)
+	}
+	if c.PrivacyHandler != nil {
+		c.PrivacyHandler.RegisterRoutes(c.App)
+	}
+	if c.AuditHandler != nil {
+		c.AuditHandler.RegisterRoutes(c.App)
+	}
+	c.UserHandler.RegisterRoutes(c.App)
 }
 
 // HealthCheck returns the health status of the application
@@ -59,6 +106,12 @@ func (c *RouteConfig) HealthCheck(ctx *fiber.Ctx) error {
 	})
 }
 
+// Metrics writes the database query duration histogram in the Prometheus text format
+func (c *RouteConfig) Metrics(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.QueryMetrics.WritePrometheus(ctx, "db_query_duration_seconds")
+}
+
 // ReadinessCheck returns the readiness status of the application
 // In a real implementation, this would check database connections, cache, etc.
 func (c *RouteConfig) ReadinessCheck(ctx *fiber.Ctx) error {
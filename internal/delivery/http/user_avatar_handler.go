@@ -0,0 +1,130 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+)
+
+// UserAvatarHandler handles user avatar HTTP requests
+type UserAvatarHandler struct {
+	uc     usecase.UserAvatarUsecaseInterface
+	logger *zap.Logger
+}
+
+// NewUserAvatarHandler creates a new user avatar handler
+func NewUserAvatarHandler(uc usecase.UserAvatarUsecaseInterface, logger *zap.Logger) *UserAvatarHandler {
+	return &UserAvatarHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+// UploadAvatar stores the JPEG, PNG or GIF image sent as the "avatar" field of a
+// multipart form and returns signed URLs for the image and its thumbnail
+func (h *UserAvatarHandler) UploadAvatar(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Multipart field \"avatar\" is required"))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		h.logger.Error("Failed to open avatar upload",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Invalid avatar upload"))
+	}
+	defer file.Close()
+
+	h.logger.Info("Uploading user avatar",
+		zap.String("user_id", id),
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.Int64("size", fileHeader.Size),
+	)
+
+	avatar, err := h.uc.UploadAvatar(c.UserContext(), id, file)
+	if err != nil {
+		h.logger.Error("Failed to upload user avatar",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+				fiber.StatusNotFound,
+				"User Not Found"))
+		case errors.Is(err, domain.ErrFileTooLarge):
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(helper.ErrorResponse(nil,
+				fiber.StatusRequestEntityTooLarge,
+				err.Error()))
+		case errors.Is(err, domain.ErrInvalidImage):
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(helper.ErrorResponse(nil,
+				fiber.StatusUnsupportedMediaType,
+				err.Error()))
+		case errors.Is(err, domain.ErrVersionConflict):
+			return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(nil,
+				fiber.StatusConflict,
+				"User has been modified by another request"))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+				fiber.StatusInternalServerError,
+				"Failed to upload avatar"))
+		}
+	}
+
+	return c.JSON(helper.SuccessResponse(avatar, fiber.StatusOK, "Avatar uploaded successfully"))
+}
+
+// GetAvatar redirects to a signed URL of the user's avatar. Pass
+// ?variant=thumbnail for the thumbnail or ?redirect=false to get the URLs as JSON.
+func (h *UserAvatarHandler) GetAvatar(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	avatar, err := h.uc.GetAvatar(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrObjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+				fiber.StatusNotFound,
+				"Avatar Not Found"))
+		}
+		h.logger.Error("Failed to get user avatar",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to get avatar"))
+	}
+
+	if !c.QueryBool("redirect", true) {
+		return c.JSON(helper.SuccessResponse(avatar, fiber.StatusOK, "Avatar retrieved successfully"))
+	}
+
+	target := avatar.URL
+	if c.Query("variant") == "thumbnail" {
+		target = avatar.ThumbnailURL
+	}
+	return c.Redirect(target, fiber.StatusFound)
+}
+
+// RegisterRoutes registers the user avatar routes
+func (h *UserAvatarHandler) RegisterRoutes(app *fiber.App) {
+	app.Put("/users/:id/avatar", h.UploadAvatar)
+	app.Get("/users/:id/avatar", h.GetAvatar)
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+	"app-hexagonal/pkg/xlsx"
+)
+
+// UserExportHandler handles user export HTTP requests
+type UserExportHandler struct {
+	uc     usecase.UserExportUsecaseInterface
+	logger *zap.Logger
+}
+
+// NewUserExportHandler creates a new user export handler
+func NewUserExportHandler(uc usecase.UserExportUsecaseInterface, logger *zap.Logger) *UserExportHandler {
+	return &UserExportHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+// ExportUsers streams users matching the listing filters as CSV, NDJSON or XLSX.
+// Supported query parameters are format, fields (comma separated columns),
+// search and async. With async=true the export is written to disk in the
+// background and a download link is returned once the job completes.
+func (h *UserExportHandler) ExportUsers(c *fiber.Ctx) error {
+	format, err := usecase.ParseExportFormat(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Unsupported export format, use csv, ndjson or xlsx"))
+	}
+
+	columns, err := usecase.ParseExportColumns(c.Query("fields"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			err.Error()))
+	}
+
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			err.Error()))
+	}
+	requestID := c.Get("X-Request-ID", "unknown")
+
+	if c.QueryBool("async") {
+		job, err := h.uc.StartExportJob(c.UserContext(), format, filter, columns)
+		if err != nil {
+			h.logger.Error("Failed to start user export",
+				zap.String("request_id", requestID),
+				zap.Error(err),
+			)
+			return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+				fiber.StatusInternalServerError,
+				"Failed to start export"))
+		}
+
+		h.logger.Info("Started background user export",
+			zap.String("request_id", requestID),
+			zap.String("job_id", job.ID),
+			zap.String("format", string(format)),
+		)
+
+		c.Location("/users/export/" + job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(helper.SuccessResponse(job,
+			fiber.StatusAccepted,
+			"Export started"))
+	}
+
+	h.logger.Info("Streaming user export",
+		zap.String("request_id", requestID),
+		zap.String("format", string(format)),
+		zap.Strings("columns", columns),
+	)
+
+	c.Set(fiber.HeaderContentType, exportContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="users.%s"`, format))
+
+	// The body is produced after the handler returns, when the request context
+	// is gone. The export keeps its values, such as the tenant, and is
+	// cancelled once the client stops reading or the server shuts down.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.UserContext()))
+	shutdown := c.Context().Done()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		go func() {
+			select {
+			case <-shutdown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		exported, err := h.uc.Export(ctx, format, filter, columns, &cancelOnErrorWriter{writer: w, cancel: cancel})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			h.logger.Error("User export stream failed",
+				zap.String("request_id", requestID),
+				zap.Int("exported", exported),
+				zap.Error(err),
+			)
+			return
+		}
+
+		h.logger.Info("User export completed",
+			zap.String("request_id", requestID),
+			zap.Int("exported", exported),
+		)
+	})
+
+	return nil
+}
+
+// GetExportJob returns the status of a background export job
+func (h *UserExportHandler) GetExportJob(c *fiber.Ctx) error {
+	job, err := h.getJob(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.jobErrorResponse(c, err)
+	}
+
+	return c.JSON(helper.SuccessResponse(job, fiber.StatusOK, "Export job retrieved successfully"))
+}
+
+// DownloadExport sends the file produced by a completed export job
+func (h *UserExportHandler) DownloadExport(c *fiber.Ctx) error {
+	job, err := h.getJob(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.jobErrorResponse(c, err)
+	}
+
+	if job.Status != domain.JobCompleted {
+		return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(job,
+			fiber.StatusConflict,
+			"Export is not ready yet"))
+	}
+
+	c.Set(fiber.HeaderContentType, exportContentType(job.Format))
+	return c.Download(job.FilePath, job.FileName)
+}
+
+// RegisterRoutes registers the user export routes
+func (h *UserExportHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/users/export", h.ExportUsers)
+	app.Get("/users/export/:id", h.GetExportJob)
+	app.Get("/users/export/:id/download", h.DownloadExport)
+}
+
+// getJob loads an export job started by the principal and tenant of ctx and
+// fills in its download link once completed
+func (h *UserExportHandler) getJob(ctx context.Context, id string) (*domain.ExportJob, error) {
+	job, err := h.uc.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == domain.JobCompleted {
+		job.DownloadURL = "/users/export/" + job.ID + "/download"
+	}
+	return job, nil
+}
+
+// jobErrorResponse maps an export job lookup error to an HTTP response
+func (h *UserExportHandler) jobErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, domain.ErrExportJobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+			fiber.StatusNotFound,
+			"Export job not found"))
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+		fiber.StatusInternalServerError,
+		"Failed to get export job"))
+}
+
+// cancelOnErrorWriter cancels the export as soon as writing to the client fails
+type cancelOnErrorWriter struct {
+	writer io.Writer
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnErrorWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if err != nil {
+		w.cancel()
+	}
+	return n, err
+}
+
+// exportContentType returns the MIME type of an export format
+func exportContentType(format domain.ExportFormat) string {
+	switch format {
+	case domain.ExportFormatNDJSON:
+		return "application/x-ndjson"
+	case domain.ExportFormatXLSX:
+		return xlsx.ContentType
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
@@ -1,13 +1,20 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"app-hexagonal/internal/domain"
 	helper "app-hexagonal/internal/helper"
 	"app-hexagonal/internal/resilience"
 	"app-hexagonal/internal/usecase"
+	"app-hexagonal/pkg/queryspec"
 )
 
 // UserRequest represents the user creation/update request structure with validation tags
@@ -16,6 +23,12 @@ type UserRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+// UserPatchRequest represents a partial user update; omitted fields are left unchanged
+type UserPatchRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=2,max=50"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
 type UserHandler struct {
 	uc         usecase.UserUsecaseInterface
 	logger     *zap.Logger
@@ -41,7 +54,7 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
 	)
 
-	user, err := h.uc.GetUserByID(id)
+	user, err := h.uc.GetUserByID(c.UserContext(), id)
 	if err != nil {
 		h.logger.Error("Failed to get user",
 			zap.String("user_id", id),
@@ -59,9 +72,105 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 		zap.String("user_name", user.Name),
 	)
 
+	etag := userETag(user)
+	c.Set(fiber.HeaderETag, etag)
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && etagListMatches(ifNoneMatch, etag, true) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(helper.SuccessResponse(user, fiber.StatusOK, "User retrieved successfully"))
 }
 
+// ListUsers returns a page of users. Supported query parameters are page,
+// page_size, search, created_after, created_before, updated_after,
+// updated_before, sort and filter[field][operator]. Passing cursor, empty for
+// the first page, switches to keyset pagination ordered by creation time.
+func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			err.Error()))
+	}
+
+	if c.Context().QueryArgs().Has("cursor") {
+		return h.listUsersByCursor(c, filter)
+	}
+
+	h.logger.Info("Listing users",
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.Int("page", filter.Page),
+		zap.Int("page_size", filter.PageSize),
+	)
+
+	list, err := h.uc.ListUsers(c.UserContext(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list users",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to list users"))
+	}
+
+	totalPages := 0
+	if list.PageSize > 0 {
+		totalPages = int((list.Total + int64(list.PageSize) - 1) / int64(list.PageSize))
+	}
+
+	return c.JSON(helper.SuccessResponseWithMetadata(list.Users,
+		fiber.StatusOK,
+		"Users retrieved successfully",
+		helper.Metadata{
+			Page: &helper.PageInfo{
+				CurrentPage:  list.Page,
+				PageSize:     list.PageSize,
+				TotalRecords: int(list.Total),
+				TotalPages:   totalPages,
+				HasNext:      list.Page < totalPages,
+				HasPrevious:  list.Page > 1,
+			},
+		}))
+}
+
+// listUsersByCursor returns a keyset page with cursors for the neighbouring pages
+func (h *UserHandler) listUsersByCursor(c *fiber.Ctx, filter domain.UserFilter) error {
+	list, err := h.uc.ListUsersByCursor(c.UserContext(), filter, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+				fiber.StatusBadRequest,
+				"Invalid cursor"))
+		}
+		if errors.Is(err, queryspec.ErrInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+				fiber.StatusBadRequest,
+				err.Error()))
+		}
+		h.logger.Error("Failed to list users",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to list users"))
+	}
+
+	return c.JSON(helper.SuccessResponseWithMetadata(list.Users,
+		fiber.StatusOK,
+		"Users retrieved successfully",
+		helper.Metadata{
+			Page: &helper.PageInfo{
+				PageSize:       list.PageSize,
+				HasNext:        list.HasNext,
+				HasPrevious:    list.HasPrevious,
+				NextCursor:     list.NextCursor,
+				PreviousCursor: list.PreviousCursor,
+			},
+		}))
+}
+
 func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	var req UserRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -94,20 +203,319 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 			"Validation failed: "+err.Error()))
 	}
 
-	// In a real implementation, you would create a domain user and save it
-	// For now, we'll just return a success response
+	// The usecase assigns the ID
+	user := &domain.User{
+		Name:  req.Name,
+		Email: req.Email,
+	}
+	if err := h.uc.CreateUser(c.UserContext(), user); err != nil {
+		h.logger.Error("Failed to create user",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.String("user_email", req.Email),
+			zap.Error(err),
+		)
+		if errors.Is(err, domain.ErrDuplicateEmail) {
+			return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(nil,
+				fiber.StatusConflict,
+				"Email already in use"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to create user"))
+	}
+
 	h.logger.Info("User created successfully",
 		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
-		zap.String("user_name", req.Name),
-		zap.String("user_email", req.Email),
+		zap.String("user_id", user.ID),
 	)
 
-	return c.Status(fiber.StatusCreated).JSON(helper.SuccessResponse(nil,
+	c.Location("/users/" + user.ID)
+	c.Set(fiber.HeaderETag, userETag(user))
+	return c.Status(fiber.StatusCreated).JSON(helper.SuccessResponse(user,
 		fiber.StatusCreated,
 		"User created successfully"))
 }
 
+// UpdateUser replaces the user's attributes. The request must carry an If-Match
+// header with the ETag previously returned by GetUser.
+func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
+	var req UserRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Invalid request body"))
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error("Validation failed for user update",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.String("user_id", c.Params("id")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Validation failed: "+err.Error()))
+	}
+
+	return h.conditionalUpdate(c, func(user *domain.User) {
+		user.Name = req.Name
+		user.Email = req.Email
+	})
+}
+
+// PatchUser updates only the provided user attributes. Like UpdateUser it
+// requires an If-Match header matching the current ETag.
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	var req UserPatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Invalid request body"))
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error("Validation failed for user patch",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.String("user_id", c.Params("id")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Validation failed: "+err.Error()))
+	}
+
+	return h.conditionalUpdate(c, func(user *domain.User) {
+		if req.Name != nil {
+			user.Name = *req.Name
+		}
+		if req.Email != nil {
+			user.Email = *req.Email
+		}
+	})
+}
+
+// conditionalUpdate loads the user, checks the If-Match precondition, applies the
+// mutation and persists it with optimistic locking
+func (h *UserHandler) conditionalUpdate(c *fiber.Ctx, apply func(user *domain.User)) error {
+	id := c.Params("id")
+
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		h.logger.Warn("Missing If-Match header on user update",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		)
+		return c.Status(fiber.StatusPreconditionRequired).JSON(helper.ErrorResponse(nil,
+			fiber.StatusPreconditionRequired,
+			"If-Match header is required"))
+	}
+
+	user, err := h.uc.GetUserByID(c.UserContext(), id)
+	if err != nil {
+		h.logger.Error("Failed to get user",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+			fiber.StatusNotFound,
+			"User Not Found"))
+	}
+
+	if !etagListMatches(ifMatch, userETag(user), false) {
+		h.logger.Warn("If-Match precondition failed",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.String("if_match", ifMatch),
+			zap.Int64("current_version", user.Version),
+		)
+		c.Set(fiber.HeaderETag, userETag(user))
+		return c.Status(fiber.StatusPreconditionFailed).JSON(helper.ErrorResponse(nil,
+			fiber.StatusPreconditionFailed,
+			"User has been modified by another request"))
+	}
+
+	apply(user)
+
+	if err := h.uc.UpdateUser(c.UserContext(), user); err != nil {
+		h.logger.Error("Failed to update user",
+			zap.String("user_id", id),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		switch {
+		case errors.Is(err, domain.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(helper.ErrorResponse(nil,
+				fiber.StatusPreconditionFailed,
+				"User has been modified by another request"))
+		case errors.Is(err, domain.ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+				fiber.StatusNotFound,
+				"User Not Found"))
+		case errors.Is(err, domain.ErrDuplicateEmail):
+			return c.Status(fiber.StatusConflict).JSON(helper.ErrorResponse(nil,
+				fiber.StatusConflict,
+				"Email already in use"))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+				fiber.StatusInternalServerError,
+				"Failed to update user"))
+		}
+	}
+
+	h.logger.Info("User updated successfully",
+		zap.String("user_id", id),
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.Int64("version", user.Version),
+	)
+
+	c.Set(fiber.HeaderETag, userETag(user))
+	return c.JSON(helper.SuccessResponse(user, fiber.StatusOK, "User updated successfully"))
+}
+
 func (h *UserHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/users", h.ListUsers)
 	app.Get("/users/:id", h.GetUser)
 	app.Post("/users", h.CreateUser)
+	app.Put("/users/:id", h.UpdateUser)
+	app.Patch("/users/:id", h.PatchUser)
+}
+
+// userETag builds a strong entity tag from the user's version
+func userETag(user *domain.User) string {
+	return fmt.Sprintf(`"%d"`, user.Version)
+}
+
+// entityTag is an entity tag of RFC 9110, such as "3" or W/"3"
+type entityTag struct {
+	weak   bool
+	opaque string
+}
+
+// parseETag parses a header holding a single entity tag
+func parseETag(header string) (entityTag, error) {
+	tags, err := parseETagList(header)
+	if err != nil {
+		return entityTag{}, err
+	}
+	if len(tags) != 1 {
+		return entityTag{}, fmt.Errorf("expected one entity tag: %s", header)
+	}
+	return tags[0], nil
+}
+
+// parseETagList parses the comma separated entity tags of an If-Match or
+// If-None-Match header. The opaque part of a tag may itself hold commas.
+func parseETagList(header string) ([]entityTag, error) {
+	var tags []entityTag
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return tags, nil
+		}
+		// Empty list elements are allowed
+		if rest[0] == ',' {
+			rest = rest[1:]
+			continue
+		}
+
+		var tag entityTag
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+		if rest == "" || rest[0] != '"' {
+			return nil, fmt.Errorf("invalid entity tag: %s", header)
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid entity tag: %s", header)
+		}
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, fmt.Errorf("invalid entity tag: %s", header)
+		}
+	}
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match header is "*"
+// or lists the strong entity tag etag. weak selects the weak comparison of
+// If-None-Match, which ignores the W/ prefix; the strong comparison of
+// If-Match never matches a weak tag. A malformed header matches nothing.
+func etagListMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	current, err := parseETag(etag)
+	if err != nil {
+		return false
+	}
+	tags, err := parseETagList(header)
+	if err != nil {
+		return false
+	}
+	for _, tag := range tags {
+		if tag.opaque == current.opaque && (weak || !tag.weak) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUserFilter reads the user listing filters from the query string. It is
+// shared by every endpoint that selects users so they filter identically.
+// The date bounds are RFC 3339 timestamps. sort and filter[field][operator]
+// are validated against domain.UserQuerySchema.
+func parseUserFilter(c *fiber.Ctx) (domain.UserFilter, error) {
+	filter := domain.UserFilter{
+		Page:     c.QueryInt("page", 1),
+		PageSize: c.QueryInt("page_size", 10),
+		Search:   strings.TrimSpace(c.Query("search")),
+	}
+
+	bounds := []struct {
+		param  string
+		target *time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+	for _, bound := range bounds {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.param)
+		}
+		*bound.target = parsed
+	}
+
+	var filters []queryspec.RawFilter
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if field, operator, ok := queryspec.ParseFilterKey(string(key)); ok {
+			filters = append(filters, queryspec.RawFilter{Field: field, Operator: operator, Value: string(value)})
+		}
+	})
+	query, err := domain.UserQuerySchema.Parse(c.Query("sort"), filters)
+	if err != nil {
+		return filter, err
+	}
+	filter.Query = query
+	return filter, nil
 }
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+)
+
+// importAsyncThreshold is the upload size in bytes above which an import runs as a background job
+const importAsyncThreshold = 1 << 20
+
+// defaultImportMaxSize is the largest upload in bytes imported when no limit is configured
+const defaultImportMaxSize = 100 << 20
+
+// errImportTooLarge reports an upload larger than the import size limit
+var errImportTooLarge = errors.New("import file is too large")
+
+// UserImportPath is where users are imported. Its body is read as a stream, so
+// it is exempt from the server's body limit and held to the import size limit
+// instead.
+const UserImportPath = "/users/import"
+
+// UserImportHandler handles bulk user import HTTP requests
+type UserImportHandler struct {
+	uc      usecase.UserImportUsecaseInterface
+	logger  *zap.Logger
+	maxSize int64
+}
+
+// NewUserImportHandler creates a new user import handler accepting uploads of
+// up to maxSize bytes, or defaultImportMaxSize when maxSize is not positive
+func NewUserImportHandler(uc usecase.UserImportUsecaseInterface, logger *zap.Logger, maxSize int64) *UserImportHandler {
+	if maxSize <= 0 {
+		maxSize = defaultImportMaxSize
+	}
+	return &UserImportHandler{
+		uc:      uc,
+		logger:  logger,
+		maxSize: maxSize,
+	}
+}
+
+// ImportUsers imports users from a CSV or NDJSON upload. The file may be sent as the
+// "file" field of a multipart form or as the raw request body. Small files are
+// imported synchronously; large files, or requests with ?async=true, start a
+// background job that can be polled with GetImportJob. Uploads larger than the
+// import size limit are answered with 413.
+func (h *UserImportHandler) ImportUsers(c *fiber.Ctx) error {
+	source, err := h.importSource(c)
+	if errors.Is(err, errImportTooLarge) {
+		return h.tooLarge(c)
+	}
+	if err != nil {
+		h.logger.Error("Failed to read import upload",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Invalid import file"))
+	}
+	defer source.reader.Close()
+
+	format, err := source.format(c.Query("format"))
+	if err != nil {
+		c.Context().SetConnectionClose()
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Unsupported import format, use csv or ndjson"))
+	}
+
+	if c.QueryBool("async") || source.size > importAsyncThreshold {
+		return h.startImportJob(c, format, source.reader)
+	}
+
+	h.logger.Info("Importing users",
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.String("format", string(format)),
+		zap.Int64("size", source.size),
+	)
+
+	report, err := h.uc.Import(c.UserContext(), format, source.reader)
+	if err != nil {
+		c.Context().SetConnectionClose()
+		h.logger.Error("User import failed",
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(helper.ErrorResponse(report,
+			fiber.StatusUnprocessableEntity,
+			"Import failed: "+err.Error()))
+	}
+
+	h.logger.Info("Users imported",
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.Int("created", report.Created),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed),
+	)
+
+	return c.JSON(helper.SuccessResponse(report, fiber.StatusOK, "Import completed"))
+}
+
+// GetImportJob returns the progress of a background import job started by
+// the same principal and tenant
+func (h *UserImportHandler) GetImportJob(c *fiber.Ctx) error {
+	job, err := h.uc.GetImportJob(c.UserContext(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrImportJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+				fiber.StatusNotFound,
+				"Import job not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to get import job"))
+	}
+
+	return c.JSON(helper.SuccessResponse(job, fiber.StatusOK, "Import job retrieved successfully"))
+}
+
+// RegisterRoutes registers the user import routes
+func (h *UserImportHandler) RegisterRoutes(app *fiber.App) {
+	app.Post(UserImportPath, h.ImportUsers)
+	app.Get(UserImportPath+"/:id", h.GetImportJob)
+}
+
+// startImportJob spools the upload to a temporary file, since the request body is
+// only valid while the handler runs, and hands it to a background job
+func (h *UserImportHandler) startImportJob(c *fiber.Ctx, format domain.ImportFormat, r io.Reader) error {
+	spool, err := os.CreateTemp("", "user-import-*")
+	if err != nil {
+		h.logger.Error("Failed to create import spool file", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to start import"))
+	}
+
+	// A body of unknown length is only found to be too large while spooling
+	n, err := io.Copy(spool, io.LimitReader(r, h.maxSize+1))
+	if err != nil || n > h.maxSize {
+		c.Context().SetConnectionClose()
+		spool.Close()
+		os.Remove(spool.Name())
+		if err == nil {
+			return h.tooLarge(c)
+		}
+		h.logger.Error("Failed to spool import upload", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to start import"))
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			"Failed to start import"))
+	}
+
+	job := h.uc.StartImportJob(c.UserContext(), format, &removeOnClose{File: spool})
+
+	h.logger.Info("Started background user import",
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.String("job_id", job.ID),
+		zap.String("format", string(format)),
+	)
+
+	c.Location(UserImportPath + "/" + job.ID)
+	return c.Status(fiber.StatusAccepted).JSON(helper.SuccessResponse(job,
+		fiber.StatusAccepted,
+		"Import started"))
+}
+
+// tooLarge rejects an upload larger than the import size limit
+func (h *UserImportHandler) tooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	h.logger.Warn("Import upload exceeds the size limit",
+		zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+		zap.Int64("max_size", h.maxSize),
+	)
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(helper.ErrorResponse(nil,
+		fiber.StatusRequestEntityTooLarge,
+		"Import file is too large"))
+}
+
+// importUpload describes where the import data comes from
+type importUpload struct {
+	reader      io.ReadCloser
+	size        int64
+	filename    string
+	contentType string
+}
+
+// importSource returns the uploaded file, preferring a multipart "file" field
+// over the raw body. A request whose declared length is over the import size
+// limit is rejected before its body is read.
+func (h *UserImportHandler) importSource(c *fiber.Ctx) (*importUpload, error) {
+	if int64(c.Request().Header.ContentLength()) > h.maxSize {
+		return nil, errImportTooLarge
+	}
+
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &importUpload{
+			reader:      file,
+			size:        fileHeader.Size,
+			filename:    fileHeader.Filename,
+			contentType: fileHeader.Header.Get(fiber.HeaderContentType),
+		}, nil
+	}
+
+	// The server streams request bodies, so a large upload is read as it
+	// arrives instead of being buffered. A body of unknown length is treated
+	// as large and runs as a background job. When the handler gives up before
+	// the end of the body, the connection is closed rather than reused.
+	size := int64(c.Request().Header.ContentLength())
+	if size == 0 {
+		return nil, errors.New("request body is empty")
+	}
+	var reader io.Reader
+	if c.Request().IsBodyStream() {
+		reader = c.Context().RequestBodyStream()
+		if size < 0 {
+			size = importAsyncThreshold + 1
+		}
+	} else {
+		body := c.Body()
+		if len(body) == 0 {
+			return nil, errors.New("request body is empty")
+		}
+		reader, size = bytes.NewReader(body), int64(len(body))
+	}
+	return &importUpload{
+		reader:      io.NopCloser(reader),
+		size:        size,
+		contentType: c.Get(fiber.HeaderContentType),
+	}, nil
+}
+
+// format resolves the import format from the explicit query value, the file
+// extension or the content type, in that order
+func (u *importUpload) format(explicit string) (domain.ImportFormat, error) {
+	for _, candidate := range []string{explicit, filepath.Ext(u.filename), u.contentType} {
+		if candidate == "" {
+			continue
+		}
+		if format, err := usecase.ParseImportFormat(candidate); err == nil {
+			return format, nil
+		}
+	}
+	return "", domain.ErrUnsupportedFormat
+}
+
+// removeOnClose deletes the underlying temporary file once it is closed
+type removeOnClose struct {
+	*os.File
+}
+
+func (f *removeOnClose) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"app-hexagonal/internal/domain"
+	helper "app-hexagonal/internal/helper"
+	"app-hexagonal/internal/usecase"
+)
+
+// UserPreferencesHandler handles the user preferences document
+type UserPreferencesHandler struct {
+	uc     usecase.UserPreferencesUsecaseInterface
+	logger *zap.Logger
+}
+
+// NewUserPreferencesHandler creates a new user preferences handler
+func NewUserPreferencesHandler(uc usecase.UserPreferencesUsecaseInterface, logger *zap.Logger) *UserPreferencesHandler {
+	return &UserPreferencesHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+// GetPreferences returns the preferences document with the user's ETag
+func (h *UserPreferencesHandler) GetPreferences(c *fiber.Ctx) error {
+	user, err := h.uc.GetPreferences(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.errorResponse(c, err, "Failed to get preferences")
+	}
+
+	c.Set(fiber.HeaderETag, userETag(user))
+	return c.JSON(helper.SuccessResponse(user.Preferences, fiber.StatusOK, "Preferences retrieved successfully"))
+}
+
+// ReplacePreferences replaces the whole preferences document
+func (h *UserPreferencesHandler) ReplacePreferences(c *fiber.Ctx) error {
+	return h.update(c, func(id string, document map[string]interface{}, version int64) (*domain.User, error) {
+		return h.uc.ReplacePreferences(c.UserContext(), id, document, version)
+	})
+}
+
+// PatchPreferences applies the body as a JSON merge patch (RFC 7386): keys set
+// to null are removed and nested objects are merged
+func (h *UserPreferencesHandler) PatchPreferences(c *fiber.Ctx) error {
+	return h.update(c, func(id string, document map[string]interface{}, version int64) (*domain.User, error) {
+		return h.uc.PatchPreferences(c.UserContext(), id, document, version)
+	})
+}
+
+// update decodes the JSON object in the body and applies it. An If-Match
+// header makes the change conditional on the user's current ETag.
+func (h *UserPreferencesHandler) update(c *fiber.Ctx, apply func(id string, document map[string]interface{}, version int64) (*domain.User, error)) error {
+	id := c.Params("id")
+	requestID := c.Get("X-Request-ID", "unknown")
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(c.Body(), &document); err != nil || document == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+			fiber.StatusBadRequest,
+			"Request body must be a JSON object"))
+	}
+
+	var version int64
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" && ifMatch != "*" {
+		tag, err := parseETag(ifMatch)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(helper.ErrorResponse(nil,
+				fiber.StatusBadRequest,
+				"Invalid If-Match header"))
+		}
+		// If-Match compares strongly, so a weak or foreign tag matches no version
+		parsed, err := strconv.ParseInt(tag.opaque, 10, 64)
+		if tag.weak || err != nil || parsed < 1 {
+			return h.errorResponse(c, domain.ErrVersionConflict, "Failed to update preferences")
+		}
+		version = parsed
+	}
+
+	user, err := apply(id, document, version)
+	if err != nil {
+		h.logger.Warn("Failed to update preferences",
+			zap.String("user_id", id),
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return h.errorResponse(c, err, "Failed to update preferences")
+	}
+
+	h.logger.Info("Preferences updated",
+		zap.String("user_id", id),
+		zap.String("request_id", requestID),
+		zap.Int64("version", user.Version),
+	)
+
+	c.Set(fiber.HeaderETag, userETag(user))
+	return c.JSON(helper.SuccessResponse(user.Preferences, fiber.StatusOK, "Preferences updated successfully"))
+}
+
+// RegisterRoutes registers the user preferences routes
+func (h *UserPreferencesHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/users/:id/preferences", h.GetPreferences)
+	app.Put("/users/:id/preferences", h.ReplacePreferences)
+	app.Patch("/users/:id/preferences", h.PatchPreferences)
+}
+
+// errorResponse maps a preferences error to an HTTP response
+func (h *UserPreferencesHandler) errorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(helper.ErrorResponse(nil,
+			fiber.StatusNotFound,
+			"User Not Found"))
+	case errors.Is(err, domain.ErrVersionConflict):
+		return c.Status(fiber.StatusPreconditionFailed).JSON(helper.ErrorResponse(nil,
+			fiber.StatusPreconditionFailed,
+			"User has been modified by another request"))
+	case errors.Is(err, domain.ErrPreferencesTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(helper.ErrorResponse(nil,
+			fiber.StatusRequestEntityTooLarge,
+			"Preferences document is too large"))
+	default:
+		h.logger.Error(message,
+			zap.String("user_id", c.Params("id")),
+			zap.String("request_id", c.Get("X-Request-ID", "unknown")),
+			zap.Error(err),
+		)
+		return c.Status(fiber.StatusInternalServerError).JSON(helper.ErrorResponse(nil,
+			fiber.StatusInternalServerError,
+			message))
+	}
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is the kind of change an audit entry records
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "create"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+)
+
+// AuditChange is the change of one field. Old and New hold JSON values and are
+// absent when the field was blank, or when the values are withheld because the
+// field is redacted or the values were erased.
+type AuditChange struct {
+	Old      json.RawMessage `json:"old,omitempty"`
+	New      json.RawMessage `json:"new,omitempty"`
+	Redacted bool            `json:"redacted,omitempty"`
+}
+
+// AuditEntry records who changed which fields of an entity and when. Entries
+// are written by the audit log GORM plugin in the transaction of the change.
+type AuditEntry struct {
+	ID int64 `json:"id" gorm:"primaryKey"`
+	// EntityType is the table of the entity, such as users
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Action     AuditAction            `json:"action"`
+	Changes    map[string]AuditChange `json:"changes" gorm:"serializer:json"`
+	// ActorID is the principal the change was made on behalf of, empty for
+	// changes made without one such as command line imports
+	ActorID   string    `json:"actor_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName keeps the audit trail in the audit_log table
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditFilter selects audit entries. Empty fields and zero times match every entry.
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	// From and To bound the time of the change, inclusive of From and
+	// exclusive of To
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+// AuditEntryList is a page of audit entries, newest first
+type AuditEntryList struct {
+	Entries  []AuditEntry
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// AuditRepository reads the audit trail. Entries are only ever written by the
+// audit log plugin alongside the change they record.
+type AuditRepository interface {
+	// List returns a page of the entries matching the filter, newest first
+	List(ctx context.Context, filter AuditFilter) (*AuditEntryList, error)
+	// EntityEntries returns every entry about an entity, oldest first
+	EntityEntries(ctx context.Context, entityType, entityID string) ([]AuditEntry, error)
+	// EraseValues removes the old and new values from the entries about an
+	// entity, keeping who changed which fields and when
+	EraseValues(ctx context.Context, entityType, entityID string) error
+}
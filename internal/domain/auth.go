@@ -18,9 +18,11 @@ type TokenResponse struct {
 	ExpiresIn    int    `json:"expires_in"`
 }
 
-// JWTClaims represents the claims in a JWT token
+// JWTClaims represents the claims in a JWT token. TenantID is the tenant the
+// user logged in to, empty when tenancy is not used.
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	TenantID string `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
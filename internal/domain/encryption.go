@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// KeyManager is the port for a key management service. It wraps the data keys
+// that encrypt personal data with key encryption keys that never leave it, so
+// a local key file can later be replaced by a cloud KMS.
+type KeyManager interface {
+	// CurrentKeyID names the key that wraps new data keys
+	CurrentKeyID() string
+	// WrapKey encrypts a data key with the named key encryption key
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey. It returns
+	// ErrEncryptionKeyNotFound when the key encryption key is not available.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrUserNotFound is returned when the requested user does not exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrDuplicateEmail is returned when another user already has the email address
+	ErrDuplicateEmail = errors.New("email already in use")
+
+	// ErrVersionConflict is returned when an update was based on a stale version of the entity
+	ErrVersionConflict = errors.New("version conflict")
+
+	// ErrImportJobNotFound is returned when a background import job does not exist
+	ErrImportJobNotFound = errors.New("import job not found")
+
+	// ErrExportJobNotFound is returned when a background export job does not exist
+	ErrExportJobNotFound = errors.New("export job not found")
+
+	// ErrObjectNotFound is returned by Storage when an object does not exist
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrInvalidImage is returned when an upload is not a supported image
+	ErrInvalidImage = errors.New("invalid image")
+
+	// ErrFileTooLarge is returned when an upload exceeds the allowed size
+	ErrFileTooLarge = errors.New("file too large")
+
+	// ErrUnsupportedFormat is returned when an import or export format is not recognised
+	ErrUnsupportedFormat = errors.New("unsupported format")
+
+	// ErrPrivacyRequestNotFound is returned when a privacy request does not exist
+	ErrPrivacyRequestNotFound = errors.New("privacy request not found")
+
+	// ErrPrivacyRequestState is returned when a privacy request is not in a state that allows the change
+	ErrPrivacyRequestState = errors.New("privacy request can no longer be changed")
+
+	// ErrErasureAlreadyRequested is returned when the user already has an open erasure request
+	ErrErasureAlreadyRequested = errors.New("erasure already requested")
+
+	// ErrErasureRecordTampered is returned when the erasure records do not form an unbroken hash chain
+	ErrErasureRecordTampered = errors.New("erasure record chain is broken")
+
+	// ErrEncryptionKeyNotFound is returned when data was encrypted with a key the KeyManager does not hold
+	ErrEncryptionKeyNotFound = errors.New("encryption key not found")
+
+	// ErrDecryptionFailed is returned when a ciphertext is malformed or fails authentication
+	ErrDecryptionFailed = errors.New("decryption failed")
+
+	// ErrPreferencesTooLarge is returned when a preferences document exceeds the allowed size
+	ErrPreferencesTooLarge = errors.New("preferences document too large")
+
+	// ErrInvalidAuditFilter is returned when an audit log query has an empty or inverted time range
+	ErrInvalidAuditFilter = errors.New("invalid audit filter")
+
+	// ErrWatcherTooSlow is reported when a change stream was closed because its
+	// consumer fell behind and changes were dropped
+	ErrWatcherTooSlow = errors.New("watcher fell behind and missed changes")
+
+	// ErrInvalidCursor is returned when a pagination cursor is malformed, forged or expired
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
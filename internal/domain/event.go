@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// EventType names a domain event and is used as its routing key
+type EventType string
+
+const (
+	EventUserCreated  EventType = "user.created"
+	EventUserUpdated  EventType = "user.updated"
+	EventUserDeleted  EventType = "user.deleted"
+	EventUserLoggedIn EventType = "user.logged_in"
+)
+
+// Event is something that happened in the domain and is announced to other
+// services. Events carry identifiers rather than personal data, so the outbox
+// never holds names or emails in plaintext.
+type Event struct {
+	ID          string      `json:"id"`
+	Type        EventType   `json:"type"`
+	AggregateID string      `json:"aggregate_id"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+	Data        interface{} `json:"data,omitempty"`
+}
+
+// UserEventData is the data of the user events
+type UserEventData struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// OutboxMessage is an event waiting in the outbox to be published
+type OutboxMessage struct {
+	// ID is the event ID, which consumers use to drop duplicate deliveries
+	ID          string
+	EventType   EventType
+	AggregateID string
+	// Payload is the JSON encoded Event
+	Payload string
+	// Attempts counts how often the message was claimed for publishing
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+}
+
+// Transactor runs work atomically. Repositories called with the context passed
+// to fn take part in the transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxRepository stores events until they are published. Add must be called
+// inside the Transactor transaction that changes the aggregate, so an event is
+// stored if and only if the change is committed.
+type OutboxRepository interface {
+	Add(ctx context.Context, events ...Event) error
+	// Claim returns up to limit unsent messages that are due at now and hides
+	// them from other callers until lease has passed
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]OutboxMessage, error)
+	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+	// MarkFailed records the error and makes the message due again at retryAt
+	MarkFailed(ctx context.Context, id string, lastError string, retryAt time.Time) error
+	// PurgeSent deletes messages sent before the given time
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventPublisher delivers outbox messages to a message broker
+type EventPublisher interface {
+	// Publish returns once the broker has accepted the message
+	Publish(ctx context.Context, message OutboxMessage) error
+}
@@ -0,0 +1,33 @@
+package domain
+
+// UserPreferences is a free-form JSON document of client settings, such as the
+// preferred language or notification choices, stored with the user
+type UserPreferences map[string]interface{}
+
+// Merge applies a JSON merge patch (RFC 7386) and returns the patched document.
+// Null values remove keys and nested objects are merged recursively; the
+// receiver and the patch are left unchanged.
+func (p UserPreferences) Merge(patch map[string]interface{}) UserPreferences {
+	return UserPreferences(mergePatch(p, patch))
+}
+
+// mergePatch merges patch into a copy of target
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(target)+len(patch))
+	for key, value := range target {
+		merged[key] = value
+	}
+
+	for key, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(merged, key)
+		case map[string]interface{}:
+			existing, _ := merged[key].(map[string]interface{})
+			merged[key] = mergePatch(existing, value)
+		default:
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// principalKey carries the authenticated principal in a context
+type principalKey struct{}
+
+// WithPrincipal returns a context recording that the work done with it is on
+// behalf of the principal with the given ID
+func WithPrincipal(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, principalKey{}, id)
+}
+
+// PrincipalFromContext returns the ID of the principal carried by ctx, or an
+// empty string when the work is not done on behalf of anyone
+func PrincipalFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(principalKey{}).(string)
+	return id
+}
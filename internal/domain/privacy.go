@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PrivacyRequestType is the kind of data subject request
+type PrivacyRequestType string
+
+const (
+	PrivacyRequestExport  PrivacyRequestType = "export"
+	PrivacyRequestErasure PrivacyRequestType = "erasure"
+)
+
+// PrivacyRequestStatus is the lifecycle state of a privacy request
+type PrivacyRequestStatus string
+
+const (
+	PrivacyRequestScheduled  PrivacyRequestStatus = "scheduled"
+	PrivacyRequestProcessing PrivacyRequestStatus = "processing"
+	PrivacyRequestCompleted  PrivacyRequestStatus = "completed"
+	PrivacyRequestCancelled  PrivacyRequestStatus = "cancelled"
+)
+
+// PrivacyExportFormat is the encoding of a personal data archive
+type PrivacyExportFormat string
+
+const (
+	PrivacyExportJSON PrivacyExportFormat = "json"
+	PrivacyExportZIP  PrivacyExportFormat = "zip"
+)
+
+// PrivacyRequest tracks a subject access or erasure request. Erasures wait until
+// ScheduledFor so they can still be cancelled during the grace period.
+type PrivacyRequest struct {
+	ID           string               `json:"id"`
+	UserID       string               `json:"user_id"`
+	Type         PrivacyRequestType   `json:"type"`
+	Status       PrivacyRequestStatus `json:"status"`
+	Error        string               `json:"error,omitempty"`
+	RequestedAt  time.Time            `json:"requested_at"`
+	ScheduledFor time.Time            `json:"scheduled_for"`
+	CompletedAt  *time.Time           `json:"completed_at,omitempty"`
+	CancelledAt  *time.Time           `json:"cancelled_at,omitempty"`
+}
+
+// PersonalData is what a contributor holds about a user
+type PersonalData struct {
+	// Data is encoded as JSON under the contributor's name
+	Data interface{}
+	// Files are added to ZIP archives next to the JSON document
+	Files []PersonalDataFile
+}
+
+// PersonalDataFile is a file held about a user, opened only when it is archived
+type PersonalDataFile struct {
+	Name string
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// PersonalDataContributor is implemented by every module that stores data about
+// users, so subject access and erasure requests cover all of it
+type PersonalDataContributor interface {
+	// Name identifies the contributor in archives and erasure records
+	Name() string
+	// ExportPersonalData returns the data held about the user, or nil if there is none
+	ExportPersonalData(ctx context.Context, userID string) (*PersonalData, error)
+	// ErasePersonalData deletes or anonymizes the data held about the user. It
+	// must succeed when there is nothing left to erase, so erasures can be retried.
+	ErasePersonalData(ctx context.Context, userID string) error
+}
+
+// ErasureRecord proves that a user's data was erased. Records are append-only and
+// each one is chained to its predecessor by hash, so removing or changing a
+// record is detected by VerifyErasureRecords.
+type ErasureRecord struct {
+	Sequence     int64     `json:"sequence" gorm:"primaryKey;autoIncrement:false"`
+	ID           string    `json:"id"`
+	RequestID    string    `json:"request_id"`
+	UserID       string    `json:"user_id"`
+	Contributors []string  `json:"contributors" gorm:"serializer:json"`
+	RequestedAt  time.Time `json:"requested_at"`
+	ErasedAt     time.Time `json:"erased_at"`
+	PreviousHash string    `json:"previous_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// Seal links the record to the previous one and computes its hash. Times are
+// truncated to seconds so they survive every database's timestamp precision.
+func (r *ErasureRecord) Seal(sequence int64, previousHash string) {
+	r.Sequence = sequence
+	r.PreviousHash = previousHash
+	r.RequestedAt = r.RequestedAt.UTC().Truncate(time.Second)
+	r.ErasedAt = r.ErasedAt.UTC().Truncate(time.Second)
+	r.Hash = r.computeHash()
+}
+
+func (r *ErasureRecord) computeHash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		fmt.Sprint(r.Sequence),
+		r.ID,
+		r.RequestID,
+		r.UserID,
+		strings.Join(r.Contributors, ","),
+		r.RequestedAt.UTC().Format(time.RFC3339),
+		r.ErasedAt.UTC().Format(time.RFC3339),
+		r.PreviousHash,
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyErasureRecords checks that records, ordered by sequence and starting at
+// the first one, form an unbroken hash chain
+func VerifyErasureRecords(records []ErasureRecord) error {
+	previousHash := ""
+	for i := range records {
+		record := &records[i]
+		if record.Sequence != int64(i+1) || record.PreviousHash != previousHash || record.Hash != record.computeHash() {
+			return fmt.Errorf("%w: record %d", ErrErasureRecordTampered, record.Sequence)
+		}
+		previousHash = record.Hash
+	}
+	return nil
+}
+
+// PrivacyRepository persists privacy requests and erasure records
+type PrivacyRepository interface {
+	StoreRequest(ctx context.Context, request *PrivacyRequest) error
+	// FindRequest returns ErrPrivacyRequestNotFound for unknown requests
+	FindRequest(ctx context.Context, id string) (*PrivacyRequest, error)
+	// FindOpenErasure returns the user's scheduled or processing erasure, or
+	// ErrPrivacyRequestNotFound if there is none
+	FindOpenErasure(ctx context.Context, userID string) (*PrivacyRequest, error)
+	// DueErasures returns up to limit scheduled erasures whose grace period ended
+	// before now, oldest first
+	DueErasures(ctx context.Context, now time.Time, limit int) ([]PrivacyRequest, error)
+	// TransitionRequest saves the request only if its stored status is still from,
+	// returning ErrPrivacyRequestState otherwise. This lets a worker claim an
+	// erasure without racing a cancellation or another worker.
+	TransitionRequest(ctx context.Context, request *PrivacyRequest, from PrivacyRequestStatus) error
+	// AppendErasureRecord seals the record after the last stored one and stores it
+	AppendErasureRecord(ctx context.Context, record *ErasureRecord) error
+	// ErasureRecords returns all erasure records ordered by sequence
+	ErasureRecords(ctx context.Context) ([]ErasureRecord, error)
+}
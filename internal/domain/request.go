@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"strings"
+)
+
+type (
+	// requestIDKey carries the ID of the request being served in a context
+	requestIDKey struct{}
+	// traceIDKey carries the distributed trace the request belongs to in a context
+	traceIDKey struct{}
+)
+
+// WithRequestID returns a context recording that the work done with it serves
+// the request with the given ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or an empty
+// string when the work is not done for a request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithTraceID returns a context recording the trace the work done with it belongs to
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx, or an empty string
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// TraceIDFromTraceparent returns the trace ID of a W3C traceparent header, such
+// as 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, or an empty
+// string when the header is malformed or the trace ID is all zeros
+func TraceIDFromTraceparent(header string) string {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 {
+		return ""
+	}
+
+	traceID := strings.ToLower(parts[1])
+	if strings.Trim(traceID, "0") == "" || strings.Trim(traceID, "0123456789abcdef") != "" {
+		return ""
+	}
+	return traceID
+}
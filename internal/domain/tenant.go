@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+// tenantKey carries the tenant the work is done for in a context
+type tenantKey struct{}
+
+// WithTenant returns a context recording that the work done with it is for the
+// tenant with the given ID. With schema per tenant enabled the tenant selects
+// the database schema its statements run in.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the ID of the tenant carried by ctx, or an empty
+// string for work not done for a tenant
+func TenantFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
@@ -1,16 +1,227 @@
 package domain
 
+import (
+	"context"
+	"sort"
+	"time"
+
+	"app-hexagonal/pkg/queryspec"
+)
+
 type User struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"-"`
+	// Version is incremented on every update and used for optimistic concurrency control
+	Version int64 `json:"version"`
+	// AvatarKey and AvatarThumbnailKey locate the avatar images in Storage
+	AvatarKey          string `json:"-"`
+	AvatarThumbnailKey string `json:"-"`
+	// EncryptedEmail holds the email ciphertext when personal data is encrypted
+	// at rest, in which case Email holds a blind index of the address
+	EncryptedEmail string `json:"-"`
+	// CreatedAt and UpdatedAt are maintained by the repository. CreatedBy and
+	// UpdatedBy hold the ID of the principal that created and last changed the
+	// user; changes made without one, such as command line imports, leave them
+	// as is.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	// Preferences holds the user's client settings as a JSON document
+	Preferences UserPreferences `json:"preferences"`
+	// DeletedAt marks the user as soft deleted; deleted users are hidden from lookups
+	// and listings but keep their email reserved
+	DeletedAt *time.Time `json:"-"`
+}
+
+// UserFilter describes which users to list and which page to return
+type UserFilter struct {
+	Page     int
+	PageSize int
+	// Search matches users whose name or email contains the given text
+	Search string
+	// CreatedAfter, CreatedBefore, UpdatedAfter and UpdatedBefore bound the
+	// timestamps, inclusive of the after bound and exclusive of the before
+	// bound. Zero values leave the range open.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	// Query holds the client's sort and filters, validated against
+	// UserQuerySchema. Users sorting equal, or all users without a sort, are
+	// ordered by ID.
+	Query queryspec.Spec
+	// Keyset selects keyset pagination, in which case Page is ignored and
+	// users are ordered by creation time and ID
+	Keyset *UserKeyset
+}
+
+// UserQuerySchema whitelists the user fields list endpoints sort and filter by
+var UserQuerySchema = queryspec.NewSchema(
+	queryspec.Field{Name: "id", Column: "id", Type: queryspec.String, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.In}},
+	queryspec.Field{Name: "name", Column: "name", Type: queryspec.String, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.Contains, queryspec.StartsWith, queryspec.In}},
+	queryspec.Field{Name: "email", Column: "email", Type: queryspec.String, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.Contains, queryspec.StartsWith, queryspec.In}},
+	queryspec.Field{Name: "version", Column: "version", Type: queryspec.Int, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.Gt, queryspec.Gte, queryspec.Lt, queryspec.Lte}},
+	queryspec.Field{Name: "created_at", Column: "created_at", Type: queryspec.Time, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Gt, queryspec.Gte, queryspec.Lt, queryspec.Lte}},
+	queryspec.Field{Name: "updated_at", Column: "updated_at", Type: queryspec.Time, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Gt, queryspec.Gte, queryspec.Lt, queryspec.Lte}},
+	queryspec.Field{Name: "created_by", Column: "created_by", Type: queryspec.String,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.In}},
+	queryspec.Field{Name: "updated_by", Column: "updated_by", Type: queryspec.String,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Ne, queryspec.In}},
+)
+
+// userField returns a field of UserQuerySchema for in-memory evaluation
+func userField(user *User) queryspec.Getter {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return user.ID
+		case "name":
+			return user.Name
+		case "email":
+			return user.Email
+		case "version":
+			return user.Version
+		case "created_at":
+			return user.CreatedAt
+		case "updated_at":
+			return user.UpdatedAt
+		case "created_by":
+			return user.CreatedBy
+		case "updated_by":
+			return user.UpdatedBy
+		default:
+			return nil
+		}
+	}
+}
+
+// MatchesQuery reports whether the user passes the filters of the query, for
+// adapters that cannot hand them to a database
+func (f UserFilter) MatchesQuery(user *User) bool {
+	return f.Query.Matches(userField(user))
+}
+
+// SortUsers orders ID-sorted users by the query's sort, keeping users
+// that sort equal in ID order
+func (f UserFilter) SortUsers(users []User) {
+	if len(f.Query.Sort) == 0 {
+		return
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return f.Query.Less(userField(&users[i]), userField(&users[j]))
+	})
+}
+
+// UserKey is the position of a user in keyset order
+type UserKey struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// KeyOf returns the keyset position of the user
+func KeyOf(user *User) UserKey {
+	return UserKey{CreatedAt: user.CreatedAt, ID: user.ID}
+}
+
+// Less reports whether k sorts before other
+func (k UserKey) Less(other UserKey) bool {
+	if !k.CreatedAt.Equal(other.CreatedAt) {
+		return k.CreatedAt.Before(other.CreatedAt)
+	}
+	return k.ID < other.ID
+}
+
+// UserKeyset selects a keyset page by the user it follows or precedes. Leaving
+// both nil selects the first page.
+type UserKeyset struct {
+	After  *UserKey
+	Before *UserKey
+}
+
+// MatchesDates reports whether the timestamps of the user fall within the filter's
+// date ranges. Search is matched by the adapters themselves.
+func (f UserFilter) MatchesDates(user *User) bool {
+	return inRange(user.CreatedAt, f.CreatedAfter, f.CreatedBefore) &&
+		inRange(user.UpdatedAt, f.UpdatedAfter, f.UpdatedBefore)
+}
+
+// inRange reports whether t lies in [after, before), treating zero bounds as open
+func inRange(t, after, before time.Time) bool {
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before) {
+		return false
+	}
+	return true
+}
+
+// UserList is a page of users together with pagination details. Keyset pages
+// leave Total and Page unset and report the neighbouring pages instead.
+type UserList struct {
+	Users    []User
+	Total    int64
+	Page     int
+	PageSize int
+
+	HasNext     bool
+	HasPrevious bool
+	// NextCursor and PreviousCursor are opaque tokens for the neighbouring
+	// keyset pages, set by the usecase
+	NextCursor     string
+	PreviousCursor string
+}
+
+// UserChangeType identifies the kind of change applied to a user
+type UserChangeType string
+
+const (
+	UserChangeCreated UserChangeType = "created"
+	UserChangeUpdated UserChangeType = "updated"
+	UserChangeDeleted UserChangeType = "deleted"
+)
+
+// UserChange describes a single change applied to a user
+type UserChange struct {
+	Type UserChangeType
+	User User
+	// Tenant is the tenant the user belongs to, empty for the shared schema
+	Tenant     string
+	OccurredAt time.Time
 }
 
+// UserRepository is the persistence port for users. Every adapter must pass the
+// conformance suite in internal/repository/repositorytest.
 type UserRepository interface {
-	FindByID(id string) (*User, error)
-	FindByEmail(email string) (*User, error)
-	Store(user *User) error
-	Update(user *User) error
-	Delete(id string) error
+	// FindByID and FindByEmail return ErrUserNotFound for missing or deleted users
+	FindByID(ctx context.Context, id string) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	List(ctx context.Context, filter UserFilter) (*UserList, error)
+	// Iterate calls fn for every user matching the filter, ignoring pagination.
+	// Users are streamed from the database rather than loaded all at once.
+	Iterate(ctx context.Context, filter UserFilter, fn func(user *User) error) error
+	// Store inserts the user, returning ErrDuplicateEmail if the email is taken
+	Store(ctx context.Context, user *User) error
+	// StoreBatch inserts all users atomically; either every user is stored or none is
+	StoreBatch(ctx context.Context, users []*User) error
+	// ExistingEmails reports which of the given emails already belong to a user,
+	// including soft deleted users since their emails stay reserved
+	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	// Update persists the user only if its Version still matches the stored one.
+	// It returns ErrVersionConflict when the row was changed concurrently.
+	Update(ctx context.Context, user *User) error
+	// Delete soft deletes the user, returning ErrUserNotFound if it does not exist
+	Delete(ctx context.Context, id string) error
+	// Purge permanently removes the user, including a soft deleted one, and
+	// releases its email. It returns ErrUserNotFound if no row exists.
+	Purge(ctx context.Context, id string) error
 }
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// ExportFormat is the encoding of a user export file
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatNDJSON ExportFormat = "ndjson"
+	ExportFormatXLSX   ExportFormat = "xlsx"
+)
+
+// UserExportColumns lists the user attributes that may be exported, in default order
+var UserExportColumns = []string{"id", "name", "email", "version"}
+
+// ExportJob tracks a user export running in the background
+type ExportJob struct {
+	ID          string       `json:"id"`
+	Format      ExportFormat `json:"format"`
+	Status      JobStatus    `json:"status"`
+	Exported    int          `json:"exported"`
+	FileName    string       `json:"file_name,omitempty"`
+	DownloadURL string       `json:"download_url,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+	CreatedBy   string       `json:"created_by,omitempty"`
+
+	// Tenant is the tenant the job was started for
+	Tenant string `json:"-"`
+	// FilePath is where the finished export is stored on disk
+	FilePath string `json:"-"`
+}
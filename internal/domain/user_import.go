@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// ImportFormat is the encoding of a bulk user import file
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// UserImportRecord is a single row of a bulk user import
+type UserImportRecord struct {
+	Name     string `json:"name" validate:"required,min=2,max=50"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"omitempty,min=6"`
+}
+
+// ImportRowStatus is the outcome of importing a single row
+type ImportRowStatus string
+
+const (
+	ImportRowCreated ImportRowStatus = "created"
+	ImportRowSkipped ImportRowStatus = "skipped"
+	ImportRowFailed  ImportRowStatus = "failed"
+)
+
+// ImportRowResult reports what happened to a single row
+type ImportRowResult struct {
+	Row    int             `json:"row"`
+	Email  string          `json:"email,omitempty"`
+	Status ImportRowStatus `json:"status"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ImportReport summarizes a bulk user import
+type ImportReport struct {
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// Add records the result of a row and updates the counters
+func (r *ImportReport) Add(result ImportRowResult) {
+	switch result.Status {
+	case ImportRowCreated:
+		r.Created++
+	case ImportRowSkipped:
+		r.Skipped++
+	case ImportRowFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+// ImportJob tracks a bulk user import running in the background
+type ImportJob struct {
+	ID         string        `json:"id"`
+	Format     ImportFormat  `json:"format"`
+	Status     JobStatus     `json:"status"`
+	Processed  int           `json:"processed"`
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	CreatedBy  string        `json:"created_by,omitempty"`
+
+	// Tenant is the tenant the job was started for
+	Tenant string `json:"-"`
+}
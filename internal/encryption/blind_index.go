@@ -0,0 +1,37 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// blindIndexPrefix marks a blind index so it is never mistaken for a plaintext value
+const blindIndexPrefix = "bidx:"
+
+// BlindIndex computes a keyed hash of a value so that encrypted values can still
+// be found by exact match and kept unique. Without the key the index cannot be
+// reversed by guessing common values.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex creates a blind index keyed with at least 32 bytes. The key is
+// not rotated with the key encryption keys, since changing it would require
+// recomputing every index.
+func NewBlindIndex(key []byte) (*BlindIndex, error) {
+	if len(key) < keySize {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes", keySize)
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// Compute returns the index of value. Values are compared case-insensitively,
+// like emails under the default MySQL collation.
+func (b *BlindIndex) Compute(value string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(strings.ToLower(value)))
+	return blindIndexPrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+// Package encryption provides envelope encryption and blind indexes for
+// personal data stored by the repositories.
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/cache"
+)
+
+// ciphertextPrefix marks an encrypted value and its format version. A value is
+// stored as enc:v1:<key id>:<wrapped data key>:<nonce and ciphertext>.
+const ciphertextPrefix = "enc:v1:"
+
+const (
+	defaultDataKeyTTL   = 5 * time.Minute
+	defaultKeyCacheSize = 1000
+)
+
+// EnvelopeOptions tunes how often the KeyManager is called
+type EnvelopeOptions struct {
+	// DataKeyTTL is how long a data key encrypts new values before a new one is
+	// generated, and how long unwrapped data keys stay cached
+	DataKeyTTL time.Duration
+	// KeyCacheSize bounds the number of unwrapped data keys held in memory
+	KeyCacheSize int
+}
+
+// Envelope encrypts values with AES-256-GCM data keys that are themselves
+// wrapped by a domain.KeyManager and stored next to each ciphertext. Data keys
+// are reused for a while and cached once unwrapped, so a remote KeyManager is
+// not called for every value.
+type Envelope struct {
+	keys    domain.KeyManager
+	options EnvelopeOptions
+
+	mu        sync.Mutex
+	current   *dataKey
+	unwrapped *cache.LRUCache
+}
+
+// dataKey is a plaintext data key together with its wrapped form
+type dataKey struct {
+	keyID     string
+	plaintext []byte
+	wrapped   string
+	expiresAt time.Time
+}
+
+// NewEnvelope creates an envelope around keys. Zero options use the defaults.
+func NewEnvelope(keys domain.KeyManager, options EnvelopeOptions) *Envelope {
+	if options.DataKeyTTL <= 0 {
+		options.DataKeyTTL = defaultDataKeyTTL
+	}
+	if options.KeyCacheSize <= 0 {
+		options.KeyCacheSize = defaultKeyCacheSize
+	}
+
+	return &Envelope{
+		keys:      keys,
+		options:   options,
+		unwrapped: cache.NewLRUCache(options.KeyCacheSize),
+	}
+}
+
+// Encrypt encrypts plaintext and binds it to additionalData, which must be
+// passed unchanged to Decrypt. Binding a value to its row and column stops a
+// ciphertext from being copied to another user.
+func (e *Envelope) Encrypt(ctx context.Context, plaintext, additionalData string) (string, error) {
+	key, err := e.dataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key.plaintext)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+
+	return ciphertextPrefix + key.keyID + ":" + key.wrapped + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt. It returns domain.ErrDecryptionFailed for values
+// that are malformed, altered or bound to different additional data.
+func (e *Envelope) Decrypt(ctx context.Context, value, additionalData string) (string, error) {
+	keyID, wrapped, encoded, ok := parseCiphertext(value)
+	if !ok {
+		return "", domain.ErrDecryptionFailed
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", domain.ErrDecryptionFailed
+	}
+
+	key, err := e.unwrap(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plaintext or encrypted under a key
+// other than the KeyManager's current one
+func (e *Envelope) NeedsRotation(value string) bool {
+	keyID, _, _, ok := parseCiphertext(value)
+	return !ok || keyID != e.keys.CurrentKeyID()
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// dataKey returns the data key for new values, generating and wrapping a new
+// one when the current key has expired or the key encryption key was rotated
+func (e *Envelope) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyID := e.keys.CurrentKeyID()
+	if e.current != nil && e.current.keyID == keyID && time.Now().Before(e.current.expiresAt) {
+		return e.current, nil
+	}
+
+	plaintext := make([]byte, keySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+	wrapped, err := e.keys.WrapKey(ctx, keyID, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	e.current = &dataKey{
+		keyID:     keyID,
+		plaintext: plaintext,
+		wrapped:   base64.RawURLEncoding.EncodeToString(wrapped),
+		expiresAt: time.Now().Add(e.options.DataKeyTTL),
+	}
+	return e.current, nil
+}
+
+// unwrap returns the plaintext of a wrapped data key, asking the KeyManager
+// only when it is not cached
+func (e *Envelope) unwrap(ctx context.Context, keyID, wrapped string) ([]byte, error) {
+	cacheKey := keyID + ":" + wrapped
+	if key, err := e.unwrapped.Get(ctx, cacheKey); err == nil {
+		return key, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, domain.ErrDecryptionFailed
+	}
+	key, err := e.keys.UnwrapKey(ctx, keyID, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	e.unwrapped.Set(ctx, cacheKey, key, e.options.DataKeyTTL)
+	return key, nil
+}
+
+// parseCiphertext splits an encrypted value into its parts
+func parseCiphertext(value string) (keyID, wrapped, sealed string, ok bool) {
+	if !IsEncrypted(value) {
+		return "", "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(value, ciphertextPrefix), ":")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
@@ -0,0 +1,131 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"app-hexagonal/internal/domain"
+)
+
+// keySize is the length of AES-256 keys, used for both key encryption keys and data keys
+const keySize = 32
+
+// KeyFile is the JSON layout of a local key file. Keys maps key IDs to base64
+// encoded 32 byte keys; retired keys stay listed until nothing uses them.
+//
+//	{"current": "2026-10", "keys": {"2026-10": "...", "2025-04": "..."}}
+type KeyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LocalKeyManager is a domain.KeyManager holding its key encryption keys in
+// process memory, loaded from a key file that must be kept out of the database
+// and its backups
+type LocalKeyManager struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadLocalKeyManager reads a KeyFile from path
+func LoadLocalKeyManager(path string) (*LocalKeyManager, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	return NewLocalKeyManager(file)
+}
+
+// NewLocalKeyManager creates a key manager from the keys in file
+func NewLocalKeyManager(file KeyFile) (*LocalKeyManager, error) {
+	if _, ok := file.Keys[file.Current]; !ok {
+		return nil, fmt.Errorf("current key %q is not in the key file", file.Current)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(file.Keys))
+	for id, encoded := range file.Keys {
+		// The key ID is embedded in ciphertexts, which use ":" as a separator
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d base64 encoded bytes", id, keySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		keys[id] = aead
+	}
+
+	return &LocalKeyManager{
+		current: file.Current,
+		keys:    keys,
+	}, nil
+}
+
+func (m *LocalKeyManager) CurrentKeyID() string {
+	return m.current
+}
+
+// WrapKey seals the data key with AES-GCM, binding it to the key ID
+func (m *LocalKeyManager) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, ok := m.keys[keyID]
+	if !ok {
+		return nil, domain.ErrEncryptionKeyNotFound
+	}
+	return seal(aead, dataKey, []byte(keyID))
+}
+
+func (m *LocalKeyManager) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := m.keys[keyID]
+	if !ok {
+		return nil, domain.ErrEncryptionKeyNotFound
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
+
+// newAEAD creates an AES-GCM cipher for a 32 byte key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext under a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, domain.ErrDecryptionFailed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, domain.ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
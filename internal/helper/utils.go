@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"math/rand"
 	"time"
+
+	gormpkg "app-hexagonal/pkg/gorm"
 )
 
 const randomStringLetterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -95,23 +97,20 @@ func (d *Date) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + d.Time().UTC().Format("2006-01-02") + `"`), nil
 }
 
-type JSONB struct{}
-
-func (m *JSONB) Value() (driver.Value, error) {
-	val, err := json.Marshal(m)
-
-	return driver.Value(string(val)), err
-}
-
-func (JSONB) GormDataType() string {
-	return "JSONB"
-}
+// JSONB is a free-form JSON document column
+type JSONB = gormpkg.JSON[map[string]interface{}]
 
+// Scan decodes a JSON column value into res, which must be a pointer
 func Scan(val interface{}, res interface{}) error {
-	t, ok := val.([]byte)
-	if !ok {
-		return errors.New(fmt.Sprintf("Failed to unmarshal JSONB value: %v", val))
+	var data []byte
+	switch v := val.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("Failed to unmarshal JSONB value: %v", val)
 	}
 
-	return json.Unmarshal(t, &res)
+	return json.Unmarshal(data, res)
 }
@@ -0,0 +1,118 @@
+// Package messaging holds the domain.EventPublisher adapters.
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/streadway/amqp"
+)
+
+// RabbitMQPublisher publishes outbox messages to a durable topic exchange,
+// routed by event type. Publisher confirms are enabled, so Publish only
+// succeeds once the broker has taken responsibility for the message.
+type RabbitMQPublisher struct {
+	conn     *amqp.Connection
+	exchange string
+
+	// Publishes are serialised so each confirmation matches its message
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// NewRabbitMQPublisher creates a publisher to exchange; the channel is opened on first use
+func NewRabbitMQPublisher(conn *amqp.Connection, exchange string) *RabbitMQPublisher {
+	return &RabbitMQPublisher{
+		conn:     conn,
+		exchange: exchange,
+	}
+}
+
+func (p *RabbitMQPublisher) Publish(ctx context.Context, message domain.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.open(); err != nil {
+		return err
+	}
+
+	err := p.channel.Publish(p.exchange, string(message.EventType), false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    message.ID,
+		Type:         string(message.EventType),
+		Timestamp:    message.CreatedAt,
+		Body:         []byte(message.Payload),
+	})
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			p.reset()
+			return errors.New("rabbitmq channel closed before the event was confirmed")
+		}
+		if !confirm.Ack {
+			return errors.New("rabbitmq rejected the event")
+		}
+		return nil
+	case <-ctx.Done():
+		// A late confirmation would be taken for the next message's
+		p.reset()
+		return ctx.Err()
+	}
+}
+
+// Close closes the channel
+func (p *RabbitMQPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.channel == nil {
+		return nil
+	}
+	err := p.channel.Close()
+	p.channel = nil
+	return err
+}
+
+// open creates the confirming channel and declares the exchange if there is no
+// channel yet; callers must hold the lock
+func (p *RabbitMQPublisher) open() error {
+	if p.channel != nil {
+		return nil
+	}
+
+	channel, err := p.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open rabbitmq channel: %w", err)
+	}
+	if err := channel.ExchangeDeclare(p.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		channel.Close()
+		return fmt.Errorf("failed to declare exchange %q: %w", p.exchange, err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	p.channel = channel
+	p.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// reset drops the channel so the next publish opens a fresh one; callers must hold the lock
+func (p *RabbitMQPublisher) reset() {
+	if p.channel != nil {
+		p.channel.Close()
+		p.channel = nil
+	}
+}
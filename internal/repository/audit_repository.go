@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository reads the audit_log table written by the gormpkg.AuditLog
+// plugin. Calls made with a context carrying a transaction take part in it.
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a GORM backed audit repository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// List orders the entries newest first; IDs grow with every entry, so they
+// order entries written within the same instant
+func (r *AuditRepository) List(ctx context.Context, filter domain.AuditFilter) (*domain.AuditEntryList, error) {
+	query := gormpkg.Conn(ctx, r.db).Model(&domain.AuditEntry{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var entries []domain.AuditEntry
+	result, err := gormpkg.OffsetPagination(query, &gormpkg.Pagination{
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		OrderBy:  "id",
+		Sort:     "desc",
+	}, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuditEntryList{
+		Entries:  entries,
+		Total:    result.TotalRecords,
+		Page:     result.CurrentPage,
+		PageSize: result.PageSize,
+	}, nil
+}
+
+func (r *AuditRepository) EntityEntries(ctx context.Context, entityType, entityID string) ([]domain.AuditEntry, error) {
+	var entries []domain.AuditEntry
+	err := gormpkg.Conn(ctx, r.db).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// EraseValues rewrites the changes of every entry about the entity in one
+// transaction, or in the transaction carried by ctx
+func (r *AuditRepository) EraseValues(ctx context.Context, entityType, entityID string) error {
+	return gormpkg.NewTransactionManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		entries, err := r.EntityEntries(ctx, entityType, entityID)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			erased := make(map[string]domain.AuditChange, len(entry.Changes))
+			for field, change := range entry.Changes {
+				erased[field] = domain.AuditChange{Redacted: change.Redacted}
+			}
+			changes, err := json.Marshal(erased)
+			if err != nil {
+				return err
+			}
+			err = gormpkg.Conn(ctx, r.db).Model(&domain.AuditEntry{}).
+				Where("id = ?", entry.ID).
+				Update("changes", string(changes)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
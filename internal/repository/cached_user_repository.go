@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/cache"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// invalidationGrace is how long an invalidated key rejects new values. A read
+// that started before a write cannot put the old row back into the cache while
+// the marker is present.
+const invalidationGrace = 5 * time.Second
+
+// tombstone marks an invalidated key; encoded users never start with a zero byte
+var tombstone = []byte{0}
+
+// CachedUserRepository is a cache-aside decorator for any domain.UserRepository.
+// Users are cached by ID without their password hash, which stays out of the
+// cache. Lookups by email serve authentication, which needs the hash, so they
+// read the wrapped repository and only fill the cache by ID. Concurrent misses
+// for the same key are coalesced into a single call to the wrapped repository.
+// Calls made inside a transaction bypass the cache, so they neither read rows
+// cached before the transaction nor cache rows it has not committed.
+//
+// Cache failures never fail a lookup; the wrapped repository is used instead.
+type CachedUserRepository struct {
+	// Listing and inserts are passed through to the wrapped repository
+	domain.UserRepository
+
+	cache cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// NewCachedUserRepository wraps repo with a cache whose entries live for ttl
+func NewCachedUserRepository(repo domain.UserRepository, cache cache.Cache, ttl time.Duration) *CachedUserRepository {
+	return &CachedUserRepository{
+		UserRepository: repo,
+		cache:          cache,
+		ttl:            ttl,
+	}
+}
+
+// cachedUser is the cache encoding of a user. It leaves out the password hash.
+type cachedUser struct {
+	ID                 string                 `json:"id"`
+	Name               string                 `json:"name"`
+	Email              string                 `json:"email"`
+	Version            int64                  `json:"version"`
+	AvatarKey          string                 `json:"avatar_key"`
+	AvatarThumbnailKey string                 `json:"avatar_thumbnail_key"`
+	EncryptedEmail     string                 `json:"encrypted_email,omitempty"`
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+	CreatedBy          string                 `json:"created_by,omitempty"`
+	UpdatedBy          string                 `json:"updated_by,omitempty"`
+	Preferences        domain.UserPreferences `json:"preferences"`
+}
+
+// FindByID returns the user without its password hash when it is served from
+// the cache
+func (r *CachedUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if gormpkg.InTransaction(ctx) {
+		return r.UserRepository.FindByID(ctx, id)
+	}
+	key := userIDKey(ctx, id)
+
+	if user, ok := r.cached(ctx, key); ok {
+		return user, nil
+	}
+
+	value, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// The lookup is shared, so one caller giving up must not fail the others
+		ctx := context.WithoutCancel(ctx)
+		user, err := r.UserRepository.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		r.populate(ctx, user)
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers sharing a coalesced result must not see each other's changes
+	user := *value.(*domain.User)
+	return &user, nil
+}
+
+// FindByEmail always reads the wrapped repository, as authentication needs the
+// password hash the cache leaves out
+func (r *CachedUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if gormpkg.InTransaction(ctx) {
+		return r.UserRepository.FindByEmail(ctx, email)
+	}
+
+	value, err, _ := r.group.Do(userEmailKey(ctx, email), func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		user, err := r.UserRepository.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		r.populate(ctx, user)
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user := *value.(*domain.User)
+	return &user, nil
+}
+
+// Update invalidates the cached user even when the update fails, since a
+// version conflict means the cached copy is stale. A user read from the cache
+// has no password hash, so a missing one is read back from the wrapped
+// repository rather than written as empty; the version check fails the update
+// if the hash changed in between.
+func (r *CachedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	if user.Password == "" {
+		if stored, err := r.UserRepository.FindByID(ctx, user.ID); err == nil {
+			user.Password = stored.Password
+		}
+	}
+	err := r.UserRepository.Update(ctx, user)
+	r.invalidate(ctx, user.ID)
+	return err
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id string) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+func (r *CachedUserRepository) Purge(ctx context.Context, id string) error {
+	err := r.UserRepository.Purge(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+// cached returns the user stored under key, treating tombstones and undecodable
+// values as misses
+func (r *CachedUserRepository) cached(ctx context.Context, key string) (*domain.User, bool) {
+	value, err := r.cache.Get(ctx, key)
+	if err != nil || len(value) == 0 || value[0] == tombstone[0] {
+		return nil, false
+	}
+
+	var record cachedUser
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, false
+	}
+
+	return &domain.User{
+		ID:                 record.ID,
+		Name:               record.Name,
+		Email:              record.Email,
+		Version:            record.Version,
+		AvatarKey:          record.AvatarKey,
+		AvatarThumbnailKey: record.AvatarThumbnailKey,
+		EncryptedEmail:     record.EncryptedEmail,
+		CreatedAt:          record.CreatedAt,
+		UpdatedAt:          record.UpdatedAt,
+		CreatedBy:          record.CreatedBy,
+		UpdatedBy:          record.UpdatedBy,
+		Preferences:        record.Preferences,
+	}, true
+}
+
+// populate caches the user unless its key is present, which also keeps a
+// tombstone from being overwritten by a read that raced with a write
+func (r *CachedUserRepository) populate(ctx context.Context, user *domain.User) {
+	value, err := json.Marshal(cachedUser{
+		ID:                 user.ID,
+		Name:               user.Name,
+		Email:              user.Email,
+		Version:            user.Version,
+		AvatarKey:          user.AvatarKey,
+		AvatarThumbnailKey: user.AvatarThumbnailKey,
+		EncryptedEmail:     user.EncryptedEmail,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		CreatedBy:          user.CreatedBy,
+		UpdatedBy:          user.UpdatedBy,
+		Preferences:        user.Preferences,
+	})
+	if err != nil {
+		return
+	}
+	r.cache.Add(ctx, userIDKey(ctx, user.ID), value, r.ttl)
+}
+
+// invalidate replaces the cached user with a short-lived tombstone. It runs
+// even if the caller's context was cancelled, since the write may have landed.
+func (r *CachedUserRepository) invalidate(ctx context.Context, id string) {
+	r.cache.Set(context.WithoutCancel(ctx), userIDKey(ctx, id), tombstone, invalidationGrace)
+}
+
+// EvictCachedUsers invalidates the cached users with the given IDs. It is for
+// changes made around CachedUserRepository, such as emptying the users table
+// while seeding.
+func EvictCachedUsers(ctx context.Context, cache cache.Cache, ids ...string) {
+	for _, id := range ids {
+		cache.Set(context.WithoutCancel(ctx), userIDKey(ctx, id), tombstone, invalidationGrace)
+	}
+}
+
+func userIDKey(ctx context.Context, id string) string {
+	return tenantKeyPrefix(ctx) + "user:id:" + id
+}
+
+func userEmailKey(ctx context.Context, email string) string {
+	return tenantKeyPrefix(ctx) + "user:email:" + email
+}
+
+// tenantKeyPrefix keeps the users of each tenant apart in the cache, as the
+// same ID or email may belong to different users in different tenant schemas
+func tenantKeyPrefix(ctx context.Context) string {
+	if tenant := domain.TenantFromContext(ctx); tenant != "" {
+		return "tenant:" + tenant + ":"
+	}
+	return ""
+}
@@ -0,0 +1,379 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/encryption"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/queryspec"
+)
+
+// reencryptAttempts bounds the retries when a user changes while being re-encrypted
+const reencryptAttempts = 3
+
+// defaultMaxSortedMatches bounds the users held in memory to sort a search
+const defaultMaxSortedMatches = 10000
+
+// EncryptedUserRepository is a decorator that encrypts the name and email of a
+// user before it reaches the wrapped repository and decrypts them on the way
+// back. The email column holds a blind index of the address, so lookups by
+// email and the unique constraint keep working in every adapter.
+//
+// The wrapped repository cannot search encrypted values, so List and Iterate
+// with a search text, or sorting or filtering by name or email, read and
+// decrypt every user, which takes time in proportion to the table. Sorted and
+// keyset pages of such a search also hold every match in memory; they fail
+// with queryspec.ErrInvalid once more than maxMatches users match. Users
+// stored before encryption was enabled are read as plaintext until Reencrypt
+// converts them.
+type EncryptedUserRepository struct {
+	// Delete and Purge are passed through to the wrapped repository
+	domain.UserRepository
+
+	envelope   *encryption.Envelope
+	index      *encryption.BlindIndex
+	maxMatches int
+}
+
+// NewEncryptedUserRepository wraps repo so names and emails are stored
+// encrypted. maxMatches bounds the matches of a sorted search, 10000 when zero.
+func NewEncryptedUserRepository(repo domain.UserRepository, envelope *encryption.Envelope, index *encryption.BlindIndex, maxMatches int) *EncryptedUserRepository {
+	if maxMatches <= 0 {
+		maxMatches = defaultMaxSortedMatches
+	}
+
+	return &EncryptedUserRepository{
+		UserRepository: repo,
+		envelope:       envelope,
+		index:          index,
+		maxMatches:     maxMatches,
+	}
+}
+
+func (r *EncryptedUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	user, err := r.UserRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user, r.decrypt(ctx, user)
+}
+
+// FindByEmail looks the user up by blind index, falling back to the plaintext
+// email for users that have not been re-encrypted yet
+func (r *EncryptedUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := r.UserRepository.FindByEmail(ctx, r.index.Compute(email))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		user, err = r.UserRepository.FindByEmail(ctx, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, r.decrypt(ctx, user)
+}
+
+func (r *EncryptedUserRepository) List(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	if filter.Search != "" || filter.Query.Uses(encryptedFields...) {
+		return r.search(ctx, filter)
+	}
+
+	list, err := r.UserRepository.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Users {
+		if err := r.decrypt(ctx, &list.Users[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (r *EncryptedUserRepository) Iterate(ctx context.Context, filter domain.UserFilter, fn func(user *domain.User) error) error {
+	search := strings.ToLower(filter.Search)
+	filter.Search = ""
+	query := filter
+	if filter.Query.Uses(encryptedFields...) {
+		filter.Query = queryspec.Spec{}
+	}
+
+	return r.UserRepository.Iterate(ctx, filter, func(user *domain.User) error {
+		if err := r.decrypt(ctx, user); err != nil {
+			return err
+		}
+		if !matchesSearch(user, search) || !query.MatchesQuery(user) {
+			return nil
+		}
+		return fn(user)
+	})
+}
+
+func (r *EncryptedUserRepository) Store(ctx context.Context, user *domain.User) error {
+	encrypted, err := r.encrypt(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := r.UserRepository.Store(ctx, encrypted); err != nil {
+		return err
+	}
+	copyWrittenFields(user, encrypted)
+	return nil
+}
+
+func (r *EncryptedUserRepository) StoreBatch(ctx context.Context, users []*domain.User) error {
+	encrypted := make([]*domain.User, len(users))
+	for i, user := range users {
+		var err error
+		if encrypted[i], err = r.encrypt(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	if err := r.UserRepository.StoreBatch(ctx, encrypted); err != nil {
+		return err
+	}
+	for i, user := range users {
+		copyWrittenFields(user, encrypted[i])
+	}
+	return nil
+}
+
+// ExistingEmails checks both the blind index and the plaintext of each email,
+// since users stored before encryption was enabled still hold the plaintext
+func (r *EncryptedUserRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	lookups := make([]string, 0, 2*len(emails))
+	for _, email := range emails {
+		lookups = append(lookups, r.index.Compute(email), email)
+	}
+
+	found, err := r.UserRepository.ExistingEmails(ctx, lookups)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, email := range emails {
+		if found[r.index.Compute(email)] || found[email] {
+			existing[email] = true
+		}
+	}
+	return existing, nil
+}
+
+// Update keeps the stored ciphertext of a name or email that did not change,
+// so an update only rewrites, and the audit log only records, the fields it
+// changes
+func (r *EncryptedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	encrypted, err := r.encrypt(ctx, user)
+	if err != nil {
+		return err
+	}
+	if stored, err := r.UserRepository.FindByID(ctx, user.ID); err == nil {
+		if r.decryptsTo(ctx, stored.Name, fieldContext(user.ID, "name"), user.Name) {
+			encrypted.Name = stored.Name
+		}
+		if r.decryptsTo(ctx, stored.EncryptedEmail, fieldContext(user.ID, "email"), user.Email) {
+			encrypted.EncryptedEmail = stored.EncryptedEmail
+		}
+	}
+	if err := r.UserRepository.Update(ctx, encrypted); err != nil {
+		return err
+	}
+	copyWrittenFields(user, encrypted)
+	return nil
+}
+
+// copyWrittenFields hands the fields set by the wrapped repository on the
+// encrypted copy back to the caller's user
+func copyWrittenFields(user, encrypted *domain.User) {
+	user.Version = encrypted.Version
+	user.CreatedAt, user.UpdatedAt = encrypted.CreatedAt, encrypted.UpdatedAt
+	user.CreatedBy, user.UpdatedBy = encrypted.CreatedBy, encrypted.UpdatedBy
+}
+
+// Reencrypt encrypts every user still stored as plaintext or under a retired
+// key with the current key, returning how many users were rewritten. Soft
+// deleted users are not visible to the repository and keep their old key.
+func (r *EncryptedUserRepository) Reencrypt(ctx context.Context) (int, error) {
+	// Collect the IDs first so the updates do not run inside the iteration
+	var ids []string
+	err := r.UserRepository.Iterate(ctx, domain.UserFilter{}, func(user *domain.User) error {
+		if r.needsReencryption(user) {
+			ids = append(ids, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+	var errs []error
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return rewritten, errors.Join(append(errs, err)...)
+		}
+
+		done, err := r.reencrypt(ctx, id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if done {
+			rewritten++
+		}
+	}
+	return rewritten, errors.Join(errs...)
+}
+
+// reencrypt rewrites a single user, retrying when it was changed concurrently
+func (r *EncryptedUserRepository) reencrypt(ctx context.Context, id string) (bool, error) {
+	var err error
+	for attempt := 0; attempt < reencryptAttempts; attempt++ {
+		var user *domain.User
+		user, err = r.UserRepository.FindByID(ctx, id)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// A concurrent write may already have encrypted it with the current key
+		if !r.needsReencryption(user) {
+			return false, nil
+		}
+
+		if err = r.decrypt(ctx, user); err != nil {
+			return false, err
+		}
+		err = r.Update(ctx, user)
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			return err == nil, err
+		}
+	}
+	return false, err
+}
+
+// decryptsTo reports whether ciphertext is encrypted with the current key and
+// holds plaintext
+func (r *EncryptedUserRepository) decryptsTo(ctx context.Context, ciphertext, aad, plaintext string) bool {
+	if !encryption.IsEncrypted(ciphertext) || r.envelope.NeedsRotation(ciphertext) {
+		return false
+	}
+	decrypted, err := r.envelope.Decrypt(ctx, ciphertext, aad)
+	return err == nil && decrypted == plaintext
+}
+
+// needsReencryption reports whether a stored user is not fully encrypted with the current key
+func (r *EncryptedUserRepository) needsReencryption(user *domain.User) bool {
+	return r.envelope.NeedsRotation(user.Name) || r.envelope.NeedsRotation(user.EncryptedEmail)
+}
+
+// encrypt returns a copy of the user in its stored form
+func (r *EncryptedUserRepository) encrypt(ctx context.Context, user *domain.User) (*domain.User, error) {
+	name, err := r.envelope.Encrypt(ctx, user.Name, fieldContext(user.ID, "name"))
+	if err != nil {
+		return nil, err
+	}
+	email, err := r.envelope.Encrypt(ctx, user.Email, fieldContext(user.ID, "email"))
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := *user
+	encrypted.Name = name
+	encrypted.Email = r.index.Compute(user.Email)
+	encrypted.EncryptedEmail = email
+	return &encrypted, nil
+}
+
+// decrypt turns a stored user back into plaintext in place. Values that are
+// not encrypted are left as they are.
+func (r *EncryptedUserRepository) decrypt(ctx context.Context, user *domain.User) error {
+	if encryption.IsEncrypted(user.Name) {
+		name, err := r.envelope.Decrypt(ctx, user.Name, fieldContext(user.ID, "name"))
+		if err != nil {
+			return err
+		}
+		user.Name = name
+	}
+
+	if user.EncryptedEmail != "" {
+		email, err := r.envelope.Decrypt(ctx, user.EncryptedEmail, fieldContext(user.ID, "email"))
+		if err != nil {
+			return err
+		}
+		user.Email = email
+		user.EncryptedEmail = ""
+	}
+	return nil
+}
+
+// encryptedFields are the fields of domain.UserQuerySchema the wrapped
+// repository only sees as ciphertext or blind index
+var encryptedFields = []string{"name", "email"}
+
+// EncryptedUserColumns are the columns of the users table holding ciphertext
+// or a blind index when encryption is enabled, whose values the audit log
+// must not record
+var EncryptedUserColumns = []string{"name", "email", "encrypted_email"}
+
+// search lists the users whose decrypted name or email contains the search
+// text and pass the query filters, paginating like the other adapters. Pages
+// in a requested sort order need every match in memory, up to maxMatches.
+func (r *EncryptedUserRepository) search(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	if filter.Keyset != nil || len(filter.Query.Sort) > 0 {
+		var matches []domain.User
+		err := r.Iterate(ctx, filter, func(user *domain.User) error {
+			if len(matches) == r.maxMatches {
+				return fmt.Errorf("%w: more than %d users match, narrow the search or filters to sort or page by cursor", queryspec.ErrInvalid, r.maxMatches)
+			}
+			matches = append(matches, *user)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if filter.Keyset != nil {
+			return keysetPage(matches, filter), nil
+		}
+		filter.SortUsers(matches)
+		return offsetPage(matches, filter), nil
+	}
+
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := gormpkg.ClampPageSize(filter.PageSize)
+
+	start := (page - 1) * pageSize
+	list := &domain.UserList{Page: page, PageSize: pageSize}
+	err := r.Iterate(ctx, filter, func(user *domain.User) error {
+		if list.Total >= int64(start) && len(list.Users) < pageSize {
+			list.Users = append(list.Users, *user)
+		}
+		list.Total++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// fieldContext binds a ciphertext to the user and field it belongs to
+func fieldContext(id, field string) string {
+	return "users." + field + ":" + id
+}
+
+// matchesSearch reports whether the user's name or email contains search, which
+// must be lower case. Like LIKE under the default MySQL collation it ignores case.
+func matchesSearch(user *domain.User, search string) bool {
+	return search == "" ||
+		strings.Contains(strings.ToLower(user.Name), search) ||
+		strings.Contains(strings.ToLower(user.Email), search)
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+)
+
+// MemoryOutboxRepository is a thread-safe in-memory domain.OutboxRepository used
+// together with MemoryUserRepository. It has no transactions, so events are kept
+// even when a later step of the same change fails.
+type MemoryOutboxRepository struct {
+	mu       sync.Mutex
+	messages map[string]domain.OutboxMessage
+}
+
+// NewMemoryOutboxRepository creates an empty in-memory outbox
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{
+		messages: make(map[string]domain.OutboxMessage),
+	}
+}
+
+func (r *MemoryOutboxRepository) Add(ctx context.Context, events ...domain.Event) error {
+	messages, err := newOutboxMessages(events)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, message := range messages {
+		r.messages[message.ID] = message
+	}
+	return nil
+}
+
+func (r *MemoryOutboxRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []domain.OutboxMessage
+	for _, message := range r.messages {
+		if message.SentAt == nil && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].Attempts++
+		due[i].NextAttemptAt = now.Add(lease)
+		r.messages[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryOutboxRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message, ok := r.messages[id]; ok {
+		message.SentAt = &sentAt
+		r.messages[id] = message
+	}
+	return nil
+}
+
+func (r *MemoryOutboxRepository) MarkFailed(ctx context.Context, id string, lastError string, retryAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message, ok := r.messages[id]; ok && message.SentAt == nil {
+		message.LastError = lastError
+		message.NextAttemptAt = retryAt
+		r.messages[id] = message
+	}
+	return nil
+}
+
+func (r *MemoryOutboxRepository) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, message := range r.messages {
+		if message.SentAt != nil && message.SentAt.Before(before) {
+			delete(r.messages, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Messages returns every message in the outbox ordered by creation time
+func (r *MemoryOutboxRepository) Messages() []domain.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := make([]domain.OutboxMessage, 0, len(r.messages))
+	for _, message := range r.messages {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages
+}
+
+// MemoryTransactor is the domain.Transactor of the in-memory adapters. They have
+// no transactions, so it simply runs the work.
+type MemoryTransactor struct{}
+
+func (MemoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+)
+
+// MemoryPrivacyRepository is a thread-safe in-memory domain.PrivacyRepository
+// used together with MemoryUserRepository
+type MemoryPrivacyRepository struct {
+	mu       sync.Mutex
+	requests map[string]domain.PrivacyRequest
+	records  []domain.ErasureRecord
+}
+
+// NewMemoryPrivacyRepository creates an empty in-memory privacy repository
+func NewMemoryPrivacyRepository() *MemoryPrivacyRepository {
+	return &MemoryPrivacyRepository{
+		requests: make(map[string]domain.PrivacyRequest),
+	}
+}
+
+func (r *MemoryPrivacyRepository) StoreRequest(ctx context.Context, request *domain.PrivacyRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[request.ID] = *request
+	return nil
+}
+
+func (r *MemoryPrivacyRepository) FindRequest(ctx context.Context, id string) (*domain.PrivacyRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, domain.ErrPrivacyRequestNotFound
+	}
+	return &request, nil
+}
+
+func (r *MemoryPrivacyRepository) FindOpenErasure(ctx context.Context, userID string) (*domain.PrivacyRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, request := range r.requests {
+		if request.UserID == userID && request.Type == domain.PrivacyRequestErasure &&
+			(request.Status == domain.PrivacyRequestScheduled || request.Status == domain.PrivacyRequestProcessing) {
+			return &request, nil
+		}
+	}
+	return nil, domain.ErrPrivacyRequestNotFound
+}
+
+func (r *MemoryPrivacyRepository) DueErasures(ctx context.Context, now time.Time, limit int) ([]domain.PrivacyRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []domain.PrivacyRequest
+	for _, request := range r.requests {
+		if request.Type == domain.PrivacyRequestErasure && request.Status == domain.PrivacyRequestScheduled &&
+			!request.ScheduledFor.After(now) {
+			due = append(due, request)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ScheduledFor.Before(due[j].ScheduledFor)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *MemoryPrivacyRepository) TransitionRequest(ctx context.Context, request *domain.PrivacyRequest, from domain.PrivacyRequestStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.requests[request.ID]
+	if !ok {
+		return domain.ErrPrivacyRequestNotFound
+	}
+	if stored.Status != from {
+		return domain.ErrPrivacyRequestState
+	}
+
+	stored.Status = request.Status
+	stored.Error = request.Error
+	stored.CompletedAt = request.CompletedAt
+	stored.CancelledAt = request.CancelledAt
+	r.requests[request.ID] = stored
+	return nil
+}
+
+func (r *MemoryPrivacyRepository) AppendErasureRecord(ctx context.Context, record *domain.ErasureRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previousHash := ""
+	if len(r.records) > 0 {
+		previousHash = r.records[len(r.records)-1].Hash
+	}
+	record.Seal(int64(len(r.records)+1), previousHash)
+	r.records = append(r.records, *record)
+	return nil
+}
+
+func (r *MemoryPrivacyRepository) ErasureRecords(ctx context.Context) ([]domain.ErasureRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]domain.ErasureRecord(nil), r.records...), nil
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+)
+
+// MemoryUserRepository is a thread-safe in-memory domain.UserRepository for
+// local development and tests. It mirrors the database adapter: emails are
+// unique, deletes are soft and deleted users keep their email reserved.
+type MemoryUserRepository struct {
+	mu      sync.RWMutex
+	users   map[string]*domain.User
+	byEmail map[string]string
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:   make(map[string]*domain.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[r.byEmail[email]]
+	if !ok || user.DeletedAt != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	matches := r.matching(filter)
+	if filter.Keyset != nil {
+		return keysetPage(matches, filter), nil
+	}
+
+	filter.SortUsers(matches)
+	return offsetPage(matches, filter), nil
+}
+
+// Iterate calls fn for a snapshot of the matching users ordered by ID, so fn
+// may safely call back into the repository
+func (r *MemoryUserRepository) Iterate(ctx context.Context, filter domain.UserFilter, fn func(user *domain.User) error) error {
+	for _, user := range r.matching(filter) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) Store(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInsert(user, nil, nil); err != nil {
+		return err
+	}
+	r.insert(ctx, user)
+	return nil
+}
+
+// StoreBatch validates the whole batch before inserting so it is all or nothing
+func (r *MemoryUserRepository) StoreBatch(ctx context.Context, users []*domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pendingIDs := make(map[string]bool, len(users))
+	pendingEmails := make(map[string]bool, len(users))
+	for _, user := range users {
+		if err := r.checkInsert(user, pendingIDs, pendingEmails); err != nil {
+			return err
+		}
+		pendingIDs[user.ID] = true
+		pendingEmails[user.Email] = true
+	}
+
+	for _, user := range users {
+		r.insert(ctx, user)
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	existing := make(map[string]bool)
+	for _, email := range emails {
+		if _, ok := r.byEmail[email]; ok {
+			existing[email] = true
+		}
+	}
+	return existing, nil
+}
+
+// Update replaces the stored user if its version matches, then increments the
+// version and sets the audit fields on both the stored and the given user
+func (r *MemoryUserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok || stored.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	if stored.Version != user.Version {
+		return domain.ErrVersionConflict
+	}
+	if owner, taken := r.byEmail[user.Email]; taken && owner != user.ID {
+		return domain.ErrDuplicateEmail
+	}
+
+	delete(r.byEmail, stored.Email)
+	user.Version++
+	user.CreatedAt, user.CreatedBy = stored.CreatedAt, stored.CreatedBy
+	user.UpdatedAt, user.UpdatedBy = time.Now(), stored.UpdatedBy
+	if principal := domain.PrincipalFromContext(ctx); principal != "" {
+		user.UpdatedBy = principal
+	}
+
+	updated := *user
+	updated.DeletedAt = stored.DeletedAt
+	r.users[user.ID] = &updated
+	r.byEmail[updated.Email] = updated.ID
+	return nil
+}
+
+// Delete soft deletes the user; the email stays reserved like with the unique index
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	deletedAt := time.Now()
+	user.DeletedAt = &deletedAt
+	return nil
+}
+
+// Purge removes the user and releases its email, even if it was soft deleted
+func (r *MemoryUserRepository) Purge(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	delete(r.byEmail, user.Email)
+	delete(r.users, id)
+	return nil
+}
+
+// checkInsert reports whether the user can be inserted; the pending maps hold the
+// IDs and emails of earlier users in the same batch
+func (r *MemoryUserRepository) checkInsert(user *domain.User, pendingIDs map[string]bool, pendingEmails map[string]bool) error {
+	if _, ok := r.byEmail[user.Email]; ok || pendingEmails[user.Email] {
+		return domain.ErrDuplicateEmail
+	}
+	if _, ok := r.users[user.ID]; ok || pendingIDs[user.ID] {
+		return domain.ErrDuplicateEmail
+	}
+	return nil
+}
+
+// insert stores a copy of the user, filling in the fields the database adapter
+// gets from column defaults and the audit columns; callers must hold the write lock
+func (r *MemoryUserRepository) insert(ctx context.Context, user *domain.User) {
+	if user.Version == 0 {
+		user.Version = 1
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	if principal := domain.PrincipalFromContext(ctx); principal != "" {
+		if user.CreatedBy == "" {
+			user.CreatedBy = principal
+		}
+		if user.UpdatedBy == "" {
+			user.UpdatedBy = principal
+		}
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	r.byEmail[user.Email] = user.ID
+}
+
+// matching returns copies of the live users matching the filter ordered by ID.
+// Search is case-insensitive, like LIKE under the default MySQL collation.
+func (r *MemoryUserRepository) matching(filter domain.UserFilter) []domain.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	users := make([]domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		if !filter.MatchesDates(user) || !filter.MatchesQuery(user) {
+			continue
+		}
+		users = append(users, *user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
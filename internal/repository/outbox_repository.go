@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+)
+
+// OutboxRepository stores outbox messages with GORM. Add joins the transaction
+// carried by ctx, so events are committed together with the change that raised them.
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a GORM backed outbox
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) Add(ctx context.Context, events ...domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	messages, err := newOutboxMessages(events)
+	if err != nil {
+		return err
+	}
+	return gormpkg.Conn(ctx, r.db).Create(&messages).Error
+}
+
+// Claim bumps the attempt counter of each due message with a conditional update,
+// so of two relays reading the same message only one gets to publish it
+func (r *OutboxRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	var due []domain.OutboxMessage
+	err := gormpkg.Conn(gormpkg.WithPrimary(ctx), r.db).
+		Where("sent_at IS NULL AND next_attempt_at <= ?", now).
+		Order("next_attempt_at, created_at").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, message := range due {
+		result := gormpkg.Conn(ctx, r.db).Model(&domain.OutboxMessage{}).
+			Where("id = ? AND attempts = ? AND sent_at IS NULL", message.ID, message.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":        message.Attempts + 1,
+				"next_attempt_at": now.Add(lease),
+			})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		message.Attempts++
+		message.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, message)
+	}
+	return claimed, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	return gormpkg.Conn(ctx, r.db).Model(&domain.OutboxMessage{}).
+		Where("id = ?", id).
+		Update("sent_at", sentAt).Error
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, lastError string, retryAt time.Time) error {
+	return gormpkg.Conn(ctx, r.db).Model(&domain.OutboxMessage{}).
+		Where("id = ? AND sent_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"last_error":      lastError,
+			"next_attempt_at": retryAt,
+		}).Error
+}
+
+func (r *OutboxRepository) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	result := gormpkg.Conn(ctx, r.db).Where("sent_at < ?", before).Delete(&domain.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// newOutboxMessages encodes events as messages that are due immediately
+func newOutboxMessages(events []domain.Event) ([]domain.OutboxMessage, error) {
+	messages := make([]domain.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, domain.OutboxMessage{
+			ID:            event.ID,
+			EventType:     event.Type,
+			AggregateID:   event.AggregateID,
+			Payload:       string(payload),
+			CreatedAt:     event.OccurredAt,
+			NextAttemptAt: event.OccurredAt,
+		})
+	}
+	return messages, nil
+}
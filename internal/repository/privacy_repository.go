@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// appendAttempts bounds the retries when concurrent appends race for a sequence
+const appendAttempts = 5
+
+// PrivacyRepository stores privacy requests and erasure records with GORM. Calls
+// made with a context carrying a transaction from
+// gormpkg.TransactionManager.WithinTransaction take part in it.
+type PrivacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository creates a GORM backed privacy repository
+func NewPrivacyRepository(db *gorm.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+func (r *PrivacyRepository) StoreRequest(ctx context.Context, request *domain.PrivacyRequest) error {
+	return gormpkg.Conn(ctx, r.db).Create(request).Error
+}
+
+func (r *PrivacyRepository) FindRequest(ctx context.Context, id string) (*domain.PrivacyRequest, error) {
+	var request domain.PrivacyRequest
+	err := gormpkg.Conn(ctx, r.db).First(&request, "id = ?", id).Error
+	return &request, translatePrivacyError(err)
+}
+
+func (r *PrivacyRepository) FindOpenErasure(ctx context.Context, userID string) (*domain.PrivacyRequest, error) {
+	var request domain.PrivacyRequest
+	err := gormpkg.Conn(gormpkg.WithPrimary(ctx), r.db).
+		Where("user_id = ? AND type = ? AND status IN ?", userID, domain.PrivacyRequestErasure,
+			[]domain.PrivacyRequestStatus{domain.PrivacyRequestScheduled, domain.PrivacyRequestProcessing}).
+		First(&request).Error
+	return &request, translatePrivacyError(err)
+}
+
+func (r *PrivacyRepository) DueErasures(ctx context.Context, now time.Time, limit int) ([]domain.PrivacyRequest, error) {
+	var requests []domain.PrivacyRequest
+	err := gormpkg.Conn(gormpkg.WithPrimary(ctx), r.db).
+		Where("type = ? AND status = ? AND scheduled_for <= ?", domain.PrivacyRequestErasure, domain.PrivacyRequestScheduled, now).
+		Order("scheduled_for").
+		Limit(limit).
+		Find(&requests).Error
+	return requests, err
+}
+
+// TransitionRequest updates the mutable request fields guarded by the stored status
+func (r *PrivacyRepository) TransitionRequest(ctx context.Context, request *domain.PrivacyRequest, from domain.PrivacyRequestStatus) error {
+	result := gormpkg.Conn(ctx, r.db).Model(&domain.PrivacyRequest{}).
+		Where("id = ? AND status = ?", request.ID, from).
+		Updates(map[string]interface{}{
+			"status":       request.Status,
+			"error":        request.Error,
+			"completed_at": request.CompletedAt,
+			"cancelled_at": request.CancelledAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	if _, err := r.FindRequest(gormpkg.WithPrimary(ctx), request.ID); err != nil {
+		return err
+	}
+	return domain.ErrPrivacyRequestState
+}
+
+// AppendErasureRecord chains the record to the last one inside a transaction.
+// The sequence is the primary key, so of two concurrent appends one fails with a
+// duplicate key and is retried against the new last record. Inside a caller's
+// transaction each attempt runs in a savepoint, so a failed one can be retried,
+// and reads the last record with a lock, which on MySQL also reads past the
+// snapshot of a repeatable read transaction to see the record that won.
+func (r *PrivacyRepository) AppendErasureRecord(ctx context.Context, record *domain.ErasureRecord) error {
+	transactions := gormpkg.NewTransactionManager(r.db)
+	var err error
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		err = transactions.WithinTransaction(ctx, func(ctx context.Context) error {
+			var last domain.ErasureRecord
+			err := gormpkg.Conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).
+				Order("sequence DESC").Limit(1).Find(&last).Error
+			if err != nil {
+				return err
+			}
+
+			record.Seal(last.Sequence+1, last.Hash)
+			return gormpkg.Conn(ctx, r.db).Create(record).Error
+		})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *PrivacyRepository) ErasureRecords(ctx context.Context) ([]domain.ErasureRecord, error) {
+	var records []domain.ErasureRecord
+	err := gormpkg.Conn(ctx, r.db).Order("sequence").Find(&records).Error
+	return records, err
+}
+
+// translatePrivacyError maps GORM errors to the errors promised by domain.PrivacyRepository
+func translatePrivacyError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrPrivacyRequestNotFound
+	}
+	return err
+}
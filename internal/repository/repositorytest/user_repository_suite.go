@@ -0,0 +1,441 @@
+// Package repositorytest holds conformance suites that every repository adapter
+// must pass, so the in-memory and database adapters stay interchangeable.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/queryspec"
+)
+
+// UserRepositoryFactory returns an empty repository for a single subtest
+type UserRepositoryFactory func(t *testing.T) domain.UserRepository
+
+// RunUserRepositorySuite runs the domain.UserRepository contract against the
+// repositories returned by newRepo
+func RunUserRepositorySuite(t *testing.T, newRepo UserRepositoryFactory) {
+	ctx := context.Background()
+
+	t.Run("StoreAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("1", "Alice", "alice@example.com")
+		require.NoError(t, repo.Store(ctx, user))
+		assert.Equal(t, int64(1), user.Version)
+
+		found, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", found.Name)
+		assert.Equal(t, int64(1), found.Version)
+
+		found, err = repo.FindByEmail(ctx, "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "1", found.ID)
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.FindByID(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+		_, err = repo.FindByEmail(ctx, "missing@example.com")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("UniqueEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+
+		err := repo.Store(ctx, newUser("2", "Other Alice", "alice@example.com"))
+		assert.ErrorIs(t, err, domain.ErrDuplicateEmail)
+
+		require.NoError(t, repo.Store(ctx, newUser("2", "Bob", "bob@example.com")))
+		bob, err := repo.FindByID(ctx, "2")
+		require.NoError(t, err)
+		bob.Email = "alice@example.com"
+		assert.ErrorIs(t, repo.Update(ctx, bob), domain.ErrDuplicateEmail)
+	})
+
+	t.Run("StoreBatchIsAtomic", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+
+		err := repo.StoreBatch(ctx, []*domain.User{
+			newUser("2", "Bob", "bob@example.com"),
+			newUser("3", "Alice Again", "alice@example.com"),
+		})
+		assert.Error(t, err)
+
+		_, err = repo.FindByID(ctx, "2")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound, "a failed batch must not store any user")
+
+		batch := []*domain.User{
+			newUser("2", "Bob", "bob@example.com"),
+			newUser("3", "Carol", "carol@example.com"),
+		}
+		require.NoError(t, repo.StoreBatch(ctx, batch))
+		assert.Equal(t, int64(1), batch[0].Version)
+
+		existing, err := repo.ExistingEmails(ctx, []string{"bob@example.com", "carol@example.com", "dave@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"bob@example.com": true, "carol@example.com": true}, existing)
+	})
+
+	t.Run("UpdateWithVersion", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+
+		first, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		second, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+
+		first.Name = "Alice Updated"
+		require.NoError(t, repo.Update(ctx, first))
+		assert.Equal(t, int64(2), first.Version)
+
+		second.Name = "Stale Alice"
+		assert.ErrorIs(t, repo.Update(ctx, second), domain.ErrVersionConflict)
+
+		stored, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "Alice Updated", stored.Name)
+		assert.Equal(t, int64(2), stored.Version)
+
+		assert.ErrorIs(t, repo.Update(ctx, newUser("missing", "Nobody", "nobody@example.com")), domain.ErrUserNotFound)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+		require.NoError(t, repo.Store(ctx, newUser("2", "Bob", "bob@example.com")))
+
+		require.NoError(t, repo.Delete(ctx, "1"))
+		assert.ErrorIs(t, repo.Delete(ctx, "1"), domain.ErrUserNotFound)
+		assert.ErrorIs(t, repo.Delete(ctx, "missing"), domain.ErrUserNotFound)
+
+		_, err := repo.FindByID(ctx, "1")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = repo.FindByEmail(ctx, "alice@example.com")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+		list, err := repo.List(ctx, domain.UserFilter{Page: 1, PageSize: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), list.Total)
+
+		// The email of a deleted user stays reserved
+		existing, err := repo.ExistingEmails(ctx, []string{"alice@example.com"})
+		require.NoError(t, err)
+		assert.True(t, existing["alice@example.com"])
+		assert.ErrorIs(t, repo.Store(ctx, newUser("3", "New Alice", "alice@example.com")), domain.ErrDuplicateEmail)
+	})
+
+	t.Run("PurgeReleasesEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+		require.NoError(t, repo.Store(ctx, newUser("2", "Bob", "bob@example.com")))
+		require.NoError(t, repo.Delete(ctx, "2"))
+
+		require.NoError(t, repo.Purge(ctx, "1"))
+		require.NoError(t, repo.Purge(ctx, "2"), "soft deleted users can be purged")
+		assert.ErrorIs(t, repo.Purge(ctx, "1"), domain.ErrUserNotFound)
+
+		_, err := repo.FindByID(ctx, "1")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		existing, err := repo.ExistingEmails(ctx, []string{"alice@example.com", "bob@example.com"})
+		require.NoError(t, err)
+		assert.Empty(t, existing)
+		require.NoError(t, repo.Store(ctx, newUser("3", "New Alice", "alice@example.com")))
+	})
+
+	t.Run("AuditFields", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("1", "Alice", "alice@example.com")
+		require.NoError(t, repo.Store(domain.WithPrincipal(ctx, "admin"), user))
+
+		found, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		assert.WithinDuration(t, time.Now(), found.UpdatedAt, time.Minute)
+		assert.Equal(t, "admin", found.CreatedBy)
+		assert.Equal(t, "admin", found.UpdatedBy)
+
+		found.Name = "Alice Smith"
+		require.NoError(t, repo.Update(domain.WithPrincipal(ctx, "editor"), found))
+		assert.Equal(t, "editor", found.UpdatedBy)
+
+		// A change made without a principal keeps the last one
+		found.Name = "Alice Jones"
+		require.NoError(t, repo.Update(ctx, found))
+
+		found, err = repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", found.CreatedBy)
+		assert.Equal(t, "editor", found.UpdatedBy)
+		assert.False(t, found.UpdatedAt.Before(found.CreatedAt))
+	})
+
+	t.Run("PreferencesRoundTrip", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("1", "Alice", "alice@example.com")
+		user.Preferences = domain.UserPreferences{"theme": "dark", "notifications": map[string]interface{}{"email": true}}
+		require.NoError(t, repo.Store(ctx, user))
+
+		found, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "dark", found.Preferences["theme"])
+		assert.Equal(t, map[string]interface{}{"email": true}, found.Preferences["notifications"])
+
+		found.Preferences = domain.UserPreferences{"page_size": float64(50)}
+		require.NoError(t, repo.Update(ctx, found))
+
+		found, err = repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.UserPreferences{"page_size": float64(50)}, found.Preferences)
+
+		// Users stored without preferences read back an empty document
+		require.NoError(t, repo.Store(ctx, newUser("2", "Bob", "bob@example.com")))
+		found, err = repo.FindByID(ctx, "2")
+		require.NoError(t, err)
+		assert.Empty(t, found.Preferences)
+	})
+
+	t.Run("ListFiltersByDates", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+		hourAgo, inAnHour := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+		for _, tc := range []struct {
+			name   string
+			filter domain.UserFilter
+			want   int64
+		}{
+			{"CreatedAfter", domain.UserFilter{CreatedAfter: hourAgo}, 1},
+			{"CreatedAfterFuture", domain.UserFilter{CreatedAfter: inAnHour}, 0},
+			{"CreatedBefore", domain.UserFilter{CreatedBefore: inAnHour}, 1},
+			{"CreatedBeforePast", domain.UserFilter{CreatedBefore: hourAgo}, 0},
+			{"UpdatedRange", domain.UserFilter{UpdatedAfter: hourAgo, UpdatedBefore: inAnHour}, 1},
+			{"UpdatedAfterFuture", domain.UserFilter{UpdatedAfter: inAnHour}, 0},
+		} {
+			list, err := repo.List(ctx, tc.filter)
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.want, list.Total, tc.name)
+		}
+	})
+
+	t.Run("ListPaginatesAndFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 1; i <= 5; i++ {
+			require.NoError(t, repo.Store(ctx, newUser(fmt.Sprintf("%d", i), fmt.Sprintf("user %d", i), fmt.Sprintf("user%d@example.com", i))))
+		}
+		require.NoError(t, repo.Store(ctx, newUser("6", "Zed", "zed@example.org")))
+
+		list, err := repo.List(ctx, domain.UserFilter{Page: 2, PageSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), list.Total)
+		assert.Equal(t, 2, list.Page)
+		require.Len(t, list.Users, 2)
+		assert.Equal(t, "3", list.Users[0].ID)
+		assert.Equal(t, "4", list.Users[1].ID)
+
+		list, err = repo.List(ctx, domain.UserFilter{Page: 1, PageSize: 10, Search: "example.org"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), list.Total)
+		require.Len(t, list.Users, 1)
+		assert.Equal(t, "6", list.Users[0].ID)
+
+		// LIKE wildcards in the search text are matched literally
+		list, err = repo.List(ctx, domain.UserFilter{Page: 1, PageSize: 10, Search: "%"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), list.Total)
+	})
+
+	t.Run("ListCapsPageSize", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+
+		list, err := repo.List(ctx, domain.UserFilter{Page: 1, PageSize: 1000})
+		require.NoError(t, err)
+		assert.Equal(t, gormpkg.MaxPageSize, list.PageSize)
+
+		list, err = repo.List(ctx, domain.UserFilter{PageSize: 1000, Keyset: &domain.UserKeyset{}})
+		require.NoError(t, err)
+		assert.Equal(t, gormpkg.MaxPageSize, list.PageSize)
+	})
+
+	t.Run("ListSortsAndFiltersByQuery", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Carol", "carol@example.com")))
+		require.NoError(t, repo.Store(ctx, newUser("2", "Alice", "alice@example.org")))
+		require.NoError(t, repo.Store(ctx, newUser("3", "Bob", "bob@example.com")))
+		require.NoError(t, repo.Store(ctx, newUser("4", "Alice", "alice@example.net")))
+
+		ids := func(list *domain.UserList) []string {
+			var ids []string
+			for _, user := range list.Users {
+				ids = append(ids, user.ID)
+			}
+			return ids
+		}
+
+		for _, tc := range []struct {
+			name    string
+			sort    string
+			filters []queryspec.RawFilter
+			want    []string
+		}{
+			{"SortAscending", "name", nil, []string{"2", "4", "3", "1"}},
+			{"SortDescending", "-name", nil, []string{"1", "3", "2", "4"}},
+			{"SortByTwoFields", "name,-email", nil, []string{"2", "4", "3", "1"}},
+			{"Contains", "", []queryspec.RawFilter{{Field: "email", Operator: "contains", Value: "example.com"}}, []string{"1", "3"}},
+			{"ContainsWildcard", "", []queryspec.RawFilter{{Field: "email", Operator: "contains", Value: "_"}}, nil},
+			{"StartsWith", "", []queryspec.RawFilter{{Field: "name", Operator: "starts_with", Value: "Al"}}, []string{"2", "4"}},
+			{"Equals", "", []queryspec.RawFilter{{Field: "email", Value: "bob@example.com"}}, []string{"3"}},
+			{"In", "-id", []queryspec.RawFilter{{Field: "id", Operator: "in", Value: "1,3"}}, []string{"3", "1"}},
+			{"Ne", "", []queryspec.RawFilter{{Field: "name", Operator: "ne", Value: "Alice"}}, []string{"1", "3"}},
+			{"Version", "", []queryspec.RawFilter{{Field: "version", Operator: "gt", Value: "1"}}, nil},
+		} {
+			query, err := domain.UserQuerySchema.Parse(tc.sort, tc.filters)
+			require.NoError(t, err, tc.name)
+			list, err := repo.List(ctx, domain.UserFilter{Page: 1, PageSize: 10, Query: query})
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.want, ids(list), tc.name)
+			assert.Equal(t, int64(len(tc.want)), list.Total, tc.name)
+		}
+
+		// The requested sort applies before pagination
+		query, err := domain.UserQuerySchema.Parse("-name", nil)
+		require.NoError(t, err)
+		list, err := repo.List(ctx, domain.UserFilter{Page: 2, PageSize: 2, Query: query})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "4"}, ids(list))
+	})
+
+	t.Run("ListKeysetPages", func(t *testing.T) {
+		repo := newRepo(t)
+		// IDs run against creation order so the keyset cannot fall back on ID order
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 1; i <= 5; i++ {
+			user := newUser(fmt.Sprintf("%d", 6-i), fmt.Sprintf("user %d", i), fmt.Sprintf("user%d@example.com", i))
+			user.CreatedAt = base.Add(time.Duration(i) * time.Second)
+			require.NoError(t, repo.Store(ctx, user))
+		}
+		// Users created at the same time are ordered by ID
+		twin := newUser("0", "twin", "twin@example.com")
+		twin.CreatedAt = base.Add(5 * time.Second)
+		require.NoError(t, repo.Store(ctx, twin))
+		require.NoError(t, repo.Delete(ctx, "3"))
+
+		ids := func(list *domain.UserList) []string {
+			var ids []string
+			for _, user := range list.Users {
+				ids = append(ids, user.ID)
+			}
+			return ids
+		}
+
+		first, err := repo.List(ctx, domain.UserFilter{PageSize: 2, Keyset: &domain.UserKeyset{}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"5", "4"}, ids(first))
+		assert.True(t, first.HasNext)
+		assert.False(t, first.HasPrevious)
+
+		after := domain.KeyOf(&first.Users[1])
+		second, err := repo.List(ctx, domain.UserFilter{PageSize: 2, Keyset: &domain.UserKeyset{After: &after}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "0"}, ids(second))
+		assert.True(t, second.HasNext)
+		assert.True(t, second.HasPrevious)
+
+		after = domain.KeyOf(&second.Users[1])
+		last, err := repo.List(ctx, domain.UserFilter{PageSize: 2, Keyset: &domain.UserKeyset{After: &after}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, ids(last))
+		assert.False(t, last.HasNext)
+		assert.True(t, last.HasPrevious)
+
+		before := domain.KeyOf(&last.Users[0])
+		back, err := repo.List(ctx, domain.UserFilter{PageSize: 2, Keyset: &domain.UserKeyset{Before: &before}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "0"}, ids(back))
+		assert.True(t, back.HasNext)
+		assert.True(t, back.HasPrevious)
+
+		before = domain.KeyOf(&back.Users[0])
+		back, err = repo.List(ctx, domain.UserFilter{PageSize: 2, Keyset: &domain.UserKeyset{Before: &before}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"5", "4"}, ids(back))
+		assert.True(t, back.HasNext)
+		assert.False(t, back.HasPrevious)
+	})
+
+	t.Run("IterateIgnoresPagination", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, repo.Store(ctx, newUser(fmt.Sprintf("%d", i), fmt.Sprintf("user %d", i), fmt.Sprintf("user%d@example.com", i))))
+		}
+
+		var ids []string
+		err := repo.Iterate(ctx, domain.UserFilter{Page: 1, PageSize: 1}, func(user *domain.User) error {
+			ids = append(ids, user.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+		stop := errors.New("stop")
+		err = repo.Iterate(ctx, domain.UserFilter{}, func(user *domain.User) error {
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+	})
+
+	t.Run("ConcurrentUpdatesConflict", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(ctx, newUser("1", "Alice", "alice@example.com")))
+
+		const writers = 8
+		var wg sync.WaitGroup
+		results := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				user := newUser("1", fmt.Sprintf("Writer %d", i), "alice@example.com")
+				user.Version = 1
+				results <- repo.Update(ctx, user)
+			}(i)
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.ErrorIs(t, err, domain.ErrVersionConflict)
+		}
+		assert.Equal(t, 1, succeeded, "exactly one writer may win a version")
+	})
+}
+
+func newUser(id, name, email string) *domain.User {
+	return &domain.User{
+		ID:       id,
+		Name:     name,
+		Email:    email,
+		Password: "secret",
+	}
+}
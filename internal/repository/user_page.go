@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"sort"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+)
+
+// offsetPage selects the numbered page described by filter from the sorted users
+func offsetPage(users []domain.User, filter domain.UserFilter) *domain.UserList {
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := gormpkg.ClampPageSize(filter.PageSize)
+
+	start := (page - 1) * pageSize
+	if start > len(users) {
+		start = len(users)
+	}
+	end := start + pageSize
+	if end > len(users) {
+		end = len(users)
+	}
+
+	return &domain.UserList{
+		Users:    users[start:end],
+		Total:    int64(len(users)),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
+
+// keysetPage selects the keyset page described by filter from users, the
+// in-memory counterpart of gormpkg.KeysetPagination. users is sorted in place.
+func keysetPage(users []domain.User, filter domain.UserFilter) *domain.UserList {
+	pageSize := gormpkg.ClampPageSize(filter.PageSize)
+
+	sort.Slice(users, func(i, j int) bool {
+		return domain.KeyOf(&users[i]).Less(domain.KeyOf(&users[j]))
+	})
+
+	list := &domain.UserList{PageSize: pageSize}
+	switch keyset := filter.Keyset; {
+	case keyset.Before != nil:
+		end := sort.Search(len(users), func(i int) bool {
+			return !domain.KeyOf(&users[i]).Less(*keyset.Before)
+		})
+		start := end - pageSize
+		if start < 0 {
+			start = 0
+		}
+		list.Users = users[start:end]
+		list.HasPrevious = start > 0
+		list.HasNext = end < len(users)
+	default:
+		start := 0
+		if keyset.After != nil {
+			start = sort.Search(len(users), func(i int) bool {
+				return keyset.After.Less(domain.KeyOf(&users[i]))
+			})
+		}
+		end := start + pageSize
+		if end > len(users) {
+			end = len(users)
+		}
+		list.Users = users[start:end]
+		list.HasPrevious = start > 0
+		list.HasNext = end < len(users)
+	}
+	return list
+}
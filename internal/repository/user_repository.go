@@ -1,39 +1,222 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"strings"
+
 	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
 
 	"gorm.io/gorm"
 )
 
+// UserRepository stores users with GORM as userRow. The lookups by ID, writes
+// and deletes come from gormpkg.Repository. Calls made with a context carrying
+// a transaction from gormpkg.TransactionManager.WithinTransaction take part in it.
 type UserRepository struct {
-	db *gorm.DB
+	rows *gormpkg.Repository[userRow, string]
+	db   *gorm.DB
 }
 
 func NewUserRepository(db *gorm.DB) *UserRepository {
-	return &UserRepository{db: db}
+	return &UserRepository{
+		rows: gormpkg.NewRepository[userRow, string](db).TranslateErrors(translateUserError),
+		db:   db,
+	}
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	row, err := r.rows.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain(), nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var row userRow
+	if err := gormpkg.Conn(ctx, r.db).First(&row, "email = ?", email).Error; err != nil {
+		return nil, translateUserError(err)
+	}
+	return row.toDomain(), nil
+}
+
+// Store inserts the user and fills in the columns set on insert, such as the
+// version and creation time
+func (r *UserRepository) Store(ctx context.Context, user *domain.User) error {
+	row := newUserRow(user)
+	if err := r.rows.Store(ctx, row); err != nil {
+		return err
+	}
+	*user = *row.toDomain()
+	return nil
+}
+
+func (r *UserRepository) StoreBatch(ctx context.Context, users []*domain.User) error {
+	rows := make([]*userRow, len(users))
+	for i, user := range users {
+		rows[i] = newUserRow(user)
+	}
+	if err := r.rows.StoreBatch(ctx, rows); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		*users[i] = *row.toDomain()
+	}
+	return nil
+}
+
+// Update writes the user and, on success, fills in its new version and
+// update columns
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	row := newUserRow(user)
+	if err := r.rows.Update(ctx, row); err != nil {
+		return err
+	}
+	*user = *row.toDomain()
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	return r.rows.Delete(ctx, id)
+}
+
+func (r *UserRepository) Purge(ctx context.Context, id string) error {
+	return r.rows.Purge(ctx, id)
+}
+
+func (r *UserRepository) List(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	if filter.Keyset != nil {
+		return r.listKeyset(ctx, filter)
+	}
+
+	var rows []userRow
+	query := gormpkg.ApplySpecSort(applyUserFilter(gormpkg.Conn(ctx, r.db).Model(&userRow{}), filter), filter.Query)
+	result, err := gormpkg.OffsetPagination(query, &gormpkg.Pagination{
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		OrderBy:  "id",
+		Sort:     "asc",
+	}, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserList{
+		Users:    toDomainUsers(rows),
+		Total:    result.TotalRecords,
+		Page:     result.CurrentPage,
+		PageSize: result.PageSize,
+	}, nil
+}
+
+// userKeysetKeys is the keyset order of users; id breaks ties between users
+// created at the same time
+var userKeysetKeys = []gormpkg.SortKey{{Column: "created_at"}, {Column: "id"}}
+
+// listKeyset loads a keyset page of users without counting the matches
+func (r *UserRepository) listKeyset(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	keyset := gormpkg.Keyset{Keys: userKeysetKeys, PageSize: filter.PageSize}
+	if after := filter.Keyset.After; after != nil {
+		keyset.After = []interface{}{after.CreatedAt, after.ID}
+	}
+	if before := filter.Keyset.Before; before != nil {
+		keyset.Before = []interface{}{before.CreatedAt, before.ID}
+	}
+
+	var rows []userRow
+	result, err := gormpkg.KeysetPagination(applyUserFilter(gormpkg.Conn(ctx, r.db).Model(&userRow{}), filter), keyset, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserList{
+		Users:       toDomainUsers(rows),
+		PageSize:    gormpkg.ClampPageSize(filter.PageSize),
+		HasNext:     result.HasNext,
+		HasPrevious: result.HasPrevious,
+	}, nil
 }
 
-func (r *UserRepository) FindByID(id string) (*domain.User, error) {
-	var user domain.User
-	result := r.db.First(&user, "id = ?", id)
-	return &user, result.Error
+// Iterate streams matching users ordered by ID using a database cursor
+func (r *UserRepository) Iterate(ctx context.Context, filter domain.UserFilter, fn func(user *domain.User) error) error {
+	rows, err := applyUserFilter(gormpkg.Conn(ctx, r.db).Model(&userRow{}), filter).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row userRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row.toDomain()); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
 }
 
-func (r *UserRepository) FindByEmail(email string) (*domain.User, error) {
-	var user domain.User
-	result := r.db.First(&user, "email = ?", email)
-	return &user, result.Error
+func (r *UserRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(emails) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	// Soft deleted users still hold their email because of the unique index
+	if err := gormpkg.Conn(ctx, r.db).Unscoped().Model(&userRow{}).Where("email IN ?", emails).Pluck("email", &found).Error; err != nil {
+		return nil, err
+	}
+
+	for _, email := range found {
+		existing[email] = true
+	}
+	return existing, nil
 }
 
-func (r *UserRepository) Store(user *domain.User) error {
-	return r.db.Create(user).Error
+// applyUserFilter narrows the query to users matching the filter
+func applyUserFilter(db *gorm.DB, filter domain.UserFilter) *gorm.DB {
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		db = db.Where("name LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!'", pattern, pattern)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", filter.CreatedBefore)
+	}
+	if !filter.UpdatedAfter.IsZero() {
+		db = db.Where("updated_at >= ?", filter.UpdatedAfter)
+	}
+	if !filter.UpdatedBefore.IsZero() {
+		db = db.Where("updated_at < ?", filter.UpdatedBefore)
+	}
+	return gormpkg.ApplySpecFilters(db, filter.Query)
 }
 
-func (r *UserRepository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+// translateUserError maps GORM errors to the domain errors promised by
+// domain.UserRepository. Duplicate keys are only recognised when the connection
+// was opened with TranslateError enabled.
+func translateUserError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.ErrUserNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return domain.ErrDuplicateEmail
+	case errors.Is(err, gormpkg.ErrVersionConflict):
+		return domain.ErrVersionConflict
+	default:
+		return err
+	}
 }
 
-func (r *UserRepository) Delete(id string) error {
-	return r.db.Delete(&domain.User{}, "id = ?", id).Error
+// escapeLike escapes LIKE wildcards so the value is matched literally. The escape
+// character is "!" because backslash handling differs between dialects.
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
 }
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"time"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+)
+
+// userRow is the GORM model of the users table. It holds what only the
+// database needs, such as the soft delete marker, so domain.User stays free
+// of GORM types.
+//
+// The audit trail records who made each change, so it leaves out the updated
+// columns, and the password and email ciphertext only as changed.
+type userRow struct {
+	ID                 string
+	Name               string
+	Email              string
+	Password           string `audit:"redact"`
+	Version            int64  `gorm:"not null;default:1"`
+	AvatarKey          string
+	AvatarThumbnailKey string
+	EncryptedEmail     string `audit:"redact"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time `audit:"-"`
+	CreatedBy          string
+	UpdatedBy          string `audit:"-"`
+	Preferences        gormpkg.JSON[domain.UserPreferences]
+	DeletedAt          gorm.DeletedAt `gorm:"index"`
+}
+
+func (userRow) TableName() string {
+	return "users"
+}
+
+// AuditedModels returns the models whose changes the audit log records
+func AuditedModels() []interface{} {
+	return []interface{}{&userRow{}}
+}
+
+func newUserRow(user *domain.User) *userRow {
+	row := &userRow{
+		ID:                 user.ID,
+		Name:               user.Name,
+		Email:              user.Email,
+		Password:           user.Password,
+		Version:            user.Version,
+		AvatarKey:          user.AvatarKey,
+		AvatarThumbnailKey: user.AvatarThumbnailKey,
+		EncryptedEmail:     user.EncryptedEmail,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		CreatedBy:          user.CreatedBy,
+		UpdatedBy:          user.UpdatedBy,
+		Preferences:        gormpkg.NewJSON(user.Preferences),
+	}
+	if user.DeletedAt != nil {
+		row.DeletedAt = gorm.DeletedAt{Time: *user.DeletedAt, Valid: true}
+	}
+	return row
+}
+
+func (row *userRow) toDomain() *domain.User {
+	user := &domain.User{
+		ID:                 row.ID,
+		Name:               row.Name,
+		Email:              row.Email,
+		Password:           row.Password,
+		Version:            row.Version,
+		AvatarKey:          row.AvatarKey,
+		AvatarThumbnailKey: row.AvatarThumbnailKey,
+		EncryptedEmail:     row.EncryptedEmail,
+		CreatedAt:          row.CreatedAt,
+		UpdatedAt:          row.UpdatedAt,
+		CreatedBy:          row.CreatedBy,
+		UpdatedBy:          row.UpdatedBy,
+		Preferences:        row.Preferences.Data,
+	}
+	if row.DeletedAt.Valid {
+		deletedAt := row.DeletedAt.Time
+		user.DeletedAt = &deletedAt
+	}
+	return user
+}
+
+// toDomainUsers converts a page of rows
+func toDomainUsers(rows []userRow) []domain.User {
+	users := make([]domain.User, len(rows))
+	for i := range rows {
+		users[i] = *rows[i].toDomain()
+	}
+	return users
+}
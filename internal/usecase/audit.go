@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"app-hexagonal/internal/domain"
+)
+
+const (
+	defaultAuditPageSize = 20
+
+	// userEntityType is the entity type of users in the audit trail, their table
+	userEntityType = "users"
+)
+
+// AuditUsecaseInterface defines the interface for reading the audit trail
+type AuditUsecaseInterface interface {
+	// ListEntries returns a page of the audit entries matching the filter,
+	// newest first. An inverted time range returns ErrInvalidAuditFilter.
+	ListEntries(ctx context.Context, filter domain.AuditFilter) (*domain.AuditEntryList, error)
+}
+
+// AuditUsecase serves the audit trail recorded by the audit log plugin
+type AuditUsecase struct {
+	repo domain.AuditRepository
+}
+
+// NewAuditUsecase creates an AuditUsecase
+func NewAuditUsecase(repo domain.AuditRepository) *AuditUsecase {
+	return &AuditUsecase{repo: repo}
+}
+
+func (uc *AuditUsecase) ListEntries(ctx context.Context, filter domain.AuditFilter) (*domain.AuditEntryList, error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return nil, fmt.Errorf("%w: to must be after from", domain.ErrInvalidAuditFilter)
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = defaultAuditPageSize
+	}
+	return uc.repo.List(ctx, filter)
+}
+
+// PersonalDataContributor exposes the audit entries about a user to privacy
+// exports, and erases their values while keeping who changed which fields and
+// when. Register it after the contributors that change the user row, so the
+// entries of their changes are erased too.
+func (uc *AuditUsecase) PersonalDataContributor() domain.PersonalDataContributor {
+	return auditDataContributor{uc: uc}
+}
+
+type auditDataContributor struct {
+	uc *AuditUsecase
+}
+
+func (c auditDataContributor) Name() string {
+	return "audit_log"
+}
+
+func (c auditDataContributor) ExportPersonalData(ctx context.Context, userID string) (*domain.PersonalData, error) {
+	entries, err := c.uc.repo.EntityEntries(ctx, userEntityType, userID)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &domain.PersonalData{Data: entries}, nil
+}
+
+func (c auditDataContributor) ErasePersonalData(ctx context.Context, userID string) error {
+	return c.uc.repo.EraseValues(ctx, userEntityType, userID)
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"app-hexagonal/internal/domain"
+	"context"
 	"fmt"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 // AuthUsecaseInterface defines the interface for authentication use cases
 // This helps with dependency inversion in our hexagonal architecture
 type AuthUsecaseInterface interface {
-	Login(credentials *domain.Credentials) (*domain.TokenResponse, error)
+	Login(ctx context.Context, credentials *domain.Credentials) (*domain.TokenResponse, error)
 	RefreshToken(refreshToken string) (*domain.TokenResponse, error)
 	Logout(accessToken string) error
 	ValidateToken(tokenString string) (*domain.JWTClaims, error)
@@ -26,20 +27,24 @@ type AuthUsecase struct {
 	userRepo domain.UserRepository
 	// No need for authRepo since we're using JWT
 	jwtSecret []byte
+	events    eventRecorder
 }
 
-// NewAuthUsecase creates a new auth usecase
-func NewAuthUsecase(userRepo domain.UserRepository) *AuthUsecase {
+// NewAuthUsecase creates a new auth usecase. Successful logins are announced as
+// domain events when events.Outbox is set.
+func NewAuthUsecase(userRepo domain.UserRepository, events EventOptions) *AuthUsecase {
 	return &AuthUsecase{
 		userRepo:  userRepo,
 		jwtSecret: []byte("your-secret-key-change-this-in-production"), // In production, load from config
+		events:    newEventRecorder(events),
 	}
 }
 
-// Login authenticates a user and generates JWT tokens
-func (au *AuthUsecase) Login(credentials *domain.Credentials) (*domain.TokenResponse, error) {
+// Login authenticates a user and generates JWT tokens, which are only valid
+// for the tenant the user was found in
+func (au *AuthUsecase) Login(ctx context.Context, credentials *domain.Credentials) (*domain.TokenResponse, error) {
 	// Find user by email
-	user, err := au.userRepo.FindByEmail(credentials.Email)
+	user, err := au.userRepo.FindByEmail(ctx, credentials.Email)
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
@@ -50,17 +55,22 @@ func (au *AuthUsecase) Login(credentials *domain.Credentials) (*domain.TokenResp
 	}
 
 	// Generate access token (1 hour expiry)
-	accessToken, err := au.generateAccessToken(user.ID, user.Email)
+	tenant := domain.TenantFromContext(ctx)
+	accessToken, err := au.generateAccessToken(user.ID, user.Email, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Generate refresh token (7 days expiry)
-	refreshToken, err := au.generateRefreshToken(user.ID, user.Email)
+	refreshToken, err := au.generateRefreshToken(user.ID, user.Email, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	if err := au.events.record(ctx, newUserEvent(domain.EventUserLoggedIn, user)); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
 	return &domain.TokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -82,13 +92,13 @@ func (au *AuthUsecase) RefreshToken(refreshToken string) (*domain.TokenResponse,
 	// In a real implementation, you might want to store token type in claims
 
 	// Generate new access token
-	newAccessToken, err := au.generateAccessToken(claims.UserID, claims.Email)
+	newAccessToken, err := au.generateAccessToken(claims.UserID, claims.Email, claims.TenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new access token: %w", err)
 	}
 
 	// Generate new refresh token
-	newRefreshToken, err := au.generateRefreshToken(claims.UserID, claims.Email)
+	newRefreshToken, err := au.generateRefreshToken(claims.UserID, claims.Email, claims.TenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new refresh token: %w", err)
 	}
@@ -124,10 +134,11 @@ func (au *AuthUsecase) ValidateToken(tokenString string) (*domain.JWTClaims, err
 }
 
 // generateAccessToken generates a JWT access token
-func (au *AuthUsecase) generateAccessToken(userID, email string) (string, error) {
+func (au *AuthUsecase) generateAccessToken(userID, email, tenant string) (string, error) {
 	claims := &domain.JWTClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:   userID,
+		Email:    email,
+		TenantID: tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 1)), // 1 hour
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -140,10 +151,11 @@ func (au *AuthUsecase) generateAccessToken(userID, email string) (string, error)
 }
 
 // generateRefreshToken generates a JWT refresh token
-func (au *AuthUsecase) generateRefreshToken(userID, email string) (string, error) {
+func (au *AuthUsecase) generateRefreshToken(userID, email, tenant string) (string, error) {
 	claims := &domain.JWTClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:   userID,
+		Email:    email,
+		TenantID: tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)), // 7 days
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
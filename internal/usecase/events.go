@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// EventOptions configures where usecases record their domain events. With a
+// nil Outbox no events are recorded.
+type EventOptions struct {
+	Transactor domain.Transactor
+	Outbox     domain.OutboxRepository
+}
+
+// eventRecorder stores the events of a change in the outbox, in the same
+// transaction as the change itself
+type eventRecorder struct {
+	options EventOptions
+}
+
+func newEventRecorder(options EventOptions) eventRecorder {
+	return eventRecorder{options: options}
+}
+
+// within runs change in a transaction and adds the events it returns to the
+// outbox before the transaction commits
+func (r eventRecorder) within(ctx context.Context, change func(ctx context.Context) ([]domain.Event, error)) error {
+	if r.options.Outbox == nil {
+		_, err := change(ctx)
+		return err
+	}
+
+	return r.options.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		events, err := change(ctx)
+		if err != nil {
+			return err
+		}
+		return r.options.Outbox.Add(ctx, events...)
+	})
+}
+
+// record adds events that do not accompany a change to the outbox
+func (r eventRecorder) record(ctx context.Context, events ...domain.Event) error {
+	if r.options.Outbox == nil {
+		return nil
+	}
+	return r.options.Outbox.Add(ctx, events...)
+}
+
+// newUserEvent creates an event about the user
+func newUserEvent(eventType domain.EventType, user *domain.User) domain.Event {
+	return domain.Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		AggregateID: user.ID,
+		OccurredAt:  time.Now().UTC(),
+		Data: domain.UserEventData{
+			ID:      user.ID,
+			Version: user.Version,
+		},
+	}
+}
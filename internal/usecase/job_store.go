@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+)
+
+// defaultJobRetention is how long finished jobs are kept when no retention is configured
+const defaultJobRetention = 24 * time.Hour
+
+// jobStore keeps track of background jobs in memory. Callers always receive
+// copies so they never race with the goroutine running the job.
+type jobStore[T any] struct {
+	mu   sync.RWMutex
+	jobs map[string]*T
+}
+
+func newJobStore[T any]() *jobStore[T] {
+	return &jobStore[T]{
+		jobs: make(map[string]*T),
+	}
+}
+
+// add registers a job under id and returns a snapshot of it
+func (s *jobStore[T]) add(id string, job *T) *T {
+	s.mu.Lock()
+	s.jobs[id] = job
+	s.mu.Unlock()
+
+	snapshot := *job
+	return &snapshot
+}
+
+// update applies fn to the job while holding the lock
+func (s *jobStore[T]) update(id string, fn func(job *T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// get returns a snapshot of the job
+func (s *jobStore[T]) get(id string) (*T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+
+	snapshot := *job
+	return &snapshot, true
+}
+
+// prune forgets the jobs for which expired reports true
+func (s *jobStore[T]) prune(expired func(job *T) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, job := range s.jobs {
+		if expired(job) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// startedBy reports whether a job started for tenant by the principal
+// createdBy was started by the tenant and principal of ctx. Only they may
+// look it up.
+func startedBy(ctx context.Context, tenant, createdBy string) bool {
+	return domain.TenantFromContext(ctx) == tenant && domain.PrincipalFromContext(ctx) == createdBy
+}
+
+// finishedBefore reports whether a job with the given finish time ended before cutoff
+func finishedBefore(finishedAt *time.Time, cutoff time.Time) bool {
+	return finishedAt != nil && finishedAt.Before(cutoff)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"app-hexagonal/internal/domain"
+)
+
+const (
+	defaultRelayBatchSize  = 100
+	defaultRelayLease      = 30 * time.Second
+	defaultRelayMinBackoff = time.Second
+	defaultRelayMaxBackoff = time.Hour
+	defaultRelayRetention  = 7 * 24 * time.Hour
+)
+
+// RelayOptions configures the outbox relay. Zero values use the defaults.
+type RelayOptions struct {
+	// BatchSize is the number of messages claimed per run
+	BatchSize int
+	// Lease is how long a claimed message is hidden from other relays; it must
+	// be longer than publishing a batch takes
+	Lease time.Duration
+	// MinBackoff and MaxBackoff bound the exponential delay between attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retention is how long sent messages are kept before they are purged
+	Retention time.Duration
+	// Tenants lists the tenants whose outbox Run relays, with an empty ID for
+	// the shared one. Without it only the outbox of Run's context is relayed.
+	Tenants func(ctx context.Context) ([]string, error)
+}
+
+// OutboxRelay publishes the messages stored in the outbox. Delivery is at least
+// once: a relay that stops between publishing and marking a message sent
+// publishes it again after the lease, so consumers must drop duplicate IDs.
+type OutboxRelay struct {
+	outbox    domain.OutboxRepository
+	publisher domain.EventPublisher
+	options   RelayOptions
+}
+
+// NewOutboxRelay creates a relay from outbox to publisher
+func NewOutboxRelay(outbox domain.OutboxRepository, publisher domain.EventPublisher, options RelayOptions) *OutboxRelay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultRelayBatchSize
+	}
+	if options.Lease <= 0 {
+		options.Lease = defaultRelayLease
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = defaultRelayMinBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultRelayMaxBackoff
+	}
+	if options.Retention <= 0 {
+		options.Retention = defaultRelayRetention
+	}
+
+	return &OutboxRelay{
+		outbox:    outbox,
+		publisher: publisher,
+		options:   options,
+	}
+}
+
+// RelayPending publishes one batch of due messages, oldest first, and returns how
+// many were sent. A message that fails is retried after a backoff that doubles with
+// every attempt.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	messages, err := r.outbox.Claim(ctx, time.Now().UTC(), r.options.BatchSize, r.options.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, message := range messages {
+		if err := r.publisher.Publish(ctx, message); err != nil {
+			retryAt := time.Now().UTC().Add(r.backoff(message.Attempts))
+			errs = append(errs, fmt.Errorf("publish %s: %w", message.ID, errors.Join(err, r.outbox.MarkFailed(ctx, message.ID, err.Error(), retryAt))))
+			continue
+		}
+
+		if err := r.outbox.MarkSent(ctx, message.ID, time.Now().UTC()); err != nil {
+			errs = append(errs, fmt.Errorf("mark %s sent: %w", message.ID, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Run relays messages every interval until ctx is cancelled, going through the
+// outbox of every tenant in turn and purging old sent messages as it goes. A
+// full batch is followed by the next round immediately. Errors are passed to
+// onError and do not stop the loop.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	for {
+		full := false
+		report(forEachTenant(ctx, r.options.Tenants, func(ctx context.Context) {
+			sent, err := r.RelayPending(ctx)
+			report(err)
+			_, err = r.outbox.PurgeSent(ctx, time.Now().UTC().Add(-r.options.Retention))
+			report(err)
+			full = full || sent == r.options.BatchSize
+		}))
+		if full && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt of a message that has
+// been tried the given number of times
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.options.MinBackoff
+	for i := 1; i < attempts && delay < r.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.options.MaxBackoff {
+		delay = r.options.MaxBackoff
+	}
+	return delay
+}
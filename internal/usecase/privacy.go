@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultErasureGracePeriod = 7 * 24 * time.Hour
+	defaultErasureBatchSize   = 100
+
+	// userDataSection is the archive section holding the users table row
+	userDataSection = "user"
+)
+
+// PrivacyOptions configures the privacy workflows; zero values fall back to defaults
+type PrivacyOptions struct {
+	// ErasureGracePeriod is how long an erasure can be cancelled before it runs
+	ErasureGracePeriod time.Duration
+	// ErasureBatchSize is how many due erasures one ProcessDueErasures call handles
+	ErasureBatchSize int
+	// Tenants lists the tenants whose erasures RunErasures processes, with an
+	// empty ID for the shared one. Without it only those of its context run.
+	Tenants func(ctx context.Context) ([]string, error)
+}
+
+// PrivacyUsecaseInterface defines the interface for data subject request use cases
+type PrivacyUsecaseInterface interface {
+	// RegisterContributor adds a module whose data is included in exports and erasures
+	RegisterContributor(contributor domain.PersonalDataContributor)
+	// ExportPersonalData writes an archive of everything held about the user to w
+	ExportPersonalData(ctx context.Context, userID string, format domain.PrivacyExportFormat, w io.Writer) error
+	// RequestErasure schedules the user's data for erasure after the grace period
+	RequestErasure(ctx context.Context, userID string) (*domain.PrivacyRequest, error)
+	// CancelErasure cancels an erasure that has not started yet
+	CancelErasure(ctx context.Context, requestID string) (*domain.PrivacyRequest, error)
+	GetRequest(ctx context.Context, requestID string) (*domain.PrivacyRequest, error)
+	// ProcessDueErasures erases the data of users whose grace period ended and
+	// returns how many erasures completed
+	ProcessDueErasures(ctx context.Context) (int, error)
+	// ErasureRecords returns the erasure records after verifying their hash chain
+	ErasureRecords(ctx context.Context) ([]domain.ErasureRecord, error)
+}
+
+// PrivacyUsecase answers subject access and erasure requests for the users table
+// and every registered domain.PersonalDataContributor
+type PrivacyUsecase struct {
+	users   domain.UserRepository
+	repo    domain.PrivacyRepository
+	options PrivacyOptions
+
+	mu           sync.RWMutex
+	contributors []domain.PersonalDataContributor
+}
+
+// NewPrivacyUsecase creates a new privacy usecase
+func NewPrivacyUsecase(users domain.UserRepository, repo domain.PrivacyRepository, options PrivacyOptions) *PrivacyUsecase {
+	if options.ErasureGracePeriod <= 0 {
+		options.ErasureGracePeriod = defaultErasureGracePeriod
+	}
+	if options.ErasureBatchSize <= 0 {
+		options.ErasureBatchSize = defaultErasureBatchSize
+	}
+
+	return &PrivacyUsecase{
+		users:   users,
+		repo:    repo,
+		options: options,
+	}
+}
+
+// ParsePrivacyExportFormat resolves a format name to an archive format, defaulting to ZIP
+func ParsePrivacyExportFormat(value string) (domain.PrivacyExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "zip":
+		return domain.PrivacyExportZIP, nil
+	case "json":
+		return domain.PrivacyExportJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, value)
+	}
+}
+
+func (uc *PrivacyUsecase) RegisterContributor(contributor domain.PersonalDataContributor) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.contributors = append(uc.contributors, contributor)
+}
+
+// personalDataDocument is the JSON document at the root of every archive
+type personalDataDocument struct {
+	UserID      string                 `json:"user_id"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Data        map[string]interface{} `json:"data"`
+	// Files lists the attached files; JSON archives only name them
+	Files []string `json:"files,omitempty"`
+}
+
+// ExportPersonalData collects the data of every contributor before writing
+// anything, so a failing contributor never leaves a partial archive behind. The
+// export is recorded as a completed privacy request.
+func (uc *PrivacyUsecase) ExportPersonalData(ctx context.Context, userID string, format domain.PrivacyExportFormat, w io.Writer) error {
+	user, err := uc.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	document := personalDataDocument{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Data: map[string]interface{}{
+			userDataSection: user,
+		},
+	}
+
+	var files []archiveFile
+	for _, contributor := range uc.registeredContributors() {
+		data, err := contributor.ExportPersonalData(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("export %s data: %w", contributor.Name(), err)
+		}
+		if data == nil {
+			continue
+		}
+		if data.Data != nil {
+			document.Data[contributor.Name()] = data.Data
+		}
+		for _, file := range data.Files {
+			name := path.Join("files", contributor.Name(), path.Base(file.Name))
+			files = append(files, archiveFile{name: name, file: file})
+			document.Files = append(document.Files, name)
+		}
+	}
+
+	switch format {
+	case domain.PrivacyExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(document)
+	case domain.PrivacyExportZIP:
+		err = writePersonalDataZIP(ctx, w, document, files)
+	default:
+		return fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	return uc.repo.StoreRequest(ctx, &domain.PrivacyRequest{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Type:         domain.PrivacyRequestExport,
+		Status:       domain.PrivacyRequestCompleted,
+		RequestedAt:  now,
+		ScheduledFor: now,
+		CompletedAt:  &now,
+	})
+}
+
+// archiveFile is a contributor file and its path inside the archive
+type archiveFile struct {
+	name string
+	file domain.PersonalDataFile
+}
+
+// writePersonalDataZIP writes the JSON document followed by the attached files
+func writePersonalDataZIP(ctx context.Context, w io.Writer, document personalDataDocument, files []archiveFile) error {
+	archive := zip.NewWriter(w)
+
+	entry, err := archive.Create("personal_data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := copyArchiveFile(ctx, archive, file); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func copyArchiveFile(ctx context.Context, archive *zip.Writer, file archiveFile) error {
+	reader, err := file.file.Open(ctx)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", file.name, err)
+	}
+	defer reader.Close()
+
+	entry, err := archive.Create(file.name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, reader)
+	return err
+}
+
+// RequestErasure schedules an erasure unless one is already open for the user
+func (uc *PrivacyUsecase) RequestErasure(ctx context.Context, userID string) (*domain.PrivacyRequest, error) {
+	if _, err := uc.users.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.repo.FindOpenErasure(ctx, userID); err == nil {
+		return nil, domain.ErrErasureAlreadyRequested
+	} else if !errors.Is(err, domain.ErrPrivacyRequestNotFound) {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	request := &domain.PrivacyRequest{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Type:         domain.PrivacyRequestErasure,
+		Status:       domain.PrivacyRequestScheduled,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(uc.options.ErasureGracePeriod),
+	}
+	if err := uc.repo.StoreRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (uc *PrivacyUsecase) CancelErasure(ctx context.Context, requestID string) (*domain.PrivacyRequest, error) {
+	request, err := uc.repo.FindRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Type != domain.PrivacyRequestErasure {
+		return nil, domain.ErrPrivacyRequestState
+	}
+
+	now := time.Now().UTC()
+	request.Status = domain.PrivacyRequestCancelled
+	request.CancelledAt = &now
+	if err := uc.repo.TransitionRequest(ctx, request, domain.PrivacyRequestScheduled); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (uc *PrivacyUsecase) GetRequest(ctx context.Context, requestID string) (*domain.PrivacyRequest, error) {
+	return uc.repo.FindRequest(ctx, requestID)
+}
+
+// ProcessDueErasures claims each due erasure before running it, so concurrent
+// workers and cancellations never act on the same request twice. A failed
+// erasure is put back on the schedule with its error and retried on the next run.
+func (uc *PrivacyUsecase) ProcessDueErasures(ctx context.Context) (int, error) {
+	due, err := uc.repo.DueErasures(ctx, time.Now().UTC(), uc.options.ErasureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	var errs []error
+	for i := range due {
+		request := &due[i]
+
+		request.Status = domain.PrivacyRequestProcessing
+		if err := uc.repo.TransitionRequest(ctx, request, domain.PrivacyRequestScheduled); err != nil {
+			if errors.Is(err, domain.ErrPrivacyRequestState) {
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := uc.erase(ctx, request); err != nil {
+			request.Status = domain.PrivacyRequestScheduled
+			request.Error = err.Error()
+			errs = append(errs, fmt.Errorf("erasure %s: %w", request.ID, errors.Join(err, uc.repo.TransitionRequest(ctx, request, domain.PrivacyRequestProcessing))))
+			continue
+		}
+		completed++
+	}
+	return completed, errors.Join(errs...)
+}
+
+// erase runs every contributor and then removes the user row, which goes last
+// because contributors may still need it to find their data. The erasure record
+// is appended before the request completes, so a completed request always has one.
+func (uc *PrivacyUsecase) erase(ctx context.Context, request *domain.PrivacyRequest) error {
+	var erased []string
+	for _, contributor := range uc.registeredContributors() {
+		if err := contributor.ErasePersonalData(ctx, request.UserID); err != nil {
+			return fmt.Errorf("erase %s data: %w", contributor.Name(), err)
+		}
+		erased = append(erased, contributor.Name())
+	}
+
+	if err := uc.users.Purge(ctx, request.UserID); err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return fmt.Errorf("erase %s data: %w", userDataSection, err)
+	}
+	erased = append(erased, userDataSection)
+
+	now := time.Now().UTC()
+	err := uc.repo.AppendErasureRecord(ctx, &domain.ErasureRecord{
+		ID:           uuid.New().String(),
+		RequestID:    request.ID,
+		UserID:       request.UserID,
+		Contributors: erased,
+		RequestedAt:  request.RequestedAt,
+		ErasedAt:     now,
+	})
+	if err != nil {
+		return fmt.Errorf("record erasure: %w", err)
+	}
+
+	request.Status = domain.PrivacyRequestCompleted
+	request.Error = ""
+	request.CompletedAt = &now
+	return uc.repo.TransitionRequest(ctx, request, domain.PrivacyRequestProcessing)
+}
+
+func (uc *PrivacyUsecase) ErasureRecords(ctx context.Context) ([]domain.ErasureRecord, error) {
+	records, err := uc.repo.ErasureRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.VerifyErasureRecords(records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RunErasures processes the due erasures of every tenant every interval until
+// ctx is cancelled. Errors are passed to onError and do not stop the loop.
+func (uc *PrivacyUsecase) RunErasures(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	for {
+		report(forEachTenant(ctx, uc.options.Tenants, func(ctx context.Context) {
+			_, err := uc.ProcessDueErasures(ctx)
+			report(err)
+		}))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (uc *PrivacyUsecase) registeredContributors() []domain.PersonalDataContributor {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	return append([]domain.PersonalDataContributor(nil), uc.contributors...)
+}
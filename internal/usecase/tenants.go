@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"context"
+
+	"app-hexagonal/internal/domain"
+)
+
+// forEachTenant calls fn once for every tenant listed by tenants, with a
+// context carrying the tenant, or once with ctx as it is when tenants is nil.
+// An empty tenant ID stands for the data shared by all tenants. It stops
+// early, without an error, once ctx is done.
+func forEachTenant(ctx context.Context, tenants func(ctx context.Context) ([]string, error), fn func(ctx context.Context)) error {
+	if tenants == nil {
+		fn(ctx)
+		return nil
+	}
+
+	ids, err := tenants(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if id == "" {
+			fn(ctx)
+		} else {
+			fn(domain.WithTenant(ctx, id))
+		}
+	}
+	return nil
+}
@@ -1,41 +1,181 @@
 package usecase
 
-import "app-hexagonal/internal/domain"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/cursor"
+	"app-hexagonal/pkg/queryspec"
+
+	"github.com/google/uuid"
+)
 
 // UserUsecaseInterface defines the interface for user use cases
 // This helps with dependency inversion in our hexagonal architecture
 type UserUsecaseInterface interface {
-	GetUserByID(id string) (*domain.User, error)
-	GetUserByEmail(email string) (*domain.User, error)
-	CreateUser(user *domain.User) error
-	UpdateUser(user *domain.User) error
-	DeleteUser(id string) error
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error)
+	// ListUsersByCursor returns the keyset page identified by cursor, which is
+	// empty for the first page or a NextCursor or PreviousCursor of an earlier page.
+	// Keyset pages keep their own order, so a filter with a sort is rejected.
+	ListUsersByCursor(ctx context.Context, filter domain.UserFilter, cursor string) (*domain.UserList, error)
+	CreateUser(ctx context.Context, user *domain.User) error
+	UpdateUser(ctx context.Context, user *domain.User) error
+	DeleteUser(ctx context.Context, id string) error
+	// WatchUsers streams create/update/delete changes until ctx is cancelled.
+	// A watcher that falls too far behind is closed early; the channel closing
+	// while ctx is still live means changes were missed (domain.ErrWatcherTooSlow).
+	WatchUsers(ctx context.Context) <-chan domain.UserChange
+}
+
+// UserOptions configures the user usecase; zero values fall back to defaults
+type UserOptions struct {
+	// CursorKey signs pagination cursors. Without it a random key is used,
+	// so cursors only work on the instance that issued them until it restarts.
+	CursorKey []byte
 }
 
 type UserUsecase struct {
-	repo domain.UserRepository
+	repo    domain.UserRepository
+	feed    *userFeed
+	events  eventRecorder
+	cursors *cursor.Codec
 }
 
-func NewUserUsecase(repo domain.UserRepository) *UserUsecase {
-	return &UserUsecase{repo: repo}
+// NewUserUsecase creates the user usecase. Creates, updates and deletes are
+// announced as domain events when events.Outbox is set.
+func NewUserUsecase(repo domain.UserRepository, events EventOptions, options UserOptions) *UserUsecase {
+	cursors := cursor.NewRandomCodec()
+	if len(options.CursorKey) > 0 {
+		cursors = cursor.NewCodec(options.CursorKey)
+	}
+
+	return &UserUsecase{
+		repo:    repo,
+		feed:    newUserFeed(),
+		events:  newEventRecorder(events),
+		cursors: cursors,
+	}
 }
 
-func (uc *UserUsecase) GetUserByID(id string) (*domain.User, error) {
-	return uc.repo.FindByID(id)
+func (uc *UserUsecase) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return uc.repo.FindByID(ctx, id)
 }
 
-func (uc *UserUsecase) GetUserByEmail(email string) (*domain.User, error) {
-	return uc.repo.FindByEmail(email)
+func (uc *UserUsecase) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return uc.repo.FindByEmail(ctx, email)
 }
 
-func (uc *UserUsecase) CreateUser(user *domain.User) error {
-	return uc.repo.Store(user)
+func (uc *UserUsecase) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	return uc.repo.List(ctx, filter)
+}
+
+// userCursor is the content of a user pagination cursor
+type userCursor struct {
+	Key      domain.UserKey `json:"k"`
+	Backward bool           `json:"b,omitempty"`
+}
+
+func (uc *UserUsecase) ListUsersByCursor(ctx context.Context, filter domain.UserFilter, token string) (*domain.UserList, error) {
+	if len(filter.Query.Sort) > 0 {
+		return nil, fmt.Errorf("%w: sort cannot be combined with a cursor", queryspec.ErrInvalid)
+	}
+	filter.Keyset = &domain.UserKeyset{}
+	if token != "" {
+		var position userCursor
+		if err := uc.cursors.Decode(token, &position); err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		if position.Backward {
+			filter.Keyset.Before = &position.Key
+		} else {
+			filter.Keyset.After = &position.Key
+		}
+	}
+
+	list, err := uc.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Users) == 0 {
+		return list, nil
+	}
+
+	if list.HasNext {
+		if list.NextCursor, err = uc.cursors.Encode(userCursor{Key: domain.KeyOf(&list.Users[len(list.Users)-1])}); err != nil {
+			return nil, err
+		}
+	}
+	if list.HasPrevious {
+		if list.PreviousCursor, err = uc.cursors.Encode(userCursor{Key: domain.KeyOf(&list.Users[0]), Backward: true}); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (uc *UserUsecase) CreateUser(ctx context.Context, user *domain.User) error {
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+
+	err := uc.events.within(ctx, func(ctx context.Context) ([]domain.Event, error) {
+		if err := uc.repo.Store(ctx, user); err != nil {
+			return nil, err
+		}
+		return []domain.Event{newUserEvent(domain.EventUserCreated, user)}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.notify(ctx, domain.UserChangeCreated, *user)
+	return nil
+}
+
+func (uc *UserUsecase) UpdateUser(ctx context.Context, user *domain.User) error {
+	err := uc.events.within(ctx, func(ctx context.Context) ([]domain.Event, error) {
+		if err := uc.repo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		return []domain.Event{newUserEvent(domain.EventUserUpdated, user)}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.notify(ctx, domain.UserChangeUpdated, *user)
+	return nil
+}
+
+func (uc *UserUsecase) DeleteUser(ctx context.Context, id string) error {
+	err := uc.events.within(ctx, func(ctx context.Context) ([]domain.Event, error) {
+		if err := uc.repo.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		return []domain.Event{newUserEvent(domain.EventUserDeleted, &domain.User{ID: id})}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.notify(ctx, domain.UserChangeDeleted, domain.User{ID: id})
+	return nil
 }
 
-func (uc *UserUsecase) UpdateUser(user *domain.User) error {
-	return uc.repo.Update(user)
+func (uc *UserUsecase) WatchUsers(ctx context.Context) <-chan domain.UserChange {
+	return uc.feed.subscribe(ctx)
 }
 
-func (uc *UserUsecase) DeleteUser(id string) error {
-	return uc.repo.Delete(id)
+// notify publishes a change to the watchers of the tenant of ctx
+func (uc *UserUsecase) notify(ctx context.Context, changeType domain.UserChangeType, user domain.User) {
+	uc.feed.publish(domain.UserChange{
+		Type:       changeType,
+		User:       user,
+		Tenant:     domain.TenantFromContext(ctx),
+		OccurredAt: time.Now(),
+	})
 }
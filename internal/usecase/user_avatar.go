@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register GIF decoding
+	"image/jpeg"
+	_ "image/png" // register PNG decoding
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAvatarMaxSize       = 5 << 20
+	defaultAvatarMaxDimension  = 4096
+	defaultAvatarThumbnailSize = 128
+	defaultAvatarURLTTL        = 15 * time.Minute
+)
+
+// avatarExtensions maps accepted image content types to object key extensions
+var avatarExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// AvatarOptions limits avatar uploads; zero values fall back to defaults
+type AvatarOptions struct {
+	// MaxSize is the largest accepted upload in bytes
+	MaxSize int64
+	// MaxDimension is the largest accepted width or height in pixels
+	MaxDimension int
+	// ThumbnailSize is the edge length of the square thumbnail
+	ThumbnailSize int
+	// URLTTL is how long signed download URLs stay valid
+	URLTTL time.Duration
+	// OnOrphan is called with the key of an image that is no longer referenced
+	// but could not be deleted, so it can be reported and removed later
+	OnOrphan func(key string, err error)
+}
+
+// UserAvatarUsecaseInterface defines the interface for user avatar use cases
+type UserAvatarUsecaseInterface interface {
+	// UploadAvatar validates the image read from r, stores it together with a
+	// thumbnail and returns signed download URLs
+	UploadAvatar(ctx context.Context, userID string, r io.Reader) (*domain.Avatar, error)
+	// GetAvatar returns signed download URLs for the user's current avatar
+	GetAvatar(ctx context.Context, userID string) (*domain.Avatar, error)
+}
+
+// UserAvatarUsecase stores user avatars in a domain.Storage
+type UserAvatarUsecase struct {
+	repo    domain.UserRepository
+	storage domain.Storage
+	options AvatarOptions
+}
+
+// NewUserAvatarUsecase creates a new user avatar usecase
+func NewUserAvatarUsecase(repo domain.UserRepository, storage domain.Storage, options AvatarOptions) *UserAvatarUsecase {
+	if options.MaxSize <= 0 {
+		options.MaxSize = defaultAvatarMaxSize
+	}
+	if options.MaxDimension <= 0 {
+		options.MaxDimension = defaultAvatarMaxDimension
+	}
+	if options.ThumbnailSize <= 0 {
+		options.ThumbnailSize = defaultAvatarThumbnailSize
+	}
+	if options.URLTTL <= 0 {
+		options.URLTTL = defaultAvatarURLTTL
+	}
+
+	return &UserAvatarUsecase{
+		repo:    repo,
+		storage: storage,
+		options: options,
+	}
+}
+
+func (uc *UserAvatarUsecase) UploadAvatar(ctx context.Context, userID string, r io.Reader) (*domain.Avatar, error) {
+	user, err := uc.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read one byte past the limit so oversized uploads can be detected
+	data, err := io.ReadAll(io.LimitReader(r, uc.options.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > uc.options.MaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", domain.ErrFileTooLarge, uc.options.MaxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := avatarExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported content type %s", domain.ErrInvalidImage, contentType)
+	}
+
+	// Check the dimensions before decoding so huge images are not allocated
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+	if config.Width > uc.options.MaxDimension || config.Height > uc.options.MaxDimension {
+		return nil, fmt.Errorf("%w: dimensions exceed %dpx", domain.ErrInvalidImage, uc.options.MaxDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, squareThumbnail(img, uc.options.ThumbnailSize), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	// Every upload gets new keys so cached URLs never serve stale images
+	prefix := fmt.Sprintf("avatars/%s/%s", user.ID, uuid.New().String())
+	avatarKey := prefix + extension
+	thumbnailKey := prefix + "_thumb.jpg"
+
+	if err := uc.storage.Put(ctx, avatarKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+	if err := uc.storage.Put(ctx, thumbnailKey, &thumbnail, int64(thumbnail.Len()), "image/jpeg"); err != nil {
+		uc.discard(ctx, avatarKey)
+		return nil, err
+	}
+
+	oldAvatarKey, oldThumbnailKey := user.AvatarKey, user.AvatarThumbnailKey
+	user.AvatarKey = avatarKey
+	user.AvatarThumbnailKey = thumbnailKey
+
+	if err := uc.repo.Update(ctx, user); err != nil {
+		uc.discard(ctx, avatarKey, thumbnailKey)
+		return nil, err
+	}
+
+	// The previous images are no longer referenced; failing to remove them
+	// only leaks storage, so the upload still succeeds
+	uc.discard(ctx, oldAvatarKey, oldThumbnailKey)
+
+	return uc.signedAvatar(ctx, user)
+}
+
+// discard deletes images that are no longer referenced, reporting those that
+// could not be deleted to OnOrphan
+func (uc *UserAvatarUsecase) discard(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := uc.storage.Delete(ctx, key); err != nil && uc.options.OnOrphan != nil {
+			uc.options.OnOrphan(key, err)
+		}
+	}
+}
+
+func (uc *UserAvatarUsecase) GetAvatar(ctx context.Context, userID string) (*domain.Avatar, error) {
+	user, err := uc.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarKey == "" {
+		return nil, domain.ErrObjectNotFound
+	}
+
+	return uc.signedAvatar(ctx, user)
+}
+
+// signedAvatar builds time-limited URLs for the user's avatar images
+func (uc *UserAvatarUsecase) signedAvatar(ctx context.Context, user *domain.User) (*domain.Avatar, error) {
+	expiresAt := time.Now().Add(uc.options.URLTTL)
+
+	url, err := uc.storage.SignedURL(ctx, user.AvatarKey, uc.options.URLTTL)
+	if err != nil {
+		return nil, err
+	}
+	thumbnailURL, err := uc.storage.SignedURL(ctx, user.AvatarThumbnailKey, uc.options.URLTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Avatar{
+		URL:          url,
+		ThumbnailURL: thumbnailURL,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// squareThumbnail center-crops img to a square and scales it to size x size
+// by averaging the source pixels covered by each target pixel. Transparent
+// areas are flattened onto white since the thumbnail is encoded as JPEG.
+func squareThumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	edge := bounds.Dx()
+	if bounds.Dy() < edge {
+		edge = bounds.Dy()
+	}
+	offsetX := bounds.Min.X + (bounds.Dx()-edge)/2
+	offsetY := bounds.Min.Y + (bounds.Dy()-edge)/2
+
+	thumb := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0 := offsetY + y*edge/size
+		y1 := offsetY + (y+1)*edge/size
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < size; x++ {
+			x0 := offsetX + x*edge/size
+			x1 := offsetX + (x+1)*edge/size
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// RGBA() is alpha-premultiplied, so compositing over white adds the uncovered part
+			white := 0xffff - a/n
+			i := thumb.PixOffset(x, y)
+			thumb.Pix[i+0] = uint8((r/n + white) >> 8)
+			thumb.Pix[i+1] = uint8((g/n + white) >> 8)
+			thumb.Pix[i+2] = uint8((b/n + white) >> 8)
+			thumb.Pix[i+3] = 0xff
+		}
+	}
+	return thumb
+}
+
+// PersonalDataContributor exposes the stored avatar images to privacy exports
+// and erasures
+func (uc *UserAvatarUsecase) PersonalDataContributor() domain.PersonalDataContributor {
+	return avatarDataContributor{uc: uc}
+}
+
+type avatarDataContributor struct {
+	uc *UserAvatarUsecase
+}
+
+func (c avatarDataContributor) Name() string {
+	return "avatar"
+}
+
+func (c avatarDataContributor) ExportPersonalData(ctx context.Context, userID string) (*domain.PersonalData, error) {
+	user, err := c.uc.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarKey == "" {
+		return nil, nil
+	}
+
+	var files []domain.PersonalDataFile
+	for _, key := range []string{user.AvatarKey, user.AvatarThumbnailKey} {
+		files = append(files, domain.PersonalDataFile{
+			Name: path.Base(key),
+			Open: func(ctx context.Context) (io.ReadCloser, error) {
+				reader, _, err := c.uc.storage.Get(ctx, key)
+				return reader, err
+			},
+		})
+	}
+	return &domain.PersonalData{Files: files}, nil
+}
+
+// ErasePersonalData deletes the avatar images and clears the user's references
+// to them. A user that no longer exists has nothing left to erase.
+func (c avatarDataContributor) ErasePersonalData(ctx context.Context, userID string) error {
+	user, err := c.uc.repo.FindByID(ctx, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.AvatarKey == "" {
+		return nil
+	}
+
+	for _, key := range []string{user.AvatarKey, user.AvatarThumbnailKey} {
+		if err := c.uc.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	user.AvatarKey = ""
+	user.AvatarThumbnailKey = ""
+	return c.uc.repo.Update(ctx, user)
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// UserExportUsecaseInterface defines the interface for user export use cases
+type UserExportUsecaseInterface interface {
+	// Export streams matching users to w and returns the number of exported users
+	Export(ctx context.Context, format domain.ExportFormat, filter domain.UserFilter, columns []string, w io.Writer) (int, error)
+	// StartExportJob writes the export to the export directory in the
+	// background, for the tenant and principal of ctx but past its cancellation
+	StartExportJob(ctx context.Context, format domain.ExportFormat, filter domain.UserFilter, columns []string) (*domain.ExportJob, error)
+	// GetExportJob returns a job started by the tenant and principal of ctx
+	GetExportJob(ctx context.Context, id string) (*domain.ExportJob, error)
+}
+
+// ExportOptions configures the user export usecase; zero values fall back to defaults
+type ExportOptions struct {
+	// Dir is where background exports are written. It should be dedicated to
+	// exports, since files in it older than Retention are removed.
+	Dir string
+	// Retention is how long finished background exports, and their files,
+	// are kept. It defaults to a day.
+	Retention time.Duration
+}
+
+// UserExportUsecase streams users from the repository into export files
+type UserExportUsecase struct {
+	repo      domain.UserRepository
+	dir       string
+	retention time.Duration
+	jobs      *jobStore[domain.ExportJob]
+}
+
+// NewUserExportUsecase creates a new user export usecase that stores
+// background exports in options.Dir
+func NewUserExportUsecase(repo domain.UserRepository, options ExportOptions) *UserExportUsecase {
+	if options.Retention <= 0 {
+		options.Retention = defaultJobRetention
+	}
+
+	return &UserExportUsecase{
+		repo:      repo,
+		dir:       options.Dir,
+		retention: options.Retention,
+		jobs:      newJobStore[domain.ExportJob](),
+	}
+}
+
+// ParseExportFormat resolves a format name to an export format, defaulting to CSV
+func ParseExportFormat(value string) (domain.ExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "csv":
+		return domain.ExportFormatCSV, nil
+	case "ndjson", "jsonl":
+		return domain.ExportFormatNDJSON, nil
+	case "xlsx":
+		return domain.ExportFormatXLSX, nil
+	default:
+		return "", fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, value)
+	}
+}
+
+// ParseExportColumns validates a comma separated column list against
+// domain.UserExportColumns. An empty list selects every column.
+func ParseExportColumns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return domain.UserExportColumns, nil
+	}
+
+	allowed := make(map[string]bool, len(domain.UserExportColumns))
+	for _, column := range domain.UserExportColumns {
+		allowed[column] = true
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, column := range strings.Split(value, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "" || seen[column] {
+			continue
+		}
+		if !allowed[column] {
+			return nil, fmt.Errorf("unknown export column %q", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func (uc *UserExportUsecase) Export(ctx context.Context, format domain.ExportFormat, filter domain.UserFilter, columns []string, w io.Writer) (int, error) {
+	return uc.export(ctx, format, filter, columns, w, nil)
+}
+
+func (uc *UserExportUsecase) StartExportJob(ctx context.Context, format domain.ExportFormat, filter domain.UserFilter, columns []string) (*domain.ExportJob, error) {
+	if err := os.MkdirAll(uc.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	uc.prune(time.Now())
+
+	id := uuid.New().String()
+	job := uc.jobs.add(id, &domain.ExportJob{
+		ID:        id,
+		Format:    format,
+		Status:    domain.JobPending,
+		FileName:  fmt.Sprintf("users-%s.%s", time.Now().UTC().Format("20060102-150405"), format),
+		StartedAt: time.Now(),
+		CreatedBy: domain.PrincipalFromContext(ctx),
+		Tenant:    domain.TenantFromContext(ctx),
+	})
+
+	// The job outlives the request but still reads the request's tenant
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		uc.jobs.update(id, func(j *domain.ExportJob) {
+			j.Status = domain.JobRunning
+		})
+
+		path, exported, err := uc.exportToFile(ctx, id, format, filter, columns)
+
+		uc.jobs.update(id, func(j *domain.ExportJob) {
+			finishedAt := time.Now()
+			j.FinishedAt = &finishedAt
+			j.Exported = exported
+			if err != nil {
+				j.Status = domain.JobFailed
+				j.Error = err.Error()
+				return
+			}
+			j.Status = domain.JobCompleted
+			j.FilePath = path
+		})
+	}()
+
+	return job, nil
+}
+
+func (uc *UserExportUsecase) GetExportJob(ctx context.Context, id string) (*domain.ExportJob, error) {
+	job, ok := uc.jobs.get(id)
+	if !ok || !startedBy(ctx, job.Tenant, job.CreatedBy) {
+		return nil, domain.ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+// prune forgets the jobs that finished longer than the retention ago and
+// removes files as old from the export directory, including those left by
+// earlier runs and by exports that failed midway
+func (uc *UserExportUsecase) prune(now time.Time) {
+	cutoff := now.Add(-uc.retention)
+	uc.jobs.prune(func(job *domain.ExportJob) bool {
+		return finishedBefore(job.FinishedAt, cutoff)
+	})
+
+	entries, err := os.ReadDir(uc.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		os.Remove(filepath.Join(uc.dir, entry.Name()))
+	}
+}
+
+// exportToFile writes the export to a temporary file and renames it into place
+// once complete, so a partially written export is never served
+func (uc *UserExportUsecase) exportToFile(ctx context.Context, id string, format domain.ExportFormat, filter domain.UserFilter, columns []string) (string, int, error) {
+	path := filepath.Join(uc.dir, id+"."+string(format))
+
+	file, err := os.CreateTemp(uc.dir, id+"-*.tmp")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	exported, err := uc.export(ctx, format, filter, columns, file, func(exported int) {
+		uc.jobs.update(id, func(j *domain.ExportJob) {
+			j.Exported = exported
+		})
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", exported, err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", exported, fmt.Errorf("failed to store export file: %w", err)
+	}
+	return path, exported, nil
+}
+
+// exportProgressInterval is how often, in rows, progress is reported for background exports
+const exportProgressInterval = 1000
+
+func (uc *UserExportUsecase) export(ctx context.Context, format domain.ExportFormat, filter domain.UserFilter, columns []string, w io.Writer, progress func(exported int)) (int, error) {
+	writer, err := newUserRowWriter(format, w, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	err = uc.repo.Iterate(ctx, filter, func(user *domain.User) error {
+		if err := writer.write(user); err != nil {
+			return err
+		}
+		exported++
+		if progress != nil && exported%exportProgressInterval == 0 {
+			progress(exported)
+		}
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	return exported, writer.close()
+}
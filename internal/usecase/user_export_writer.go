@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/pkg/xlsx"
+)
+
+// userRowWriter encodes exported users one at a time
+type userRowWriter interface {
+	write(user *domain.User) error
+	close() error
+}
+
+func newUserRowWriter(format domain.ExportFormat, w io.Writer, columns []string) (userRowWriter, error) {
+	switch format {
+	case domain.ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{writer: writer, columns: columns}, nil
+	case domain.ExportFormatNDJSON:
+		return &ndjsonRowWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	case domain.ExportFormatXLSX:
+		writer, err := xlsx.NewStreamWriter(w, "Users")
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.WriteRow(columns); err != nil {
+			return nil, err
+		}
+		return &xlsxRowWriter{writer: writer, columns: columns}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, format)
+	}
+}
+
+// exportValues returns the user's values for the selected columns
+func exportValues(user *domain.User, columns []string) []string {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "id":
+			values[i] = user.ID
+		case "name":
+			values[i] = user.Name
+		case "email":
+			values[i] = user.Email
+		case "version":
+			values[i] = strconv.FormatInt(user.Version, 10)
+		}
+	}
+	return values
+}
+
+// spreadsheetSafe neutralises values that spreadsheet applications would
+// evaluate as formulas, such as a name of =HYPERLINK(...), by prefixing them
+// with an apostrophe. CSV and XLSX exports are opened in spreadsheets and
+// carry user controlled text; NDJSON keeps the values as they are.
+func spreadsheetSafe(values []string) []string {
+	for i, value := range values {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			values[i] = "'" + value
+		}
+	}
+	return values
+}
+
+type csvRowWriter struct {
+	writer  *csv.Writer
+	columns []string
+}
+
+func (w *csvRowWriter) write(user *domain.User) error {
+	return w.writer.Write(spreadsheetSafe(exportValues(user, w.columns)))
+}
+
+func (w *csvRowWriter) close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (w *ndjsonRowWriter) write(user *domain.User) error {
+	values := exportValues(user, w.columns)
+	record := make(map[string]interface{}, len(w.columns))
+	for i, column := range w.columns {
+		if column == "version" {
+			record[column] = user.Version
+			continue
+		}
+		record[column] = values[i]
+	}
+	return w.encoder.Encode(record)
+}
+
+func (w *ndjsonRowWriter) close() error {
+	return nil
+}
+
+type xlsxRowWriter struct {
+	writer  *xlsx.StreamWriter
+	columns []string
+}
+
+func (w *xlsxRowWriter) write(user *domain.User) error {
+	return w.writer.WriteRow(spreadsheetSafe(exportValues(user, w.columns)))
+}
+
+func (w *xlsxRowWriter) close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"app-hexagonal/internal/domain"
+)
+
+// userFeedBuffer is the number of changes buffered per watcher before it is
+// considered too slow and closed
+const userFeedBuffer = 64
+
+// userFeed fans out user changes to every active watcher of their tenant
+type userFeed struct {
+	mu sync.Mutex
+	// watchers maps every watcher to the tenant it watches
+	watchers map[chan domain.UserChange]string
+}
+
+func newUserFeed() *userFeed {
+	return &userFeed{
+		watchers: make(map[chan domain.UserChange]string),
+	}
+}
+
+// subscribe registers a watcher of the changes made for the tenant of ctx that
+// is removed and closed once ctx is done, or earlier by publish when the
+// watcher falls behind
+func (f *userFeed) subscribe(ctx context.Context) <-chan domain.UserChange {
+	ch := make(chan domain.UserChange, userFeedBuffer)
+
+	f.mu.Lock()
+	f.watchers[ch] = domain.TenantFromContext(ctx)
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		f.remove(ch)
+		f.mu.Unlock()
+	}()
+
+	return ch
+}
+
+// publish delivers the change to the watchers of its tenant without blocking
+// on slow ones. A watcher whose buffer is full is closed rather than silently skipped, so
+// it can tell that it missed changes.
+func (f *userFeed) publish(change domain.UserChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch, tenant := range f.watchers {
+		if tenant != change.Tenant {
+			continue
+		}
+		select {
+		case ch <- change:
+		default:
+			f.remove(ch)
+		}
+	}
+}
+
+// remove closes a watcher unless it was already removed. f.mu must be held.
+func (f *userFeed) remove(ch chan domain.UserChange) {
+	if _, ok := f.watchers[ch]; ok {
+		delete(f.watchers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultImportBatchSize is used when no batch size is configured
+const defaultImportBatchSize = 500
+
+// UserImportUsecaseInterface defines the interface for bulk user import use cases
+type UserImportUsecaseInterface interface {
+	// Import reads all records from r and returns a per-row report
+	Import(ctx context.Context, format domain.ImportFormat, r io.Reader) (*domain.ImportReport, error)
+	// StartImportJob runs the import in the background and closes r when done.
+	// It imports for the tenant and principal of ctx but past its cancellation.
+	StartImportJob(ctx context.Context, format domain.ImportFormat, r io.ReadCloser) *domain.ImportJob
+	// GetImportJob returns a job started by the tenant and principal of ctx
+	GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error)
+}
+
+// UserImportUsecase validates and inserts users in batches
+type UserImportUsecase struct {
+	repo      domain.UserRepository
+	validate  *validator.Validate
+	batchSize int
+	jobs      *jobStore[domain.ImportJob]
+}
+
+// NewUserImportUsecase creates a new bulk user import usecase
+func NewUserImportUsecase(repo domain.UserRepository, validate *validator.Validate, batchSize int) *UserImportUsecase {
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	return &UserImportUsecase{
+		repo:      repo,
+		validate:  validate,
+		batchSize: batchSize,
+		jobs:      newJobStore[domain.ImportJob](),
+	}
+}
+
+// ParseImportFormat resolves a format name, file extension or content type to an import format
+func ParseImportFormat(value string) (domain.ImportFormat, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+		value = mediaType
+	}
+
+	switch strings.TrimPrefix(value, ".") {
+	case "csv", "text/csv", "application/csv":
+		return domain.ImportFormatCSV, nil
+	case "ndjson", "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return domain.ImportFormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, value)
+	}
+}
+
+func (uc *UserImportUsecase) Import(ctx context.Context, format domain.ImportFormat, r io.Reader) (*domain.ImportReport, error) {
+	return uc.importRecords(ctx, format, r, nil)
+}
+
+func (uc *UserImportUsecase) StartImportJob(ctx context.Context, format domain.ImportFormat, r io.ReadCloser) *domain.ImportJob {
+	cutoff := time.Now().Add(-defaultJobRetention)
+	uc.jobs.prune(func(job *domain.ImportJob) bool {
+		return finishedBefore(job.FinishedAt, cutoff)
+	})
+
+	id := uuid.New().String()
+	job := uc.jobs.add(id, &domain.ImportJob{
+		ID:        id,
+		Format:    format,
+		Status:    domain.JobPending,
+		StartedAt: time.Now(),
+		CreatedBy: domain.PrincipalFromContext(ctx),
+		Tenant:    domain.TenantFromContext(ctx),
+	})
+
+	// The job outlives the request but still writes to the request's tenant
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer r.Close()
+
+		uc.jobs.update(job.ID, func(j *domain.ImportJob) {
+			j.Status = domain.JobRunning
+		})
+
+		report, err := uc.importRecords(ctx, format, r, func(processed int) {
+			uc.jobs.update(job.ID, func(j *domain.ImportJob) {
+				j.Processed = processed
+			})
+		})
+
+		uc.jobs.update(job.ID, func(j *domain.ImportJob) {
+			finishedAt := time.Now()
+			j.FinishedAt = &finishedAt
+			j.Report = report
+			if err != nil {
+				j.Status = domain.JobFailed
+				j.Error = err.Error()
+				return
+			}
+			j.Status = domain.JobCompleted
+		})
+	}()
+
+	return job
+}
+
+func (uc *UserImportUsecase) GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error) {
+	job, ok := uc.jobs.get(id)
+	if !ok || !startedBy(ctx, job.Tenant, job.CreatedBy) {
+		return nil, domain.ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+// pendingImportRow is a validated row waiting to be inserted with its batch
+type pendingImportRow struct {
+	row    int
+	record domain.UserImportRecord
+}
+
+// importRecords streams records from r, validating each one and inserting valid
+// rows in batches. A partial report is returned alongside fatal errors.
+func (uc *UserImportUsecase) importRecords(ctx context.Context, format domain.ImportFormat, r io.Reader, progress func(processed int)) (*domain.ImportReport, error) {
+	reader, err := newUserRecordReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.ImportReport{Rows: []domain.ImportRowResult{}}
+	seen := make(map[string]bool)
+	batch := make([]pendingImportRow, 0, uc.batchSize)
+	processed := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := uc.storeBatch(ctx, batch, report); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		if progress != nil {
+			progress(processed)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		row, record, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		processed++
+
+		if err != nil {
+			if !errors.Is(err, errMalformedRow) {
+				return report, err
+			}
+			report.Add(domain.ImportRowResult{Row: row, Status: domain.ImportRowFailed, Error: err.Error()})
+			continue
+		}
+
+		record.Name = strings.TrimSpace(record.Name)
+		record.Email = strings.ToLower(strings.TrimSpace(record.Email))
+
+		if err := uc.validate.Struct(record); err != nil {
+			report.Add(domain.ImportRowResult{Row: row, Email: record.Email, Status: domain.ImportRowFailed, Error: err.Error()})
+			continue
+		}
+
+		if seen[record.Email] {
+			report.Add(domain.ImportRowResult{Row: row, Email: record.Email, Status: domain.ImportRowSkipped, Error: "duplicate email in file"})
+			continue
+		}
+		seen[record.Email] = true
+
+		batch = append(batch, pendingImportRow{row: row, record: record})
+		if len(batch) >= uc.batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// storeBatch skips rows whose email already exists and inserts the rest in one
+// transaction, falling back to one row at a time when an email is taken meanwhile
+func (uc *UserImportUsecase) storeBatch(ctx context.Context, batch []pendingImportRow, report *domain.ImportReport) error {
+	emails := make([]string, 0, len(batch))
+	for _, pending := range batch {
+		emails = append(emails, pending.record.Email)
+	}
+
+	existing, err := uc.repo.ExistingEmails(ctx, emails)
+	if err != nil {
+		return fmt.Errorf("failed to check existing emails: %w", err)
+	}
+
+	results := make([]domain.ImportRowResult, len(batch))
+	users := make([]*domain.User, 0, len(batch))
+	inserted := make([]int, 0, len(batch))
+
+	for i, pending := range batch {
+		results[i] = domain.ImportRowResult{Row: pending.row, Email: pending.record.Email}
+
+		if existing[pending.record.Email] {
+			results[i].Status = domain.ImportRowSkipped
+			results[i].Error = "email already exists"
+			continue
+		}
+
+		user := &domain.User{
+			ID:    uuid.New().String(),
+			Name:  pending.record.Name,
+			Email: pending.record.Email,
+		}
+		if pending.record.Password != "" {
+			hashed, err := bcrypt.GenerateFromPassword([]byte(pending.record.Password), bcrypt.DefaultCost)
+			if err != nil {
+				results[i].Status = domain.ImportRowFailed
+				results[i].Error = "failed to hash password"
+				continue
+			}
+			user.Password = string(hashed)
+		}
+
+		users = append(users, user)
+		inserted = append(inserted, i)
+	}
+
+	storeErrs := make([]error, len(users))
+	if err := uc.repo.StoreBatch(ctx, users); errors.Is(err, domain.ErrDuplicateEmail) {
+		// Another writer took one of the emails since ExistingEmails; insert
+		// the rows one at a time so only the conflicting ones are rejected
+		for j, user := range users {
+			storeErrs[j] = uc.repo.Store(ctx, user)
+		}
+	} else {
+		for j := range users {
+			storeErrs[j] = err
+		}
+	}
+
+	for j, i := range inserted {
+		switch err := storeErrs[j]; {
+		case errors.Is(err, domain.ErrDuplicateEmail):
+			results[i].Status = domain.ImportRowSkipped
+			results[i].Error = "email already exists"
+		case err != nil:
+			results[i].Status = domain.ImportRowFailed
+			results[i].Error = err.Error()
+		default:
+			results[i].Status = domain.ImportRowCreated
+		}
+	}
+
+	for _, result := range results {
+		report.Add(result)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"app-hexagonal/internal/domain"
+)
+
+// maxNDJSONLineSize bounds the size of a single NDJSON record
+const maxNDJSONLineSize = 1 << 20
+
+// errMalformedRow marks a row that could not be decoded; the import continues with the next row
+var errMalformedRow = errors.New("malformed row")
+
+// userRecordReader decodes import records one at a time
+type userRecordReader interface {
+	// next returns the 1-based row number and the decoded record, or io.EOF when done
+	next() (int, domain.UserImportRecord, error)
+}
+
+func newUserRecordReader(format domain.ImportFormat, r io.Reader) (userRecordReader, error) {
+	switch format {
+	case domain.ImportFormatCSV:
+		return newCSVRecordReader(r)
+	case domain.ImportFormatNDJSON:
+		return newNDJSONRecordReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, format)
+	}
+}
+
+// csvRecordReader reads records from CSV with a header row naming the columns.
+// Row numbers count data rows and exclude the header.
+type csvRecordReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header must contain a %q column", required)
+		}
+	}
+
+	return &csvRecordReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvRecordReader) next() (int, domain.UserImportRecord, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return 0, domain.UserImportRecord{}, io.EOF
+	}
+	r.row++
+
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return r.row, domain.UserImportRecord{}, fmt.Errorf("%w: %v", errMalformedRow, parseErr.Err)
+		}
+		return r.row, domain.UserImportRecord{}, err
+	}
+
+	return r.row, domain.UserImportRecord{
+		Name:     r.field(fields, "name"),
+		Email:    r.field(fields, "email"),
+		Password: r.field(fields, "password"),
+	}, nil
+}
+
+// field returns the named column of the row, or an empty string when absent
+func (r *csvRecordReader) field(fields []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+// ndjsonRecordReader reads one JSON object per line. Row numbers are line numbers
+// and blank lines are ignored.
+type ndjsonRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONRecordReader(r io.Reader) *ndjsonRecordReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &ndjsonRecordReader{scanner: scanner}
+}
+
+func (r *ndjsonRecordReader) next() (int, domain.UserImportRecord, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record domain.UserImportRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return r.line, domain.UserImportRecord{}, fmt.Errorf("%w: %v", errMalformedRow, err)
+		}
+		return r.line, record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return r.line + 1, domain.UserImportRecord{}, fmt.Errorf("failed to read ndjson: %w", err)
+	}
+	return 0, domain.UserImportRecord{}, io.EOF
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"app-hexagonal/internal/domain"
+)
+
+const (
+	defaultPreferencesMaxSize = 16 << 10
+
+	// preferencesUpdateAttempts bounds the retries of an unconditional update
+	// that keeps losing the race with other writers
+	preferencesUpdateAttempts = 3
+)
+
+// PreferencesOptions limits preferences documents; zero values fall back to defaults
+type PreferencesOptions struct {
+	// MaxSize is the largest accepted document in bytes once encoded
+	MaxSize int
+}
+
+// UserPreferencesUsecaseInterface defines the interface for user preferences use cases.
+// A version of 0 applies a change to whatever version is current; any other
+// version must match the stored one or ErrVersionConflict is returned.
+type UserPreferencesUsecaseInterface interface {
+	// GetPreferences returns the user, whose Preferences are never nil
+	GetPreferences(ctx context.Context, userID string) (*domain.User, error)
+	// ReplacePreferences replaces the whole preferences document
+	ReplacePreferences(ctx context.Context, userID string, preferences domain.UserPreferences, version int64) (*domain.User, error)
+	// PatchPreferences applies a JSON merge patch to the preferences document
+	PatchPreferences(ctx context.Context, userID string, patch map[string]interface{}, version int64) (*domain.User, error)
+}
+
+// UserPreferencesUsecase manages the preferences document stored with each user
+type UserPreferencesUsecase struct {
+	repo    domain.UserRepository
+	options PreferencesOptions
+}
+
+// NewUserPreferencesUsecase creates a new user preferences usecase
+func NewUserPreferencesUsecase(repo domain.UserRepository, options PreferencesOptions) *UserPreferencesUsecase {
+	if options.MaxSize <= 0 {
+		options.MaxSize = defaultPreferencesMaxSize
+	}
+
+	return &UserPreferencesUsecase{
+		repo:    repo,
+		options: options,
+	}
+}
+
+func (uc *UserPreferencesUsecase) GetPreferences(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := uc.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Preferences == nil {
+		user.Preferences = domain.UserPreferences{}
+	}
+	return user, nil
+}
+
+func (uc *UserPreferencesUsecase) ReplacePreferences(ctx context.Context, userID string, preferences domain.UserPreferences, version int64) (*domain.User, error) {
+	if preferences == nil {
+		preferences = domain.UserPreferences{}
+	}
+	return uc.update(ctx, userID, version, func(domain.UserPreferences) domain.UserPreferences {
+		return preferences
+	})
+}
+
+func (uc *UserPreferencesUsecase) PatchPreferences(ctx context.Context, userID string, patch map[string]interface{}, version int64) (*domain.User, error) {
+	return uc.update(ctx, userID, version, func(current domain.UserPreferences) domain.UserPreferences {
+		return current.Merge(patch)
+	})
+}
+
+// update applies change to the stored document with optimistic locking. Without
+// a version the change is reapplied to the latest document after a conflict.
+func (uc *UserPreferencesUsecase) update(ctx context.Context, userID string, version int64, change func(current domain.UserPreferences) domain.UserPreferences) (*domain.User, error) {
+	for attempt := 1; ; attempt++ {
+		user, err := uc.repo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && user.Version != version {
+			return nil, domain.ErrVersionConflict
+		}
+
+		preferences := change(user.Preferences)
+		if err := uc.checkSize(preferences); err != nil {
+			return nil, err
+		}
+		user.Preferences = preferences
+
+		err = uc.repo.Update(ctx, user)
+		if err == nil {
+			return user, nil
+		}
+		if version != 0 || !errors.Is(err, domain.ErrVersionConflict) || attempt == preferencesUpdateAttempts {
+			return nil, err
+		}
+	}
+}
+
+// checkSize rejects documents larger than MaxSize once encoded
+func (uc *UserPreferencesUsecase) checkSize(preferences domain.UserPreferences) error {
+	encoded, err := json.Marshal(preferences)
+	if err != nil {
+		return err
+	}
+	if len(encoded) > uc.options.MaxSize {
+		return domain.ErrPreferencesTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+// Package cache provides byte-oriented caches backed by Redis or process memory
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is not cached
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores opaque values with a time to live
+type Cache interface {
+	// Get returns the cached value or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value, replacing any existing one
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Add stores the value only if the key is not cached and reports whether it did
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete removes the keys; missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxPendingInvalidations bounds the keys remembered while the primary cache is down
+const maxPendingInvalidations = 10000
+
+// FallbackCache uses a primary cache, normally Redis, and switches to an
+// in-process LRU for a cooldown period whenever the primary fails.
+//
+// Writes that fail on the primary are remembered and replayed as deletes before
+// the primary is used again, so it never serves values that were invalidated
+// during an outage. The LRU is purged on recovery for the same reason.
+type FallbackCache struct {
+	primary   Cache
+	secondary *LRUCache
+	cooldown  time.Duration
+	onError   func(err error)
+
+	mu        sync.Mutex
+	downUntil time.Time
+	pending   map[string]struct{}
+}
+
+// NewFallbackCache creates a cache that falls back to secondary for cooldown after
+// a primary failure. onError, if not nil, is called with every primary error.
+func NewFallbackCache(primary Cache, secondary *LRUCache, cooldown time.Duration, onError func(err error)) *FallbackCache {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	return &FallbackCache{
+		primary:   primary,
+		secondary: secondary,
+		cooldown:  cooldown,
+		onError:   onError,
+		pending:   make(map[string]struct{}),
+	}
+}
+
+func (c *FallbackCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.primaryAvailable(ctx) {
+		value, err := c.primary.Get(ctx, key)
+		if err == nil || errors.Is(err, ErrMiss) {
+			return value, err
+		}
+		c.markDown(err)
+	}
+	return c.secondary.Get(ctx, key)
+}
+
+func (c *FallbackCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.primaryAvailable(ctx) {
+		err := c.primary.Set(ctx, key, value, ttl)
+		if err == nil {
+			return nil
+		}
+		c.markDown(err, key)
+	} else {
+		c.remember(key)
+	}
+	return c.secondary.Set(ctx, key, value, ttl)
+}
+
+func (c *FallbackCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if c.primaryAvailable(ctx) {
+		added, err := c.primary.Add(ctx, key, value, ttl)
+		if err == nil {
+			return added, nil
+		}
+		c.markDown(err, key)
+	} else {
+		c.remember(key)
+	}
+	return c.secondary.Add(ctx, key, value, ttl)
+}
+
+func (c *FallbackCache) Delete(ctx context.Context, keys ...string) error {
+	c.secondary.Delete(ctx, keys...)
+
+	if c.primaryAvailable(ctx) {
+		err := c.primary.Delete(ctx, keys...)
+		if err == nil {
+			return nil
+		}
+		c.markDown(err, keys...)
+	} else {
+		c.remember(keys...)
+	}
+	return nil
+}
+
+// primaryAvailable reports whether the primary may be used. After a cooldown the
+// pending invalidations are replayed first; if that fails the primary stays down.
+func (c *FallbackCache) primaryAvailable(ctx context.Context) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.downUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(c.downUntil) {
+		return false
+	}
+
+	keys := make([]string, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	if err := c.primary.Delete(ctx, keys...); err != nil {
+		c.downUntil = time.Now().Add(c.cooldown)
+		c.onError(err)
+		return false
+	}
+
+	c.pending = make(map[string]struct{})
+	c.downUntil = time.Time{}
+	c.secondary.Purge()
+	return true
+}
+
+// markDown switches to the secondary for the cooldown and remembers keys whose
+// write may not have reached the primary
+func (c *FallbackCache) markDown(err error, keys ...string) {
+	c.onError(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.downUntil = time.Now().Add(c.cooldown)
+	c.rememberLocked(keys...)
+}
+
+func (c *FallbackCache) remember(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rememberLocked(keys...)
+}
+
+// rememberLocked records keys to invalidate on recovery. Keys beyond the limit
+// are dropped and may stay stale on the primary until their TTL expires.
+func (c *FallbackCache) rememberLocked(keys ...string) {
+	for _, key := range keys {
+		if len(c.pending) >= maxPendingInvalidations {
+			return
+		}
+		c.pending[key] = struct{}{}
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisCache stores values in Redis under a common key prefix
+type RedisCache struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisCache creates a cache on the pool; prefix namespaces every key, e.g. "app:"
+func NewRedisCache(pool *redis.Pool, prefix string) *RedisCache {
+	return &RedisCache{
+		pool:   pool,
+		prefix: prefix,
+	}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	value, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", c.prefix+key))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "SET", c.prefix+key, value, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (c *RedisCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// SET NX replies nil when the key already exists
+	reply, err := redis.DoContext(conn, ctx, "SET", c.prefix+key, value, "PX", ttl.Milliseconds(), "NX")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = c.prefix + key
+	}
+	_, err = redis.DoContext(conn, ctx, "DEL", args...)
+	return err
+}
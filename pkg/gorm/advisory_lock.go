@@ -0,0 +1,73 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"gorm.io/gorm"
+)
+
+// WithAdvisoryLock runs fn while holding the database advisory lock called
+// name, waiting for other holders until ctx is done. It coordinates processes
+// sharing a database, such as instances migrating the schema on boot. MySQL
+// uses GET_LOCK and PostgreSQL pg_advisory_lock; SQLite databases belong to a
+// single process, so fn runs without a lock there.
+func WithAdvisoryLock(ctx context.Context, db *gorm.DB, name string, fn func() error) error {
+	dialect := db.Dialector.Name()
+	if dialect != "mysql" && dialect != "postgres" {
+		return fn()
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	// Advisory locks belong to a session, so both calls use the same connection
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	release, err := acquireAdvisoryLock(ctx, conn, dialect, name)
+	if err != nil {
+		return fmt.Errorf("failed to acquire advisory lock %q: %w", name, err)
+	}
+	defer release()
+
+	return fn()
+}
+
+// acquireAdvisoryLock takes the lock on conn and returns the function releasing it
+func acquireAdvisoryLock(ctx context.Context, conn *sql.Conn, dialect, name string) (func(), error) {
+	// Release even when ctx is done, or the lock lingers until the connection closes
+	releaseCtx := context.WithoutCancel(ctx)
+
+	if dialect == "postgres" {
+		key := advisoryLockKey(name)
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			return nil, err
+		}
+		return func() { conn.ExecContext(releaseCtx, "SELECT pg_advisory_unlock($1)", key) }, nil
+	}
+
+	// A negative timeout waits until the lock is free or the query is cancelled
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, -1)", name).Scan(&acquired); err != nil {
+		return nil, err
+	}
+	if acquired.Int64 != 1 {
+		return nil, errors.New("lock not granted")
+	}
+	return func() { conn.ExecContext(releaseCtx, "SELECT RELEASE_LOCK(?)", name) }, nil
+}
+
+// advisoryLockKey derives the 64-bit key PostgreSQL advisory locks take from a name
+func advisoryLockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,96 @@
+package gorm
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	createdByColumn = "created_by"
+	updatedByColumn = "updated_by"
+)
+
+// AuditColumns is a GORM plugin that fills the created_by and updated_by
+// columns of any model that has them with the principal making the change.
+// GORM itself already maintains created_at and updated_at.
+type AuditColumns struct {
+	principal func(ctx context.Context) string
+}
+
+// NewAuditColumns creates the plugin. principal returns the ID of whoever the
+// statement's context acts on behalf of, or an empty string for nobody.
+func NewAuditColumns(principal func(ctx context.Context) string) *AuditColumns {
+	return &AuditColumns{principal: principal}
+}
+
+// Name implements gorm.Plugin
+func (p *AuditColumns) Name() string {
+	return "audit_columns"
+}
+
+// Initialize implements gorm.Plugin by registering the create and update callbacks
+func (p *AuditColumns) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("audit_columns:create", p.beforeCreate); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("audit_columns:update", p.beforeUpdate)
+}
+
+// beforeCreate sets created_by and updated_by on every inserted row that does
+// not carry them already
+func (p *AuditColumns) beforeCreate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	principal := p.principal(db.Statement.Context)
+	if principal == "" {
+		return
+	}
+
+	for _, column := range []string{createdByColumn, updatedByColumn} {
+		field := db.Statement.Schema.LookUpField(column)
+		if field == nil {
+			continue
+		}
+
+		switch value := db.Statement.ReflectValue; value.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				setIfZero(db, field, reflect.Indirect(value.Index(i)), principal)
+			}
+		case reflect.Struct:
+			setIfZero(db, field, value, principal)
+		}
+	}
+}
+
+// beforeUpdate records the principal as the last one to change the row. It
+// also covers map updates, where the column is added to the assignments.
+// Changes made without a principal leave the previous value in place.
+func (p *AuditColumns) beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	if db.Statement.Schema.LookUpField(updatedByColumn) == nil {
+		return
+	}
+	principal := p.principal(db.Statement.Context)
+	if principal == "" {
+		return
+	}
+
+	db.Statement.SetColumn(updatedByColumn, principal, true)
+}
+
+// setIfZero sets the field on a single row unless it already holds a value
+func setIfZero(db *gorm.DB, field *schema.Field, row reflect.Value, value string) {
+	if _, zero := field.ValueOf(db.Statement.Context, row); !zero {
+		return
+	}
+	if err := field.Set(db.Statement.Context, row, value); err != nil {
+		db.AddError(err)
+	}
+}
@@ -0,0 +1,419 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultAuditLogTable receives the entries of an AuditLog without a table of its own
+	DefaultAuditLogTable = "audit_log"
+
+	// auditBeforeKey holds the rows an update or delete is about to change
+	auditBeforeKey = "audit_log:before"
+
+	// auditTag marks a model field as left out of the audit trail with "-", or
+	// recorded as changed without its values with "redact"
+	auditTag = "audit"
+)
+
+// Audited actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditChange is the change of one column in an audit entry. Values are absent
+// when the column was blank before or after, or when the column is redacted.
+type AuditChange struct {
+	Old      json.RawMessage `json:"old,omitempty"`
+	New      json.RawMessage `json:"new,omitempty"`
+	Redacted bool            `json:"redacted,omitempty"`
+}
+
+// AuditLogConfig configures an AuditLog
+type AuditLogConfig struct {
+	// Table receives the entries, DefaultAuditLogTable when empty
+	Table string
+	// Principal returns the ID of whoever the statement's context acts on
+	// behalf of, or an empty string for nobody
+	Principal func(ctx context.Context) string
+	// RequestID returns the ID of the request the statement runs for, or an
+	// empty string
+	RequestID func(ctx context.Context) string
+	// RedactColumns are recorded as changed without their values in every
+	// audited model, like fields tagged audit:"redact"
+	RedactColumns []string
+}
+
+// AuditLog is a GORM plugin that records every create, update and delete of
+// the registered models as an entry holding the changed columns with their
+// values before and after, who made the change and for which request.
+//
+// Entries are written by the same statement callbacks as the change, inside
+// the transaction GORM opens for it or the one it already runs in, so a change
+// is never stored without its entry. The rows an update or delete matches are
+// read before it runs, locked on MySQL and PostgreSQL, and read again after
+// an update, so expressions such as version + 1 are recorded with their
+// result. Deletes record no values. Raw SQL run with Exec is not audited.
+//
+// Model fields tagged audit:"-" are left out, and those tagged
+// audit:"redact" are recorded as changed without their values.
+type AuditLog struct {
+	config AuditLogConfig
+	models map[reflect.Type]bool
+	redact map[string]bool
+}
+
+// auditLogRow is an entry as written to the audit table
+type auditLogRow struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	Changes    string
+	ActorID    string
+	RequestID  string
+	CreatedAt  time.Time
+}
+
+// NewAuditLog creates the plugin. Register the audited models before passing
+// it to gorm.DB.Use.
+func NewAuditLog(config AuditLogConfig) *AuditLog {
+	if config.Table == "" {
+		config.Table = DefaultAuditLogTable
+	}
+	redact := make(map[string]bool, len(config.RedactColumns))
+	for _, column := range config.RedactColumns {
+		redact[column] = true
+	}
+	return &AuditLog{config: config, models: make(map[reflect.Type]bool), redact: redact}
+}
+
+// Register adds models, given as pointers to or values of their structs, to
+// the audited ones
+func (p *AuditLog) Register(models ...interface{}) *AuditLog {
+	for _, model := range models {
+		modelType := reflect.TypeOf(model)
+		for modelType.Kind() == reflect.Ptr {
+			modelType = modelType.Elem()
+		}
+		p.models[modelType] = true
+	}
+	return p
+}
+
+// Name implements gorm.Plugin
+func (p *AuditLog) Name() string {
+	return "audit_log"
+}
+
+// Initialize implements gorm.Plugin by registering the callbacks around the
+// statements, inside GORM's default transaction
+func (p *AuditLog) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, register := range []func() error{
+		func() error {
+			return callbacks.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+				Register("audit_log:create", p.afterCreate)
+		},
+		func() error {
+			return callbacks.Update().After("gorm:begin_transaction").Before("gorm:update").
+				Register("audit_log:before_update", p.beforeChange)
+		},
+		func() error {
+			return callbacks.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+				Register("audit_log:update", p.afterUpdate)
+		},
+		func() error {
+			return callbacks.Delete().After("gorm:begin_transaction").Before("gorm:delete").
+				Register("audit_log:before_delete", p.beforeChange)
+		},
+		func() error {
+			return callbacks.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").
+				Register("audit_log:delete", p.afterDelete)
+		},
+	} {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audited reports whether the statement changes rows of a registered model
+func (p *AuditLog) audited(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil && !db.DryRun && stmt.Schema != nil &&
+		stmt.Schema.PrioritizedPrimaryField != nil && p.models[stmt.Schema.ModelType]
+}
+
+// beforeChange reads the rows an update or delete is about to change
+func (p *AuditLog) beforeChange(db *gorm.DB) {
+	if !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+
+	var conds []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conds = append(conds, where.Exprs...)
+		}
+	}
+	// GORM narrows the statement to the primary keys of the model it is given
+	if ids := primaryKeys(stmt, stmt.ReflectValue); len(ids) > 0 {
+		conds = append(conds, clause.IN{Column: primaryColumn(stmt), Values: ids})
+	}
+	// GORM refuses statements without conditions unless told otherwise
+	if len(conds) == 0 && !db.AllowGlobalUpdate {
+		return
+	}
+
+	rows, err := p.load(db, true, false, conds...)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+// afterCreate records every inserted row with all its non-blank columns
+func (p *AuditLog) afterCreate(db *gorm.DB) {
+	if !p.audited(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	ids := primaryKeys(db.Statement, db.Statement.ReflectValue)
+	if len(ids) == 0 {
+		return
+	}
+	rows, err := p.load(db, false, true, clause.IN{Column: primaryColumn(db.Statement), Values: ids})
+	if err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+		return
+	}
+
+	blank := reflect.New(db.Statement.Schema.ModelType).Elem()
+	entries := make([]auditLogRow, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		changes, err := p.diff(db, blank, row)
+		if err != nil {
+			db.AddError(fmt.Errorf("audit log: %w", err))
+			return
+		}
+		entries = append(entries, p.entry(db, AuditCreate, row, changes))
+	}
+	p.write(db, entries)
+}
+
+// afterUpdate records the columns that changed on every updated row
+func (p *AuditLog) afterUpdate(db *gorm.DB) {
+	before, ok := p.before(db)
+	if !ok {
+		return
+	}
+
+	ids := primaryKeys(db.Statement, before)
+	if len(ids) == 0 {
+		return
+	}
+	rows, err := p.load(db, false, true, clause.IN{Column: primaryColumn(db.Statement), Values: ids})
+	if err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+		return
+	}
+	after := make(map[string]reflect.Value, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		after[p.entityID(db, rows.Index(i))] = rows.Index(i)
+	}
+
+	var entries []auditLogRow
+	for i := 0; i < before.Len(); i++ {
+		old := before.Index(i)
+		updated, ok := after[p.entityID(db, old)]
+		if !ok {
+			continue
+		}
+		changes, err := p.diff(db, old, updated)
+		if err != nil {
+			db.AddError(fmt.Errorf("audit log: %w", err))
+			return
+		}
+		if len(changes) > 0 {
+			entries = append(entries, p.entry(db, AuditUpdate, updated, changes))
+		}
+	}
+	p.write(db, entries)
+}
+
+// afterDelete records every deleted row, soft deleted or not, without values
+func (p *AuditLog) afterDelete(db *gorm.DB) {
+	before, ok := p.before(db)
+	if !ok {
+		return
+	}
+
+	entries := make([]auditLogRow, 0, before.Len())
+	for i := 0; i < before.Len(); i++ {
+		entries = append(entries, p.entry(db, AuditDelete, before.Index(i), map[string]AuditChange{}))
+	}
+	p.write(db, entries)
+}
+
+// before returns the rows read by beforeChange if the statement changed any
+func (p *AuditLog) before(db *gorm.DB) (reflect.Value, bool) {
+	if !p.audited(db) || db.RowsAffected == 0 {
+		return reflect.Value{}, false
+	}
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	rows, ok := value.(reflect.Value)
+	return rows, ok && rows.Len() > 0
+}
+
+// load reads the rows of the statement's model matching conds on the
+// statement's connection, which is its transaction. Soft deleted rows are
+// included when unscoped or when the statement itself is unscoped.
+func (p *AuditLog) load(db *gorm.DB, lock, unscoped bool, conds ...clause.Expression) (reflect.Value, error) {
+	stmt := db.Statement
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+
+	query := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	if unscoped || stmt.Unscoped {
+		query = query.Unscoped()
+	}
+	if len(conds) > 0 {
+		query = query.Clauses(clause.Where{Exprs: conds})
+	}
+	// SQLite locks the whole database for writing instead
+	if lock && db.Dialector.Name() != "sqlite" {
+		query = query.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate})
+	}
+
+	err := query.Find(rows.Interface()).Error
+	return rows.Elem(), err
+}
+
+// diff returns the audited columns whose values differ between two rows
+func (p *AuditLog) diff(db *gorm.DB, old, updated reflect.Value) (map[string]AuditChange, error) {
+	ctx := db.Statement.Context
+	changes := make(map[string]AuditChange)
+	for _, field := range db.Statement.Schema.Fields {
+		tag := field.Tag.Get(auditTag)
+		if field.DBName == "" || tag == "-" {
+			continue
+		}
+
+		oldValue, oldBlank := field.ValueOf(ctx, old)
+		newValue, newBlank := field.ValueOf(ctx, updated)
+		oldJSON, err := auditValue(oldValue, oldBlank)
+		if err != nil {
+			return nil, err
+		}
+		newJSON, err := auditValue(newValue, newBlank)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(oldJSON, newJSON) {
+			continue
+		}
+
+		if tag == "redact" || p.redact[field.DBName] {
+			changes[field.DBName] = AuditChange{Redacted: true}
+		} else {
+			changes[field.DBName] = AuditChange{Old: oldJSON, New: newJSON}
+		}
+	}
+	return changes, nil
+}
+
+// entry builds the entry for a change of row
+func (p *AuditLog) entry(db *gorm.DB, action string, row reflect.Value, changes map[string]AuditChange) auditLogRow {
+	ctx := db.Statement.Context
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+	}
+
+	entry := auditLogRow{
+		EntityType: db.Statement.Table,
+		EntityID:   p.entityID(db, row),
+		Action:     action,
+		Changes:    string(encoded),
+		CreatedAt:  db.NowFunc(),
+	}
+	if p.config.Principal != nil {
+		entry.ActorID = p.config.Principal(ctx)
+	}
+	if p.config.RequestID != nil {
+		entry.RequestID = p.config.RequestID(ctx)
+	}
+	return entry
+}
+
+// write stores the entries on the statement's connection, failing the
+// statement, and rolling its transaction back, if they cannot be stored
+func (p *AuditLog) write(db *gorm.DB, entries []auditLogRow) {
+	if len(entries) == 0 || db.Error != nil {
+		return
+	}
+	err := db.Session(&gorm.Session{NewDB: true}).Table(p.config.Table).Create(&entries).Error
+	if err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+	}
+}
+
+// entityID returns the primary key of row as a string
+func (p *AuditLog) entityID(db *gorm.DB, row reflect.Value) string {
+	value, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, row)
+	return fmt.Sprint(value)
+}
+
+// primaryKeys returns the non-blank primary keys of a model struct or slice
+func primaryKeys(stmt *gorm.Statement, value reflect.Value) []interface{} {
+	field := stmt.Schema.PrioritizedPrimaryField
+	var ids []interface{}
+	add := func(row reflect.Value) {
+		row = reflect.Indirect(row)
+		if row.Kind() != reflect.Struct {
+			return
+		}
+		if id, blank := field.ValueOf(stmt.Context, row); !blank {
+			ids = append(ids, id)
+		}
+	}
+
+	switch value = reflect.Indirect(value); value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			add(value.Index(i))
+		}
+	case reflect.Struct:
+		add(value)
+	}
+	return ids
+}
+
+// primaryColumn is the primary key column of the statement's model
+func primaryColumn(stmt *gorm.Statement) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: stmt.Schema.PrioritizedPrimaryField.DBName}
+}
+
+// auditValue encodes a column value, leaving blank values out
+func auditValue(value interface{}, blank bool) (json.RawMessage, error) {
+	if blank {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
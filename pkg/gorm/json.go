@@ -0,0 +1,84 @@
+package gorm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON stores a value of type T in a single column as a JSON document. The
+// column type follows the dialect: JSON on MySQL, JSONB on PostgreSQL and
+// TEXT elsewhere. In API payloads it is encoded as the bare document.
+type JSON[T any] struct {
+	Data T
+}
+
+// NewJSON wraps data for storage in a JSON column
+func NewJSON[T any](data T) JSON[T] {
+	return JSON[T]{Data: data}
+}
+
+// Value implements driver.Valuer by encoding the document. It is a string so
+// that TEXT columns hold readable JSON rather than a blob.
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner. A NULL column scans to the zero value of T.
+func (j *JSON[T]) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		var zero T
+		j.Data = zero
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON column", value)
+	}
+
+	var decoded T
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode JSON column: %w", err)
+	}
+	j.Data = decoded
+	return nil
+}
+
+// MarshalJSON encodes the wrapped document
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Data)
+}
+
+// UnmarshalJSON decodes the wrapped document
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.Data)
+}
+
+// GormDataType implements schema.GormDataTypeInterface
+func (JSON[T]) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType implements migrator.GormDataTypeInterface and picks the
+// column type for the connected dialect
+func (JSON[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "JSON"
+	case "postgres":
+		return "JSONB"
+	default:
+		return "TEXT"
+	}
+}
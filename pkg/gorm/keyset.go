@@ -0,0 +1,152 @@
+package gorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SortKey is one column of a keyset ordering
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Keyset selects a page by the sort key values of the row next to it rather
+// than by an offset, so pages stay stable while rows are inserted and deep pages
+// cost the same as the first. The last key must be unique, e.g. the primary
+// key, and key columns must not be NULL.
+type Keyset struct {
+	Keys []SortKey
+	// After holds the key values of the row the page follows and Before those
+	// of the row it precedes. Leaving both nil selects the first page.
+	After  []interface{}
+	Before []interface{}
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize
+	PageSize int
+}
+
+// KeysetResult reports whether pages exist on either side of the page
+type KeysetResult struct {
+	HasNext     bool
+	HasPrevious bool
+}
+
+// KeysetPagination loads the page selected by keyset into dest, a pointer to a
+// slice. One extra row is fetched to detect the following page; when the page
+// was reached from a cursor, an existence check on the other side replaces the
+// COUNT an offset page needs.
+func KeysetPagination(db *gorm.DB, keyset Keyset, dest interface{}) (*KeysetResult, error) {
+	if len(keyset.Keys) == 0 {
+		return nil, errors.New("keyset pagination needs at least one sort key")
+	}
+	if keyset.After != nil && keyset.Before != nil {
+		return nil, errors.New("keyset pagination takes either After or Before")
+	}
+	boundary, backward := keyset.After, false
+	if keyset.Before != nil {
+		boundary, backward = keyset.Before, true
+	}
+	if boundary != nil && len(boundary) != len(keyset.Keys) {
+		return nil, fmt.Errorf("keyset boundary has %d values for %d sort keys", len(boundary), len(keyset.Keys))
+	}
+
+	pageSize := ClampPageSize(keyset.PageSize)
+
+	// A new session lets the page and the existence check share the conditions
+	base := db.Session(&gorm.Session{})
+
+	query := base
+	if boundary != nil {
+		sql, vars := keysetCondition(base, keyset.Keys, boundary, backward, false)
+		query = query.Where(sql, vars...)
+	}
+	if err := query.Order(keysetOrder(keyset.Keys, backward)).Limit(pageSize + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	more := rows.Len() > pageSize
+	if more {
+		rows.Set(rows.Slice(0, pageSize))
+	}
+	if backward {
+		reverseRows(rows)
+	}
+
+	result := &KeysetResult{}
+	if backward {
+		result.HasPrevious = more
+	} else {
+		result.HasNext = more
+	}
+	if boundary != nil {
+		// The boundary row itself belongs to the page on the other side
+		sql, vars := keysetCondition(base, keyset.Keys, boundary, !backward, true)
+		var found []map[string]interface{}
+		if err := base.Where(sql, vars...).Select(keyset.Keys[0].Column).Limit(1).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		if backward {
+			result.HasNext = len(found) > 0
+		} else {
+			result.HasPrevious = len(found) > 0
+		}
+	}
+	return result, nil
+}
+
+// keysetCondition builds the condition selecting the rows that sort after the
+// boundary values, or before them when backward is set. It expands to
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) ... so every key keeps its own direction.
+// inclusive also selects the boundary row itself.
+func keysetCondition(db *gorm.DB, keys []SortKey, values []interface{}, backward, inclusive bool) (string, []interface{}) {
+	var (
+		alternatives []string
+		vars         []interface{}
+	)
+	for i, key := range keys {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, db.Statement.Quote(keys[j].Column)+" = ?")
+			vars = append(vars, values[j])
+		}
+
+		operator := ">"
+		if key.Desc != backward {
+			operator = "<"
+		}
+		if inclusive && i == len(keys)-1 {
+			operator += "="
+		}
+		terms = append(terms, db.Statement.Quote(key.Column)+" "+operator+" ?")
+		vars = append(vars, values[i])
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", vars
+}
+
+// keysetOrder orders by the keys, reversing every direction when backward
+func keysetOrder(keys []SortKey, backward bool) clause.OrderBy {
+	order := clause.OrderBy{}
+	for _, key := range keys {
+		order.Columns = append(order.Columns, clause.OrderByColumn{
+			Column: clause.Column{Name: key.Column},
+			Desc:   key.Desc != backward,
+		})
+	}
+	return order
+}
+
+// reverseRows reverses a slice in place
+func reverseRows(rows reflect.Value) {
+	swap := reflect.Swapper(rows.Interface())
+	for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,161 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// redactedValue replaces the bound values of sensitive columns in logged SQL
+const redactedValue = "[REDACTED]"
+
+// ZapLoggerConfig configures a ZapLogger
+type ZapLoggerConfig struct {
+	// LogLevel selects the statements logged, as in gorm/logger: Silent logs
+	// none, Error failed ones, Warn slow ones as well and Info every statement
+	LogLevel logger.LogLevel
+	// SlowThreshold is the duration above which a statement is logged as slow;
+	// zero never flags a statement
+	SlowThreshold time.Duration
+	// IgnoreRecordNotFoundError skips logging gorm.ErrRecordNotFound, which
+	// lookups of missing rows return as a matter of course
+	IgnoreRecordNotFoundError bool
+	// RedactColumns lists the columns, matched case-insensitively, whose bound
+	// values are replaced in the logged SQL
+	RedactColumns []string
+	// ContextFields returns the fields added to every entry logged for a
+	// statement run with ctx, such as the request and trace IDs
+	ContextFields func(ctx context.Context) []zap.Field
+	// Metrics, when set, records the duration of every statement, whether or
+	// not it is logged
+	Metrics *QueryHistogram
+}
+
+// ZapLogger is a gorm/logger.Interface that writes to a zap logger, so SQL
+// statements appear in the structured application logs
+type ZapLogger struct {
+	log    *zap.Logger
+	config ZapLoggerConfig
+	redact map[string]bool
+}
+
+// NewZapLogger creates a GORM logger writing to log
+func NewZapLogger(log *zap.Logger, config ZapLoggerConfig) *ZapLogger {
+	redact := make(map[string]bool, len(config.RedactColumns))
+	for _, column := range config.RedactColumns {
+		redact[strings.ToLower(column)] = true
+	}
+	return &ZapLogger{log: log, config: config, redact: redact}
+}
+
+// QueryMetrics returns the histogram the ZapLogger of db records into, or nil
+// when db does not log through a ZapLogger with metrics
+func QueryMetrics(db *gorm.DB) *QueryHistogram {
+	if l, ok := db.Logger.(*ZapLogger); ok {
+		return l.config.Metrics
+	}
+	return nil
+}
+
+// LogMode implements logger.Interface
+func (l *ZapLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.config.LogLevel = level
+	return &copied
+}
+
+// Info implements logger.Interface
+func (l *ZapLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Info {
+		l.log.Info(fmt.Sprintf(msg, data...), l.fields(ctx)...)
+	}
+}
+
+// Warn implements logger.Interface
+func (l *ZapLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Warn {
+		l.log.Warn(fmt.Sprintf(msg, data...), l.fields(ctx)...)
+	}
+}
+
+// Error implements logger.Interface
+func (l *ZapLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Error {
+		l.log.Error(fmt.Sprintf(msg, data...), l.fields(ctx)...)
+	}
+}
+
+// Trace implements logger.Interface. GORM calls it after every statement.
+func (l *ZapLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	failed := err != nil && l.config.LogLevel >= logger.Error &&
+		!(l.config.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound))
+	slow := l.config.SlowThreshold > 0 && elapsed > l.config.SlowThreshold && l.config.LogLevel >= logger.Warn
+	logged := failed || slow || l.config.LogLevel >= logger.Info
+	if !logged && l.config.Metrics == nil {
+		return
+	}
+
+	sql, rows := fc()
+	if l.config.Metrics != nil {
+		l.config.Metrics.Observe(queryOperation(sql), elapsed)
+	}
+
+	if !logged {
+		return
+	}
+
+	// FileWithLineNum skips the frames up to its caller's caller, so it has to
+	// be called from Trace for the source to be the code that ran the statement
+	fields := append(l.fields(ctx),
+		zap.String("sql", sql),
+		zap.Duration("duration", elapsed),
+		zap.String("source", utils.FileWithLineNum()),
+	)
+	if rows >= 0 {
+		fields = append(fields, zap.Int64("rows", rows))
+	}
+
+	switch {
+	case failed:
+		l.log.Error("Database query failed", append(fields, zap.Error(err))...)
+	case slow:
+		l.log.Warn("Slow database query", append(fields, zap.Duration("slow_threshold", l.config.SlowThreshold))...)
+	default:
+		l.log.Info("Database query", fields...)
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter by replacing the values bound to
+// the sensitive columns before GORM writes them into the logged SQL
+func (l *ZapLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if len(l.redact) == 0 {
+		return sql, params
+	}
+	return sql, redactParams(sql, params, l.redact)
+}
+
+// fields returns the fields the context contributes to an entry
+func (l *ZapLogger) fields(ctx context.Context) []zap.Field {
+	if l.config.ContextFields == nil || ctx == nil {
+		return nil
+	}
+	return l.config.ContextFields(ctx)
+}
+
+// queryOperation returns the kind of statement, from its first keyword
+func queryOperation(sql string) string {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	switch keyword = strings.ToLower(keyword); keyword {
+	case "select", "insert", "update", "delete":
+		return keyword
+	}
+	return "other"
+}
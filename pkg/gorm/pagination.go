@@ -1,14 +1,34 @@
 package gorm
 
 import (
+	"errors"
+	"regexp"
+	"strings"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidOrder is returned when OrderBy is not a plain column name or Sort
+// is neither asc nor desc. Both end up in the ORDER BY clause, so anything
+// else is refused rather than risk SQL injection.
+var ErrInvalidOrder = errors.New("invalid pagination order")
+
+const (
+	// DefaultPageSize is the page size used when none is requested
+	DefaultPageSize = 10
+	// MaxPageSize caps the page size so that one request cannot load a whole table
+	MaxPageSize = 100
 )
 
+// orderColumn matches a column name, optionally qualified by its table
+var orderColumn = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
 // Pagination represents pagination parameters
 type Pagination struct {
 	Page     int    `json:"page"`
 	PageSize int    `json:"page_size"`
-	Cursor   string `json:"cursor"` // For cursor-based pagination
+	Cursor   string `json:"cursor"` // For cursor-based pagination, empty for the first page
 	OrderBy  string `json:"order_by"`
 	Sort     string `json:"sort"` // asc or desc
 }
@@ -26,8 +46,14 @@ type PaginationResult struct {
 	Data           interface{} `json:"data"`
 }
 
-// OffsetPagination performs offset-based pagination
+// OffsetPagination performs offset-based pagination. The order is appended to
+// any order already on db, so it acts as a tie-breaker for a caller's sort.
 func OffsetPagination(db *gorm.DB, pagination *Pagination, dest interface{}) (*PaginationResult, error) {
+	order, err := paginationOrder(pagination)
+	if err != nil {
+		return nil, err
+	}
+
 	var totalRecords int64
 	result := &PaginationResult{}
 
@@ -43,25 +69,11 @@ func OffsetPagination(db *gorm.DB, pagination *Pagination, dest interface{}) (*P
 		page = 1
 	}
 
-	pageSize := pagination.PageSize
-	if pageSize <= 0 {
-		pageSize = 10
-	}
+	pageSize := ClampPageSize(pagination.PageSize)
 
 	offset := (page - 1) * pageSize
 
-	// Apply ordering
-	orderQuery := pagination.OrderBy
-	if orderQuery == "" {
-		orderQuery = "id"
-	}
-
-	sortOrder := pagination.Sort
-	if sortOrder == "" {
-		sortOrder = "asc"
-	}
-
-	query := db.Offset(offset).Limit(pageSize).Order(orderQuery + " " + sortOrder)
+	query := db.Offset(offset).Limit(pageSize).Order(order)
 
 	if err := query.Find(dest).Error; err != nil {
 		return nil, err
@@ -80,56 +92,50 @@ func OffsetPagination(db *gorm.DB, pagination *Pagination, dest interface{}) (*P
 	return result, nil
 }
 
-// CursorPagination performs cursor-based pagination
-func CursorPagination(db *gorm.DB, pagination *Pagination, cursorField string, dest interface{}) (*PaginationResult, error) {
-	result := &PaginationResult{}
-
-	pageSize := pagination.PageSize
-	if pageSize <= 0 {
-		pageSize = 10
+// ClampPageSize returns the page size to use for a requested one: DefaultPageSize
+// when it is not positive and at most MaxPageSize
+func ClampPageSize(pageSize int) int {
+	switch {
+	case pageSize <= 0:
+		return DefaultPageSize
+	case pageSize > MaxPageSize:
+		return MaxPageSize
+	default:
+		return pageSize
 	}
+}
 
-	// Apply cursor condition if cursor is provided
-	if pagination.Cursor != "" {
-		// Note: In a real implementation, you would decode the cursor
-		// and apply the appropriate WHERE condition
-		// This is a simplified example
-		db = db.Where(cursorField+" > ?", pagination.Cursor)
+// paginationOrder validates OrderBy and Sort and builds a quoted ORDER BY column
+func paginationOrder(pagination *Pagination) (clause.OrderByColumn, error) {
+	column := pagination.OrderBy
+	if column == "" {
+		column = "id"
 	}
-
-	// Apply ordering
-	orderQuery := pagination.OrderBy
-	if orderQuery == "" {
-		orderQuery = cursorField
+	if !orderColumn.MatchString(column) {
+		return clause.OrderByColumn{}, ErrInvalidOrder
 	}
 
-	sortOrder := pagination.Sort
-	if sortOrder == "" {
-		sortOrder = "asc"
+	var desc bool
+	switch strings.ToLower(pagination.Sort) {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return clause.OrderByColumn{}, ErrInvalidOrder
 	}
 
-	query := db.Limit(pageSize).Order(orderQuery + " " + sortOrder)
-
-	if err := query.Find(dest).Error; err != nil {
-		return nil, err
+	table, name, qualified := strings.Cut(column, ".")
+	if !qualified {
+		table, name = "", column
 	}
-
-	// Calculate next cursor (simplified)
-	// In a real implementation, you would calculate based on the last record
-	result.NextCursor = ""
-	result.PreviousCursor = pagination.Cursor
-
-	result.PageSize = pageSize
-	result.Data = dest
-
-	return result, nil
+	return clause.OrderByColumn{Column: clause.Column{Table: table, Name: name}, Desc: desc}, nil
 }
 
 // NewPagination creates a new pagination instance with default values
 func NewPagination() *Pagination {
 	return &Pagination{
 		Page:     1,
-		PageSize: 10,
+		PageSize: DefaultPageSize,
 		Sort:     "asc",
 	}
 }
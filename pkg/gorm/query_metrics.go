@@ -0,0 +1,104 @@
+package gorm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultQueryBuckets are the upper bounds, in seconds, of the buckets of a
+// QueryHistogram created without its own
+var DefaultQueryBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// QueryHistogram records how long SQL statements take, by kind of statement.
+// It is safe for concurrent use.
+type QueryHistogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*querySeries
+}
+
+// querySeries holds the observations of one kind of statement
+type querySeries struct {
+	counts []uint64 // per bucket, with the last one above every bound
+	count  uint64
+	sum    float64
+}
+
+// NewQueryHistogram creates a histogram with the given bucket bounds in
+// seconds, or DefaultQueryBuckets when there are none
+func NewQueryHistogram(buckets ...float64) *QueryHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultQueryBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &QueryHistogram{buckets: buckets, series: make(map[string]*querySeries)}
+}
+
+// Observe records a statement of the given operation, such as select, that
+// took duration
+func (h *QueryHistogram) Observe(operation string, duration time.Duration) {
+	seconds := duration.Seconds()
+	bucket := sort.SearchFloat64s(h.buckets, seconds)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.series[operation]
+	if !ok {
+		series = &querySeries{counts: make([]uint64, len(h.buckets)+1)}
+		h.series[operation] = series
+	}
+	series.counts[bucket]++
+	series.count++
+	series.sum += seconds
+}
+
+// Count returns the number of statements of the operation observed so far
+func (h *QueryHistogram) Count(operation string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if series, ok := h.series[operation]; ok {
+		return series.count
+	}
+	return 0
+}
+
+// WritePrometheus writes the histogram as the metric name in the Prometheus
+// text exposition format, labelled by operation
+func (h *QueryHistogram) WritePrometheus(w io.Writer, name string) error {
+	h.mu.Lock()
+	operations := make([]string, 0, len(h.series))
+	snapshot := make(map[string]querySeries, len(h.series))
+	for operation, series := range h.series {
+		operations = append(operations, operation)
+		snapshot[operation] = querySeries{
+			counts: append([]uint64(nil), series.counts...),
+			count:  series.count,
+			sum:    series.sum,
+		}
+	}
+	h.mu.Unlock()
+	sort.Strings(operations)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# HELP %s Duration of SQL statements in seconds.\n", name)
+	fmt.Fprintf(out, "# TYPE %s histogram\n", name)
+	for _, operation := range operations {
+		series := snapshot[operation]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(out, "%s_bucket{operation=%q,le=%q} %d\n", name, operation, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(out, "%s_bucket{operation=%q,le=\"+Inf\"} %d\n", name, operation, series.count)
+		fmt.Fprintf(out, "%s_sum{operation=%q} %s\n", name, operation, strconv.FormatFloat(series.sum, 'g', -1, 64))
+		fmt.Fprintf(out, "%s_count{operation=%q} %d\n", name, operation, series.count)
+	}
+	return out.Flush()
+}
@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"strings"
+
+	"app-hexagonal/pkg/queryspec"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ApplySpec adds the filters and sort of a validated spec to the query.
+// Columns come from the resource whitelist and are quoted, and values are
+// bound as parameters, so nothing from the client is spliced into the SQL.
+func ApplySpec(db *gorm.DB, spec queryspec.Spec) *gorm.DB {
+	return ApplySpecSort(ApplySpecFilters(db, spec), spec)
+}
+
+// ApplySpecFilters adds the filters of a spec to the query
+func ApplySpecFilters(db *gorm.DB, spec queryspec.Spec) *gorm.DB {
+	for _, filter := range spec.Filters {
+		db = db.Where(specCondition(filter))
+	}
+	return db
+}
+
+// ApplySpecSort adds the sort of a spec to the query
+func ApplySpecSort(db *gorm.DB, spec queryspec.Spec) *gorm.DB {
+	for _, sort := range spec.Sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+	}
+	return db
+}
+
+// specCondition translates a filter into a clause expression
+func specCondition(filter queryspec.Filter) clause.Expression {
+	column := clause.Column{Name: filter.Column}
+	switch filter.Operator {
+	case queryspec.Ne:
+		return clause.Neq{Column: column, Value: filter.Value}
+	case queryspec.Gt:
+		return clause.Gt{Column: column, Value: filter.Value}
+	case queryspec.Gte:
+		return clause.Gte{Column: column, Value: filter.Value}
+	case queryspec.Lt:
+		return clause.Lt{Column: column, Value: filter.Value}
+	case queryspec.Lte:
+		return clause.Lte{Column: column, Value: filter.Value}
+	case queryspec.Contains:
+		value, _ := filter.Value.(string)
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, "%" + escapeLike(value) + "%"}}
+	case queryspec.StartsWith:
+		value, _ := filter.Value.(string)
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, escapeLike(value) + "%"}}
+	case queryspec.In:
+		values, _ := filter.Value.([]interface{})
+		return clause.IN{Column: column, Values: values}
+	default:
+		return clause.Eq{Column: column, Value: filter.Value}
+	}
+}
+
+// escapeLike escapes LIKE wildcards so the value is matched literally. The escape
+// character is "!" because backslash handling differs between dialects.
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
@@ -0,0 +1,220 @@
+package gorm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// insertState tracks where a scan is in an INSERT statement, whose values are
+// bound to the columns by position rather than next to them
+type insertState int
+
+const (
+	insertNone insertState = iota
+	insertTable
+	insertColumns
+	insertAfterColumns
+	insertValues
+)
+
+// keepColumnKeywords sit between a column and the value it is compared with
+var keepColumnKeywords = map[string]bool{
+	"LIKE": true, "ILIKE": true, "IN": true, "NOT": true, "IS": true,
+	"BETWEEN": true, "ESCAPE": true, "COLLATE": true, "BINARY": true,
+}
+
+// resetColumnKeywords start a clause whose values are not bound to the last column
+var resetColumnKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true,
+	"SET": true, "ON": true, "JOIN": true, "HAVING": true, "BY": true,
+	"ORDER": true, "GROUP": true, "RETURNING": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"UPDATE": true, "DELETE": true, "INTO": true, "DUPLICATE": true, "KEY": true,
+}
+
+// rowCount is the column of the values of LIMIT and OFFSET, which never hold
+// column data
+const rowCount = "-"
+
+// redactParams returns params with the values bound to the given columns
+// replaced by redactedValue. The SQL is scanned for the column each placeholder
+// is compared with, assigned to or inserted into, with ? placeholders bound in
+// order and $n placeholders by number. It fails closed: a value whose column
+// cannot be told apart, such as in ? = email, is redacted whenever the
+// statement names one of the columns, and a statement that cannot be scanned
+// has all of its values redacted.
+func redactParams(sql string, params []interface{}, columns map[string]bool) []interface{} {
+	var redacted []interface{}
+	mask := func(index int) {
+		if index < 0 || index >= len(params) {
+			return
+		}
+		if redacted == nil {
+			redacted = append([]interface{}(nil), params...)
+		}
+		redacted[index] = redactedValue
+	}
+	redact := func(index int, column string) {
+		if columns[column] {
+			mask(index)
+		}
+	}
+
+	var (
+		column   string // the column the next placeholder is bound to
+		between  bool   // the AND that follows BETWEEN keeps the column
+		state    insertState
+		inserted []string // the columns of an INSERT, in the order of its values
+		position int      // the index of the next value in the current VALUES row
+		depth    int      // parentheses
+		next     int      // the index of the next ? placeholder
+		unknown  []int    // the placeholders whose column is not known
+		named    bool     // whether the statement names one of the columns
+	)
+	bind := func(index int) {
+		switch {
+		case state == insertValues && depth >= 1:
+			if position < len(inserted) {
+				redact(index, inserted[position])
+			} else {
+				unknown = append(unknown, index)
+			}
+		case state == insertColumns:
+			// VALUES without a column list, where any value may be sensitive
+			mask(index)
+		case column == "":
+			unknown = append(unknown, index)
+		default:
+			redact(index, column)
+		}
+	}
+	identifier := func(name string) {
+		name = strings.ToLower(name)
+		if columns[name] {
+			named = true
+		}
+		switch state {
+		case insertTable, insertValues:
+		case insertColumns:
+			inserted = append(inserted, name)
+		default:
+			column = name
+		}
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			// String literal, where quotes are escaped by doubling them
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+		case c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				// The rest cannot be scanned, so no value is known to be safe
+				for index := range params {
+					unknown = append(unknown, index)
+				}
+				named, i = true, len(sql)
+				continue
+			}
+			identifier(sql[i+1 : i+1+end])
+			i += end + 2
+		case c == '?':
+			bind(next)
+			next++
+			i++
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			bind(n - 1)
+			i = j
+		case isDigit(c):
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+				i++
+			}
+		case isWordByte(c):
+			j := i
+			for j < len(sql) && (isWordByte(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+			word := strings.ToUpper(sql[i:j])
+			i = j
+
+			switch {
+			case word == "INSERT":
+				state, inserted = insertTable, nil
+			case word == "VALUES" && state == insertAfterColumns:
+				state = insertValues
+			case word == "BETWEEN":
+				between = true
+			case word == "AND" && between:
+				between = false
+			case keepColumnKeywords[word]:
+			case word == "LIMIT" || word == "OFFSET":
+				column = rowCount
+			case resetColumnKeywords[word]:
+				column = ""
+				if state == insertValues && depth == 0 {
+					state = insertNone
+				}
+			case i < len(sql) && sql[i] == '(' && state != insertTable:
+				// A function call such as LOWER(email)
+			default:
+				identifier(sql[i-len(word) : i])
+			}
+		case c == '(':
+			depth++
+			switch {
+			case state == insertTable && depth == 1:
+				state = insertColumns
+			case state == insertValues && depth == 1:
+				position = 0
+			}
+			i++
+		case c == ')':
+			depth--
+			if state == insertColumns && depth == 0 {
+				state = insertAfterColumns
+			}
+			i++
+		case c == ',':
+			if state == insertValues && depth == 1 {
+				position++
+			}
+			i++
+		default:
+			i++
+		}
+	}
+
+	if named {
+		for _, index := range unknown {
+			mask(index)
+		}
+	}
+	if redacted == nil {
+		return params
+	}
+	return redacted
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
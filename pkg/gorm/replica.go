@@ -0,0 +1,317 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaResolverName is the name the replica resolver is registered under
+const ReplicaResolverName = "app:replica_resolver"
+
+// ReplicaConfig configures read replica routing
+type ReplicaConfig struct {
+	// HealthCheckInterval is how often replicas are pinged. A replica that fails
+	// a ping is skipped until a later ping succeeds.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds a single ping
+	HealthCheckTimeout time.Duration
+	// StickyWindow is how long reads stay on the primary after a write made
+	// with a context prepared by WithReadYourWrites
+	StickyWindow time.Duration
+}
+
+// DefaultReplicaConfig returns the replica settings used when none are given
+func DefaultReplicaConfig() ReplicaConfig {
+	return ReplicaConfig{
+		HealthCheckInterval: 10 * time.Second,
+		HealthCheckTimeout:  2 * time.Second,
+		StickyWindow:        5 * time.Second,
+	}
+}
+
+type replica struct {
+	pool    *sql.DB
+	healthy atomic.Bool
+}
+
+// ReplicaResolver is a GORM plugin that sends reads to healthy replicas and
+// everything else to the primary. Writes, raw statements and all statements
+// inside a transaction use the primary; when no replica is healthy reads fall
+// back to the primary as well.
+type ReplicaResolver struct {
+	config   ReplicaConfig
+	replicas []*replica
+	next     atomic.Uint64
+	primary  gorm.ConnPool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     sync.WaitGroup
+}
+
+// NewReplicaResolver creates a resolver for the given replica pools. Register
+// it with db.Use; it takes ownership of the pools and closes them on Close.
+func NewReplicaResolver(config ReplicaConfig, replicas ...*sql.DB) *ReplicaResolver {
+	defaults := DefaultReplicaConfig()
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaults.HealthCheckInterval
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = defaults.HealthCheckTimeout
+	}
+	if config.StickyWindow < 0 {
+		config.StickyWindow = 0
+	}
+
+	resolver := &ReplicaResolver{
+		config: config,
+		stop:   make(chan struct{}),
+	}
+	for _, pool := range replicas {
+		resolver.replicas = append(resolver.replicas, &replica{pool: pool})
+	}
+	return resolver
+}
+
+// Name implements gorm.Plugin
+func (r *ReplicaResolver) Name() string {
+	return ReplicaResolverName
+}
+
+// Initialize implements gorm.Plugin. It checks every replica once before
+// routing any read to it, then keeps checking them in the background.
+func (r *ReplicaResolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Query().Before("gorm:query").Register("replica:route_query", r.routeRead),
+		callbacks.Query().After("gorm:query").Register("replica:check_query", r.checkRead),
+		callbacks.Row().Before("gorm:row").Register("replica:route_row", r.routeRead),
+		callbacks.Row().After("gorm:row").Register("replica:check_row", r.checkRead),
+		callbacks.Create().Before("*").Register("replica:route_create", r.routeWrite),
+		callbacks.Create().After("*").Register("replica:mark_create", r.markWrite),
+		callbacks.Update().Before("*").Register("replica:route_update", r.routeWrite),
+		callbacks.Update().After("*").Register("replica:mark_update", r.markWrite),
+		callbacks.Delete().Before("*").Register("replica:route_delete", r.routeWrite),
+		callbacks.Delete().After("*").Register("replica:mark_delete", r.markWrite),
+		callbacks.Raw().Before("gorm:raw").Register("replica:route_raw", r.routeWrite),
+		callbacks.Raw().After("gorm:raw").Register("replica:mark_raw", r.markWrite),
+	}
+	if err := errors.Join(registrations...); err != nil {
+		return err
+	}
+
+	r.checkReplicas()
+	if len(r.replicas) > 0 {
+		r.done.Add(1)
+		go r.healthCheckLoop()
+	}
+	return nil
+}
+
+// Close stops the health checks and closes the replica pools
+func (r *ReplicaResolver) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.done.Wait()
+
+	var errs []error
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.pool.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// HealthyReplicas returns how many replicas are currently in rotation
+func (r *ReplicaResolver) HealthyReplicas() int {
+	healthy := 0
+	for _, replica := range r.replicas {
+		if replica.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// CloseReplicas closes the replica resolver registered on db, if any
+func CloseReplicas(db *gorm.DB) error {
+	if plugin, ok := db.Config.Plugins[ReplicaResolverName]; ok {
+		return plugin.(*ReplicaResolver).Close()
+	}
+	return nil
+}
+
+// routeRead moves a read from the primary to a healthy replica unless the
+// statement must see the primary
+func (r *ReplicaResolver) routeRead(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || !r.owns(stmt.ConnPool) {
+		return
+	}
+	// Locking reads such as SELECT ... FOR UPDATE must run on the primary
+	if _, locking := stmt.Clauses["FOR"]; locking || !isReadOnlySQL(stmt.SQL.String()) || readsFromPrimary(stmt.Context, r.config.StickyWindow) {
+		stmt.ConnPool = r.primary
+		return
+	}
+
+	if replica := r.pick(); replica != nil {
+		stmt.ConnPool = replica.pool
+	} else {
+		stmt.ConnPool = r.primary
+	}
+}
+
+// checkRead takes a replica out of rotation when a read failed because the
+// connection to it was lost
+func (r *ReplicaResolver) checkRead(db *gorm.DB) {
+	if db.Error == nil || !isConnectionError(db.Error) {
+		return
+	}
+	for _, replica := range r.replicas {
+		if db.Statement.ConnPool == gorm.ConnPool(replica.pool) {
+			replica.healthy.Store(false)
+			return
+		}
+	}
+}
+
+// routeWrite makes sure a statement that may write uses the primary, even when
+// it reuses a statement that was routed to a replica before
+func (r *ReplicaResolver) routeWrite(db *gorm.DB) {
+	if r.owns(db.Statement.ConnPool) {
+		db.Statement.ConnPool = r.primary
+	}
+}
+
+// markWrite starts the read-your-writes window of the statement's session
+func (r *ReplicaResolver) markWrite(db *gorm.DB) {
+	if session, ok := db.Statement.Context.Value(readYourWritesKey{}).(*readYourWritesSession); ok {
+		session.lastWrite.Store(time.Now().UnixNano())
+	}
+}
+
+// owns reports whether the pool is the primary or one of the replicas; any
+// other pool is a transaction or an explicit connection and is left alone
+func (r *ReplicaResolver) owns(pool gorm.ConnPool) bool {
+	if pool == r.primary {
+		return true
+	}
+	for _, replica := range r.replicas {
+		if pool == gorm.ConnPool(replica.pool) {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the next healthy replica in round robin order
+func (r *ReplicaResolver) pick() *replica {
+	count := uint64(len(r.replicas))
+	if count == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < count; i++ {
+		replica := r.replicas[(start+i)%count]
+		if replica.healthy.Load() {
+			return replica
+		}
+	}
+	return nil
+}
+
+func (r *ReplicaResolver) healthCheckLoop() {
+	defer r.done.Done()
+
+	ticker := time.NewTicker(r.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkReplicas()
+		}
+	}
+}
+
+// checkReplicas pings all replicas concurrently and updates their health
+func (r *ReplicaResolver) checkReplicas() {
+	var wg sync.WaitGroup
+	for _, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), r.config.HealthCheckTimeout)
+			defer cancel()
+			replica.healthy.Store(replica.pool.PingContext(ctx) == nil)
+		}()
+	}
+	wg.Wait()
+}
+
+type readYourWritesKey struct{}
+
+type primaryKey struct{}
+
+type readYourWritesSession struct {
+	lastWrite atomic.Int64
+}
+
+// WithReadYourWrites starts a session, usually one per request. Reads made with
+// the returned context go to the primary for the resolver's sticky window after
+// any write made with it, so a caller always sees its own changes.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, &readYourWritesSession{})
+}
+
+// WithPrimary sends every read made with the returned context to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// readsFromPrimary reports whether the context pins reads to the primary
+func readsFromPrimary(ctx context.Context, window time.Duration) bool {
+	if ctx == nil {
+		return false
+	}
+	if pinned, _ := ctx.Value(primaryKey{}).(bool); pinned {
+		return true
+	}
+	session, ok := ctx.Value(readYourWritesKey{}).(*readYourWritesSession)
+	if !ok {
+		return false
+	}
+	lastWrite := session.lastWrite.Load()
+	return lastWrite != 0 && time.Since(time.Unix(0, lastWrite)) < window
+}
+
+// isReadOnlySQL reports whether a statement can be served by a replica. Empty
+// SQL is built later by GORM from query clauses, which always select.
+func isReadOnlySQL(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	if sql == "" {
+		return true
+	}
+	keyword, _, _ := strings.Cut(sql, " ")
+	keyword = strings.ToUpper(keyword)
+	// WITH is excluded because a common table expression may modify data
+	return keyword == "SELECT" && !strings.Contains(strings.ToUpper(sql), " FOR UPDATE")
+}
+
+// isConnectionError reports whether err means the connection is unusable
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr)
+}
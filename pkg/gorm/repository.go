@@ -0,0 +1,285 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"app-hexagonal/pkg/queryspec"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const versionColumn = "version"
+
+// ErrVersionConflict is returned by Repository.Update when the row was changed
+// since the entity was read
+var ErrVersionConflict = errors.New("version conflict")
+
+// Repository implements the CRUD of a GORM model T whose primary key is of type
+// ID, for concrete repositories to embed and extend with their own queries.
+// Calls made with a context carrying a transaction from
+// TransactionManager.WithinTransaction take part in it.
+//
+// Models with a gorm.DeletedAt field are soft deleted and hidden from lookups.
+// Models with an integer version column are updated only while their version
+// matches the stored one, which every update increments.
+//
+// Missing rows are reported as gorm.ErrRecordNotFound and stale versions as
+// ErrVersionConflict, unless TranslateErrors maps them to the errors of the
+// concrete repository's port.
+type Repository[T any, ID any] struct {
+	db        *gorm.DB
+	translate func(error) error
+}
+
+// NewRepository creates a repository of T stored in db
+func NewRepository[T any, ID any](db *gorm.DB) *Repository[T, ID] {
+	return &Repository[T, ID]{
+		db:        db,
+		translate: func(err error) error { return err },
+	}
+}
+
+// TranslateErrors maps every error the repository returns through translate,
+// such as gorm.ErrRecordNotFound to a domain not found error
+func (r *Repository[T, ID]) TranslateErrors(translate func(error) error) *Repository[T, ID] {
+	r.translate = translate
+	return r
+}
+
+// Query starts a query on the model's table within the transaction carried by
+// ctx, for the custom queries of concrete repositories
+func (r *Repository[T, ID]) Query(ctx context.Context) *gorm.DB {
+	return Conn(ctx, r.db).Model(new(T))
+}
+
+// FindByID returns the entity with the primary key id, or nil and the
+// translated error when it is missing
+func (r *Repository[T, ID]) FindByID(ctx context.Context, id ID) (*T, error) {
+	model, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	entity := new(T)
+	if err := Conn(ctx, r.db).Where(primaryKeyEq(model, id)).First(entity).Error; err != nil {
+		return nil, r.translate(err)
+	}
+	return entity, nil
+}
+
+// List returns a page of the entities matching the spec, sorted by the spec
+// and then by the pagination order, which defaults to the primary key. A nil
+// pagination selects the first page of NewPagination. Scopes narrow the query
+// further.
+func (r *Repository[T, ID]) List(ctx context.Context, spec queryspec.Spec, pagination *Pagination, scopes ...func(*gorm.DB) *gorm.DB) ([]T, *PaginationResult, error) {
+	model, err := r.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pagination == nil {
+		pagination = NewPagination()
+	}
+	page := *pagination
+	if page.OrderBy == "" {
+		page.OrderBy = model.PrioritizedPrimaryField.DBName
+	}
+
+	var entities []T
+	query := ApplySpec(r.Query(ctx).Scopes(scopes...), spec)
+	result, err := OffsetPagination(query, &page, &entities)
+	if err != nil {
+		return nil, nil, r.translate(err)
+	}
+	return entities, result, nil
+}
+
+// Store inserts the entity, starting its version at 1. Inside the transaction
+// carried by ctx it runs in a savepoint, so a failed insert, such as a
+// duplicate key, leaves the transaction usable on PostgreSQL.
+func (r *Repository[T, ID]) Store(ctx context.Context, entity *T) error {
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	if err := initVersion(ctx, model, entity); err != nil {
+		return err
+	}
+	return NewTransactionManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		return r.translate(Conn(ctx, r.db).Create(entity).Error)
+	})
+}
+
+// StoreBatch inserts the entities inside a single transaction, or inside the
+// transaction carried by ctx
+func (r *Repository[T, ID]) StoreBatch(ctx context.Context, entities []*T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		if err := initVersion(ctx, model, entity); err != nil {
+			return err
+		}
+	}
+
+	return NewTransactionManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		return r.translate(Conn(ctx, r.db).CreateInBatches(entities, len(entities)).Error)
+	})
+}
+
+// Update writes every column of the entity except the primary key, the
+// creation columns and the soft delete marker. On success the entity holds the
+// new version and the values written by GORM and the audit columns, such as
+// updated_at; on failure it is left as it was.
+func (r *Repository[T, ID]) Update(ctx context.Context, entity *T) error {
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(entity).Elem()
+	snapshot := reflect.New(value.Type()).Elem()
+	snapshot.Set(value)
+	query := Conn(ctx, r.db).Model(entity).Select("*").Omit(fixedColumns(model)...)
+
+	version := versionField(model)
+	var current int64
+	if version != nil {
+		v, _ := version.ValueOf(ctx, value)
+		current = reflect.Indirect(reflect.ValueOf(v)).Convert(reflect.TypeOf(current)).Int()
+		if err := version.Set(ctx, value, current+1); err != nil {
+			return err
+		}
+		query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: current})
+	}
+
+	result := query.Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = r.missingOrStale(ctx, model, value, version != nil)
+	}
+	if result.Error != nil {
+		value.Set(snapshot)
+		return r.translate(result.Error)
+	}
+	return nil
+}
+
+// Delete removes the entity, soft deleting it when the model supports it
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	return r.delete(Conn(ctx, r.db), id)
+}
+
+// Purge hard deletes the entity, ignoring the soft delete scope
+func (r *Repository[T, ID]) Purge(ctx context.Context, id ID) error {
+	return r.delete(Conn(ctx, r.db).Unscoped(), id)
+}
+
+func (r *Repository[T, ID]) delete(db *gorm.DB, id ID) error {
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	result := db.Where(primaryKeyEq(model, id)).Delete(new(T))
+	if result.Error != nil {
+		return r.translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return r.translate(gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// missingOrStale tells a missing row from a stale version after an update
+// changed nothing. MySQL counts only the rows an update changed, so an
+// unversioned row written with its current values also gets here and is
+// reported as updated. The check must not be answered by a replica that has
+// not seen the row yet.
+func (r *Repository[T, ID]) missingOrStale(ctx context.Context, model *schema.Schema, value reflect.Value, versioned bool) error {
+	id, _ := model.PrioritizedPrimaryField.ValueOf(ctx, value)
+	var count int64
+	if err := Conn(WithPrimary(ctx), r.db).Model(new(T)).Where(primaryKeyEq(model, id)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if !versioned {
+		return nil
+	}
+	return ErrVersionConflict
+}
+
+// schema returns the parsed model, cached by GORM after the first call
+func (r *Repository[T, ID]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("repository model %s has no primary key", stmt.Schema.Name)
+	}
+	return stmt.Schema, nil
+}
+
+// primaryKeyEq matches the row with the given primary key. The key is always
+// bound as a value, never read as SQL like a string passed to First would be.
+func primaryKeyEq(model *schema.Schema, id interface{}) clause.Expression {
+	return clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: model.PrioritizedPrimaryField.DBName},
+		Value:  id,
+	}
+}
+
+// fixedColumns lists the columns an update leaves alone: the primary key, the
+// creation columns and the soft delete marker
+func fixedColumns(model *schema.Schema) []string {
+	var columns []string
+	for _, field := range model.Fields {
+		switch {
+		case field.DBName == "":
+		case field.PrimaryKey, field.AutoCreateTime > 0, field.DBName == createdByColumn,
+			field.FieldType == reflect.TypeOf(gorm.DeletedAt{}):
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns
+}
+
+// initVersion starts the version of a new entity at 1
+func initVersion(ctx context.Context, model *schema.Schema, entity interface{}) error {
+	version := versionField(model)
+	if version == nil {
+		return nil
+	}
+	value := reflect.ValueOf(entity).Elem()
+	if _, zero := version.ValueOf(ctx, value); !zero {
+		return nil
+	}
+	return version.Set(ctx, value, 1)
+}
+
+// versionField returns the integer version column of the model, if it has one
+func versionField(model *schema.Schema) *schema.Field {
+	field := model.LookUpField(versionColumn)
+	if field == nil {
+		return nil
+	}
+	switch field.IndirectFieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field
+	}
+	return nil
+}
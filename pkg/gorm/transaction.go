@@ -2,15 +2,49 @@ package gorm
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
 // TransactionManager handles database transactions with safety features
 type TransactionManager struct {
-	db *gorm.DB
+	db    *gorm.DB
+	stats transactionStats
+}
+
+// TransactionStats counts the transactions run by a TransactionManager
+type TransactionStats struct {
+	// Transactions is the number of transactions started, not counting retries
+	Transactions int64
+	// Retries is the number of attempts repeated after a retryable error
+	Retries int64
+	// Failures is the number of transactions that failed after their last attempt
+	Failures int64
+}
+
+// transactionStats holds the counters behind TransactionStats
+type transactionStats struct {
+	transactions atomic.Int64
+	retries      atomic.Int64
+	failures     atomic.Int64
+}
+
+// Stats returns the counters of the transactions run so far
+func (tm *TransactionManager) Stats() TransactionStats {
+	return TransactionStats{
+		Transactions: tm.stats.transactions.Load(),
+		Retries:      tm.stats.retries.Load(),
+		Failures:     tm.stats.failures.Load(),
+	}
 }
 
 // NewTransactionManager creates a new transaction manager
@@ -27,12 +61,71 @@ func (tm *TransactionManager) WithTransaction(ctx context.Context, fn Transactio
 	return tm.WithTransactionOptions(ctx, fn, &TransactionOptions{})
 }
 
+// txKey carries the active transaction in a context
+type txKey struct{}
+
+// WithinTransaction runs fn as a unit of work whose transaction is carried by
+// the context passed to fn. Repositories that get their handle from Conn take
+// part in it. The transaction is bound to ctx, so cancelling ctx or reaching its
+// deadline interrupts the running statement, rolls the transaction back and
+// fails with the context's error. The SQLite driver only interrupts statements
+// run with Exec; its queries stop once their current step returns. A call made
+// while a transaction is already active runs in a savepoint of that
+// transaction: its failure undoes only its own work, leaving the caller free to
+// recover or to fail the whole unit.
+func (tm *TransactionManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return tm.WithinTransactionOptions(ctx, &TransactionOptions{}, fn)
+}
+
+// WithinTransactionOptions is WithinTransaction with an isolation level,
+// timeout and retries. Retrying runs fn again from the start, so fn must not
+// have effects outside the transaction. Options are ignored by nested calls,
+// which take part in the outer transaction and are retried with it.
+func (tm *TransactionManager) WithinTransactionOptions(ctx context.Context, opts *TransactionOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		return cancelled(ctx, err)
+	}
+
+	return tm.WithTransactionOptions(ctx, func(tx *gorm.DB) error {
+		return fn(context.WithValue(tx.Statement.Context, txKey{}, tx))
+	}, opts)
+}
+
+// InTransaction reports whether ctx carries a transaction from WithinTransaction.
+// Caches in front of a repository skip such calls, as they may read rows the
+// transaction has not committed.
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return ok
+}
+
+// Conn returns the transaction carried by ctx, or db when there is none, bound to ctx
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
 // TransactionOptions configures transaction behavior
 type TransactionOptions struct {
-	MaxRetries     int
-	RetryDelay     time.Duration
-	IsolationLevel string
+	MaxRetries int
+	RetryDelay time.Duration
+	// IsolationLevel is passed to the driver when the transaction begins and
+	// mapped to the closest level the dialect offers; LevelDefault keeps the
+	// database default
+	IsolationLevel sql.IsolationLevel
+	ReadOnly       bool
 	Timeout        time.Duration
+	// OnRetry, when set, is called before each retry with the number of the
+	// attempt about to run and the error that failed the previous one
+	OnRetry func(attempt int, err error)
 }
 
 // DefaultTransactionOptions returns default transaction options
@@ -40,7 +133,7 @@ func DefaultTransactionOptions() *TransactionOptions {
 	return &TransactionOptions{
 		MaxRetries:     3,
 		RetryDelay:     100 * time.Millisecond,
-		IsolationLevel: "READ_COMMITTED",
+		IsolationLevel: sql.LevelReadCommitted,
 		Timeout:        30 * time.Second,
 	}
 }
@@ -51,6 +144,8 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, fn Tra
 		opts = DefaultTransactionOptions()
 	}
 
+	parent := ctx
+
 	// Apply timeout to context if specified
 	if opts.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -58,6 +153,7 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, fn Tra
 		defer cancel()
 	}
 
+	tm.stats.transactions.Add(1)
 	var lastErr error
 
 	// Retry mechanism for handling transient errors and race conditions
@@ -66,14 +162,19 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, fn Tra
 			// Exponential backoff with jitter
 			delay := time.Duration(float64(opts.RetryDelay) * pow(2, float64(attempt-1)))
 			jitter := time.Duration(float64(delay) * 0.1 * float64(attempt%3)) // Simple jitter
-			time.Sleep(delay + jitter)
+			select {
+			case <-time.After(delay + jitter):
+			case <-parent.Done():
+				tm.stats.failures.Add(1)
+				return parent.Err()
+			}
 		}
 
-		// Create a new context for each attempt to avoid timeout carryover
+		// Give each attempt a fresh timeout, still bounded by the caller's context
 		attemptCtx := ctx
 		if opts.Timeout > 0 && attempt > 0 {
 			var cancel context.CancelFunc
-			attemptCtx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+			attemptCtx, cancel = context.WithTimeout(parent, opts.Timeout)
 			defer cancel()
 		}
 
@@ -85,23 +186,27 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, fn Tra
 		lastErr = err
 
 		// Don't retry on non-retryable errors
-		if !isRetryableError(err) {
-			return err
+		if !IsRetryableError(err) {
+			break
 		}
 
-		// Log retry attempt
 		if attempt < opts.MaxRetries {
-			// In a real implementation, you would use a logger
+			tm.stats.retries.Add(1)
+			if opts.OnRetry != nil {
+				opts.OnRetry(attempt+1, err)
+			}
 		}
 	}
 
+	tm.stats.failures.Add(1)
 	return lastErr
 }
 
 // executeTransaction performs the actual transaction execution
 func (tm *TransactionManager) executeTransaction(ctx context.Context, fn TransactionFunc, opts *TransactionOptions) error {
-	// Begin transaction with isolation level
-	tx := tm.db.Begin()
+	// Begin the transaction with its isolation level. The transaction is bound
+	// to ctx, so database/sql rolls it back when ctx is done.
+	tx := tm.db.WithContext(ctx).Begin(txOptions(tm.db.Dialector.Name(), opts))
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -114,16 +219,14 @@ func (tm *TransactionManager) executeTransaction(ctx context.Context, fn Transac
 		}
 	}()
 
-	// Set isolation level if specified
-	if opts.IsolationLevel != "" {
-		if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL " + opts.IsolationLevel).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-
 	// Execute the transaction function
 	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return cancelled(ctx, err)
+	}
+
+	// Don't commit work that was cut short by a cancelled context
+	if err := ctx.Err(); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -136,6 +239,16 @@ func (tm *TransactionManager) executeTransaction(ctx context.Context, fn Transac
 	return nil
 }
 
+// cancelled adds the context's error to err when ctx ended while the work ran,
+// since drivers report an interrupted statement with errors of their own
+func cancelled(ctx context.Context, err error) error {
+	ctxErr := ctx.Err()
+	if err == nil || ctxErr == nil || errors.Is(err, ctxErr) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ctxErr, err)
+}
+
 // ConcurrentTransactionManager handles concurrent transactions with additional safety
 type ConcurrentTransactionManager struct {
 	TransactionManager
@@ -145,7 +258,7 @@ type ConcurrentTransactionManager struct {
 // NewConcurrentTransactionManager creates a new concurrent transaction manager
 func NewConcurrentTransactionManager(db *gorm.DB) *ConcurrentTransactionManager {
 	return &ConcurrentTransactionManager{
-		TransactionManager: *NewTransactionManager(db),
+		TransactionManager: TransactionManager{db: db},
 	}
 }
 
@@ -163,30 +276,76 @@ func (ctm *ConcurrentTransactionManager) WithTransactionOptions(ctx context.Cont
 	return ctm.TransactionManager.WithTransactionOptions(ctx, fn, opts)
 }
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error) bool {
+// txOptions maps the options to sql.TxOptions for the dialect. MySQL and
+// PostgreSQL have no snapshot or linearizable level, and their repeatable read
+// already reads from a snapshot. SQLite transactions are always serializable.
+func txOptions(dialect string, opts *TransactionOptions) *sql.TxOptions {
+	level := opts.IsolationLevel
+	switch dialect {
+	case "mysql", "postgres":
+		switch level {
+		case sql.LevelSnapshot:
+			level = sql.LevelRepeatableRead
+		case sql.LevelLinearizable:
+			level = sql.LevelSerializable
+		}
+	case "sqlite":
+		level = sql.LevelDefault
+	}
+	return &sql.TxOptions{Isolation: level, ReadOnly: opts.ReadOnly}
+}
+
+// Driver error codes that mean the transaction lost a race and may succeed when retried
+const (
+	mysqlLockWaitTimeout    = 1205
+	mysqlDeadlock           = 1213
+	mysqlTooManyConnections = 1040
+
+	postgresSerializationFailure = "40001"
+	postgresDeadlockDetected     = "40P01"
+	postgresTooManyConnections   = "53300"
+
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// IsRetryableError reports whether err is a deadlock, lock wait timeout,
+// serialization failure or busy database, going by the driver error code, or
+// a refused connection. Such a transaction can be run again from the start.
+func IsRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	// Common retryable errors (MySQL/PostgreSQL)
-	retryableErrors := []string{
-		"Deadlock found when trying to get lock",
-		"try restarting transaction",
-		"database is locked",
-		"Lock wait timeout exceeded",
-		"Too many connections",
-		"connection refused",
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDeadlock, mysqlLockWaitTimeout, mysqlTooManyConnections:
+			return true
+		}
+		return false
 	}
 
-	errStr := err.Error()
-	for _, retryable := range retryableErrors {
-		if contains(errStr, retryable) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case postgresSerializationFailure, postgresDeadlockDetected, postgresTooManyConnections:
 			return true
 		}
+		return false
 	}
 
-	return false
+	// The SQLite driver reports primary result codes in the low byte
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqliteBusy, sqliteLocked:
+			return true
+		}
+		return false
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
 }
 
 // pow calculates the power of a number
@@ -197,9 +356,3 @@ func pow(base, exp float64) float64 {
 	}
 	return result
 }
-
-// contains checks if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
-		(len(s) > len(substr) && (s[:len(substr)] == substr || contains(s[1:], substr))))
-}
@@ -1,11 +1,17 @@
 package mysql
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	gormpkg "app-hexagonal/pkg/gorm"
+
 	driverMysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -23,11 +29,15 @@ type mysql struct {
 	printLog     bool
 	logLevel     logger.LogLevel
 	logThreshold time.Duration
+	logger       logger.Interface
 
 	maxIdleConnection             int
 	maxOpenConnection             int
 	connectionMaxLifetimeInSecond int
 	namingStrategy                schema.Namer
+
+	replicas      []string
+	replicaConfig gormpkg.ReplicaConfig
 }
 
 type mysqlOption func(*mysql)
@@ -49,6 +59,8 @@ func Connect(DBHost string, DBPort int, DBUserName string, DBPassword string, DB
 		maxOpenConnection:             10,
 		connectionMaxLifetimeInSecond: 60,
 		namingStrategy:                nil,
+
+		replicaConfig: gormpkg.DefaultReplicaConfig(),
 	}
 
 	for _, o := range options {
@@ -59,19 +71,68 @@ func Connect(DBHost string, DBPort int, DBUserName string, DBPassword string, DB
 }
 
 func connect(param *mysql) (*gorm.DB, error) {
-	// Construct MySQL DSN
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+	db, err := open(param, param.dsn(param.DBHost, param.DBPort), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(param.replicas) == 0 {
+		return db, nil
+	}
+
+	// Replicas are not pinged here: one that is down is kept out of rotation by
+	// the health checks instead of failing the start up
+	replicas := make([]*sql.DB, 0, len(param.replicas))
+	for _, replica := range param.replicas {
+		replicaDB, err := open(param, param.replicaDSN(replica), true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open replica %s: %w", replica, err)
+		}
+		sqlDB, _ := replicaDB.DB()
+		replicas = append(replicas, sqlDB)
+	}
+
+	if err := db.Use(gormpkg.NewReplicaResolver(param.replicaConfig, replicas...)); err != nil {
+		return nil, fmt.Errorf("failed to register replicas: %w", err)
+	}
+	return db, nil
+}
+
+// dsn builds the MySQL DSN for the given server
+func (param *mysql) dsn(host string, port int) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=%s",
 		param.DBUserName,
 		param.DBPassword,
-		param.DBHost,
-		param.DBPort,
+		net.JoinHostPort(host, strconv.Itoa(port)),
 		param.DBDatabaseName,
 		param.DBTimezone,
 	)
+}
 
+// replicaDSN returns replica as is when it is a full DSN, otherwise it treats it
+// as host or host:port and reuses the primary's credentials and database
+func (param *mysql) replicaDSN(replica string) string {
+	if strings.Contains(replica, "@") {
+		return replica
+	}
+	host, port, err := net.SplitHostPort(replica)
+	if err != nil {
+		return param.dsn(replica, param.DBPort)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		portNumber = param.DBPort
+	}
+	return param.dsn(host, portNumber)
+}
+
+// open connects to a single server and applies the pool settings
+func open(param *mysql, dsn string, skipPing bool) (*gorm.DB, error) {
 	// GORM Config
-	cfg := &gorm.Config{}
-	if param.printLog {
+	// TranslateError converts driver errors such as duplicate keys into gorm.ErrDuplicatedKey
+	cfg := &gorm.Config{TranslateError: true, DisableAutomaticPing: skipPing}
+	if param.logger != nil {
+		cfg.Logger = param.logger
+	} else if param.printLog {
 		cfg.Logger = logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
 			SlowThreshold: param.logThreshold,
 			LogLevel:      param.logLevel,
@@ -82,8 +143,9 @@ func connect(param *mysql) (*gorm.DB, error) {
 		cfg.NamingStrategy = param.namingStrategy
 	}
 
-	// Open Database Connection
-	db, err := gorm.Open(driverMysql.Open(dsn), cfg)
+	// Open Database Connection. The server version is only queried when pinging,
+	// since it needs a reachable server.
+	db, err := gorm.Open(driverMysql.New(driverMysql.Config{DSN: dsn, SkipInitializeWithVersion: skipPing}), cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -141,6 +203,13 @@ func SetPrintLog(isEnable bool, level logger.LogLevel, latencyThreshold time.Dur
 	}
 }
 
+// SetLogger logs through l instead of the text logger of SetPrintLog
+func SetLogger(l logger.Interface) mysqlOption {
+	return func(c *mysql) {
+		c.logger = l
+	}
+}
+
 func SetTimezone(timezone string) mysqlOption {
 	return func(c *mysql) {
 		if timezone == "" {
@@ -159,3 +228,23 @@ func SetTablePrefix(prefix string) mysqlOption {
 		}
 	}
 }
+
+// SetReplicas adds read replicas. Each replica is a full DSN or a host with an
+// optional port, in which case the primary's credentials and database are used.
+// Reads are sent to healthy replicas; writes and transactions use the primary.
+func SetReplicas(replicas ...string) mysqlOption {
+	return func(c *mysql) {
+		for _, replica := range replicas {
+			if replica = strings.TrimSpace(replica); replica != "" {
+				c.replicas = append(c.replicas, replica)
+			}
+		}
+	}
+}
+
+// SetReplicaConfig sets the replica health check and read-your-writes settings
+func SetReplicaConfig(config gormpkg.ReplicaConfig) mysqlOption {
+	return func(c *mysql) {
+		c.replicaConfig = config
+	}
+}
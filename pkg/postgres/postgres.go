@@ -1,11 +1,19 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	gormpkg "app-hexagonal/pkg/gorm"
+
 	driverPostgres "gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -19,15 +27,22 @@ type psql struct {
 	DBPassword     string
 	DBDatabaseName string
 	DBTimezone     string
+	DBSSLMode      string
 
 	printLog     bool
 	logLevel     logger.LogLevel
 	logThreshold time.Duration
+	logger       logger.Interface
 
 	maxIdleConnection             int
 	maxOpenConnection             int
 	connectionMaxLifetimeInSecond int
 	namingStrategy                schema.Namer
+
+	replicas      []string
+	replicaConfig gormpkg.ReplicaConfig
+
+	tenancy *TenancyConfig
 }
 type pgsqlOption func(*psql)
 
@@ -39,6 +54,7 @@ func Connect(DBHost string, DBPort int, DBUserName string, DBPassword string, DB
 		DBPassword:     DBPassword,
 		DBDatabaseName: DBDatabaseName,
 		DBTimezone:     "UTC",
+		DBSSLMode:      "disable",
 
 		printLog:     false,
 		logLevel:     logger.Silent,
@@ -48,6 +64,8 @@ func Connect(DBHost string, DBPort int, DBUserName string, DBPassword string, DB
 		maxOpenConnection:             10,
 		connectionMaxLifetimeInSecond: 60,
 		namingStrategy:                nil,
+
+		replicaConfig: gormpkg.DefaultReplicaConfig(),
 	}
 
 	for _, o := range options {
@@ -58,16 +76,96 @@ func Connect(DBHost string, DBPort int, DBUserName string, DBPassword string, DB
 }
 
 func connect(param *psql) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable&TimeZone=%s",
-		param.DBUserName,
-		param.DBPassword,
-		param.DBHost,
-		param.DBPort,
-		param.DBDatabaseName,
-		param.DBTimezone)
-
-	cfg := &gorm.Config{}
-	if param.printLog {
+	db, err := open(param, param.dsn(param.DBHost, param.DBPort), false)
+	if err != nil {
+		return nil, err
+	}
+	if param.tenancy != nil {
+		// Replicas would need a pool per tenant of their own
+		if len(param.replicas) > 0 {
+			return nil, errors.New("schema per tenant does not support read replicas")
+		}
+		shared, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database handle: %w", err)
+		}
+		schemas := newPostgresSchemas(param, shared, param.tenancy.Migrations)
+		if err := db.Use(NewSchemaPerTenant(*param.tenancy, schemas)); err != nil {
+			return nil, fmt.Errorf("failed to register schema per tenant: %w", err)
+		}
+		return db, nil
+	}
+	if len(param.replicas) == 0 {
+		return db, nil
+	}
+
+	// Replicas are not pinged here: one that is down is kept out of rotation by
+	// the health checks instead of failing the start up
+	replicas := make([]*sql.DB, 0, len(param.replicas))
+	for _, replica := range param.replicas {
+		replicaDB, err := open(param, param.replicaDSN(replica), true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open replica %s: %w", replica, err)
+		}
+		sqlDB, _ := replicaDB.DB()
+		replicas = append(replicas, sqlDB)
+	}
+
+	if err := db.Use(gormpkg.NewReplicaResolver(param.replicaConfig, replicas...)); err != nil {
+		return nil, fmt.Errorf("failed to register replicas: %w", err)
+	}
+	return db, nil
+}
+
+// dsn builds the PostgreSQL URL for the given server
+func (param *psql) dsn(host string, port int) string {
+	return (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(param.DBUserName, param.DBPassword),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     "/" + param.DBDatabaseName,
+		RawQuery: url.Values{"sslmode": {param.DBSSLMode}, "TimeZone": {param.DBTimezone}}.Encode(),
+	}).String()
+}
+
+// tenantDSN returns the primary's URL with schema as the search_path of every connection
+func (param *psql) tenantDSN(schema string) string {
+	dsn, _ := url.Parse(param.dsn(param.DBHost, param.DBPort))
+	query := dsn.Query()
+	query.Set("search_path", schema)
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+// connMaxLifetime returns how long a connection may be reused
+func (param *psql) connMaxLifetime() time.Duration {
+	return time.Duration(param.connectionMaxLifetimeInSecond) * time.Second
+}
+
+// replicaDSN returns replica as is when it is a full DSN, otherwise it treats it
+// as host or host:port and reuses the primary's credentials and database
+func (param *psql) replicaDSN(replica string) string {
+	if strings.Contains(replica, "://") || strings.Contains(replica, "=") {
+		return replica
+	}
+	host, port, err := net.SplitHostPort(replica)
+	if err != nil {
+		return param.dsn(replica, param.DBPort)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		portNumber = param.DBPort
+	}
+	return param.dsn(host, portNumber)
+}
+
+// open connects to a single server and applies the pool settings
+func open(param *psql, dsn string, skipPing bool) (*gorm.DB, error) {
+	// TranslateError converts driver errors such as duplicate keys into gorm.ErrDuplicatedKey
+	cfg := &gorm.Config{TranslateError: true, DisableAutomaticPing: skipPing}
+	if param.logger != nil {
+		cfg.Logger = param.logger
+	} else if param.printLog {
 		cfg.Logger = logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
 			SlowThreshold: param.logThreshold,
 			LogLevel:      param.logLevel,
@@ -88,7 +186,7 @@ func connect(param *psql) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to get database handle: %w", err)
 	}
 	sqlDB.SetMaxOpenConns(param.maxOpenConnection)
-	sqlDB.SetConnMaxLifetime(time.Duration(param.connectionMaxLifetimeInSecond) * time.Second)
+	sqlDB.SetConnMaxLifetime(param.connMaxLifetime())
 	sqlDB.SetMaxIdleConns(param.maxIdleConnection)
 
 	return db, nil
@@ -135,6 +233,13 @@ func SetPrintLog(isEnable bool, level logger.LogLevel, latencyThreshold time.Dur
 	}
 }
 
+// SetLogger logs through l instead of the text logger of SetPrintLog
+func SetLogger(l logger.Interface) pgsqlOption {
+	return func(c *psql) {
+		c.logger = l
+	}
+}
+
 func SetTimezone(timezone string) pgsqlOption {
 	return func(c *psql) {
 		if timezone == "" {
@@ -145,6 +250,15 @@ func SetTimezone(timezone string) pgsqlOption {
 	}
 }
 
+// SetSSLMode sets the libpq sslmode, e.g. disable, require or verify-full
+func SetSSLMode(mode string) pgsqlOption {
+	return func(c *psql) {
+		if mode != "" {
+			c.DBSSLMode = mode
+		}
+	}
+}
+
 // SetTablePrefix sets the table prefix for all tables
 func SetTablePrefix(prefix string) pgsqlOption {
 	return func(c *psql) {
@@ -153,3 +267,31 @@ func SetTablePrefix(prefix string) pgsqlOption {
 		}
 	}
 }
+
+// SetReplicas adds read replicas. Each replica is a full DSN or a host with an
+// optional port, in which case the primary's credentials and database are used.
+// Reads are sent to healthy replicas; writes and transactions use the primary.
+func SetReplicas(replicas ...string) pgsqlOption {
+	return func(c *psql) {
+		for _, replica := range replicas {
+			if replica = strings.TrimSpace(replica); replica != "" {
+				c.replicas = append(c.replicas, replica)
+			}
+		}
+	}
+}
+
+// SetReplicaConfig sets the replica health check and read-your-writes settings
+func SetReplicaConfig(config gormpkg.ReplicaConfig) pgsqlOption {
+	return func(c *psql) {
+		c.replicaConfig = config
+	}
+}
+
+// SetTenancy keeps every tenant in a schema of its own, see SchemaPerTenant.
+// A nil config keeps a single schema. It cannot be combined with SetReplicas.
+func SetTenancy(config *TenancyConfig) pgsqlOption {
+	return func(c *psql) {
+		c.tenancy = config
+	}
+}
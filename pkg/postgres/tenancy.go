@@ -0,0 +1,639 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratePostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// TenancyName is the name the schema per tenant plugin is registered under
+const TenancyName = "postgres:schema_per_tenant"
+
+// maxIdentifierLength is the longest name PostgreSQL keeps without truncating
+const maxIdentifierLength = 63
+
+var (
+	// ErrNoTenant is returned for statements without a tenant when one is required
+	ErrNoTenant = errors.New("no tenant in context")
+	// ErrUnknownTenant is returned for a tenant whose schema was never provisioned
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrInvalidTenant is returned for tenant IDs that cannot name a schema
+	ErrInvalidTenant = errors.New("invalid tenant ID")
+)
+
+// tenantID matches the tenant IDs accepted as part of a schema name
+var tenantID = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// TenancyConfig configures the schema per tenant strategy
+type TenancyConfig struct {
+	// Tenant returns the ID of the tenant the statement's context acts for, or
+	// an empty string for none
+	Tenant func(ctx context.Context) string
+	// SchemaPrefix is put before the tenant ID to name its schema, tenant_ by default
+	SchemaPrefix string
+	// RequireTenant refuses statements without a tenant with ErrNoTenant instead
+	// of running them on the shared schema of the connection
+	RequireTenant bool
+	// AutoProvision provisions the schema of a tenant the first time it is
+	// used. Without it such statements fail with ErrUnknownTenant until the
+	// tenant is provisioned. Only enable it when tenant IDs come from a trusted
+	// source, as every new ID creates a schema.
+	AutoProvision bool
+	// Migrations returns the migrations applied to every tenant schema
+	Migrations func() (source.Driver, error)
+	// MaxOpenConns and MaxIdleConns size the pool of each tenant, 5 and 1 by
+	// default, as most tenants are idle most of the time
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxIdleTime closes tenant connections left idle this long, a minute
+	// by default
+	ConnMaxIdleTime time.Duration
+	// PoolIdleTimeout closes the pool of a tenant not used this long, ten
+	// minutes by default. The next statement of the tenant opens it again.
+	PoolIdleTimeout time.Duration
+}
+
+const (
+	defaultTenantMaxOpenConns    = 5
+	defaultTenantMaxIdleConns    = 1
+	defaultTenantConnMaxIdleTime = time.Minute
+	defaultTenantPoolIdleTimeout = 10 * time.Minute
+)
+
+// Schema returns the name of the tenant's schema
+func (c TenancyConfig) Schema(tenant string) (string, error) {
+	schema := c.prefix() + tenant
+	if !tenantID.MatchString(tenant) || len(schema) > maxIdentifierLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, tenant)
+	}
+	return schema, nil
+}
+
+// prefix returns the prefix of tenant schema names
+func (c TenancyConfig) prefix() string {
+	if c.SchemaPrefix == "" {
+		return "tenant_"
+	}
+	return c.SchemaPrefix
+}
+
+// TenantStatus reports the migration state of one tenant schema
+type TenantStatus struct {
+	Tenant  string
+	Schema  string
+	Version uint
+	Dirty   bool
+	// Pending is the number of migrations not applied yet
+	Pending int
+	// Err is why the tenant could not be migrated or inspected
+	Err error
+}
+
+// TenantSchemas does the database work of SchemaPerTenant. SetTenancy uses
+// the PostgreSQL implementation; tests may provide their own.
+type TenantSchemas interface {
+	// Exists reports whether the schema has been created
+	Exists(ctx context.Context, schema string) (bool, error)
+	// Create creates the schema unless it exists
+	Create(ctx context.Context, schema string) error
+	// Migrate applies the pending migrations to the schema when up is set,
+	// and reports its state
+	Migrate(ctx context.Context, tenant, schema string, up bool) TenantStatus
+	// List returns the names of the schemas starting with prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Open opens a pool whose connections use the schema
+	Open(schema string) (*sql.DB, error)
+}
+
+// SchemaPerTenant is a GORM plugin that keeps every tenant in its own schema.
+// Statements, including whole transactions, run on a pool of the tenant taken
+// from their context, whose connections have the tenant's schema as their
+// search_path. Statements without a tenant run on the connection's own pool
+// and schema, which holds the data shared by all tenants.
+//
+// Each tenant in use holds its own pool of up to MaxOpenConns connections.
+// Idle connections are closed after ConnMaxIdleTime, and pools left unused for
+// PoolIdleTimeout are closed in the background.
+type SchemaPerTenant struct {
+	config  TenancyConfig
+	schemas TenantSchemas
+
+	shared *sql.DB
+	mu     sync.RWMutex
+	pools  map[string]*tenantPool
+	// provisioning serialises opening and provisioning tenants
+	provisioning sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     sync.WaitGroup
+}
+
+// tenantPool is the pool of one tenant and when it was last handed out
+type tenantPool struct {
+	db   *sql.DB
+	used atomic.Int64
+}
+
+// NewSchemaPerTenant creates the plugin keeping tenants in the schemas that
+// schemas manages. Register it with db.Use; SetTenancy does so for PostgreSQL.
+func NewSchemaPerTenant(config TenancyConfig, schemas TenantSchemas) *SchemaPerTenant {
+	if config.MaxOpenConns <= 0 {
+		config.MaxOpenConns = defaultTenantMaxOpenConns
+	}
+	if config.MaxIdleConns <= 0 {
+		config.MaxIdleConns = defaultTenantMaxIdleConns
+	}
+	if config.MaxIdleConns > config.MaxOpenConns {
+		config.MaxIdleConns = config.MaxOpenConns
+	}
+	if config.ConnMaxIdleTime <= 0 {
+		config.ConnMaxIdleTime = defaultTenantConnMaxIdleTime
+	}
+	if config.PoolIdleTimeout <= 0 {
+		config.PoolIdleTimeout = defaultTenantPoolIdleTimeout
+	}
+
+	return &SchemaPerTenant{
+		config:  config,
+		schemas: schemas,
+		pools:   make(map[string]*tenantPool),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Tenancy returns the schema per tenant plugin registered on db, if any
+func Tenancy(db *gorm.DB) (*SchemaPerTenant, bool) {
+	plugin, ok := db.Config.Plugins[TenancyName]
+	if !ok {
+		return nil, false
+	}
+	tenancy, ok := plugin.(*SchemaPerTenant)
+	return tenancy, ok
+}
+
+// Name implements gorm.Plugin
+func (t *SchemaPerTenant) Name() string {
+	return TenancyName
+}
+
+// Initialize implements gorm.Plugin by putting itself in front of the
+// connection pool of db
+func (t *SchemaPerTenant) Initialize(db *gorm.DB) error {
+	shared, ok := db.ConnPool.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("schema per tenant needs a *sql.DB connection pool, got %T", db.ConnPool)
+	}
+	t.shared = shared
+	db.ConnPool = t
+	db.Statement.ConnPool = t
+
+	// Row cannot carry the error of choosing the pool, so it is checked first
+	err := db.Callback().Row().Before("gorm:row").Register("tenancy:check_row", func(db *gorm.DB) {
+		if db.Error == nil && db.Statement.ConnPool == gorm.ConnPool(t) {
+			if _, err := t.pool(db.Statement.Context); err != nil {
+				db.AddError(err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	t.done.Add(1)
+	go t.evictLoop()
+	return nil
+}
+
+// Close stops closing idle pools and closes the pools of the tenants; the
+// shared pool is closed with db
+func (t *SchemaPerTenant) Close() error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	t.done.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+	for schema, pool := range t.pools {
+		errs = append(errs, pool.db.Close())
+		delete(t.pools, schema)
+	}
+	return errors.Join(errs...)
+}
+
+// CloseTenancy closes the schema per tenant plugin registered on db, if any
+func CloseTenancy(db *gorm.DB) error {
+	if tenancy, ok := Tenancy(db); ok {
+		return tenancy.Close()
+	}
+	return nil
+}
+
+func (t *SchemaPerTenant) evictLoop() {
+	defer t.done.Done()
+
+	ticker := time.NewTicker(t.config.PoolIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.evictIdle(time.Now().Add(-t.config.PoolIdleTimeout))
+		}
+	}
+}
+
+// evictIdle closes the pools last handed out before cutoff. A pool is handed
+// out under the read lock, so one being evicted cannot be handed out meanwhile,
+// and one still running a long transaction is kept.
+func (t *SchemaPerTenant) evictIdle(cutoff time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for schema, pool := range t.pools {
+		if pool.used.Load() < cutoff.UnixNano() && pool.db.Stats().InUse == 0 {
+			pool.db.Close()
+			delete(t.pools, schema)
+		}
+	}
+}
+
+// PrepareContext implements gorm.ConnPool
+func (t *SchemaPerTenant) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	pool, err := t.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.PrepareContext(ctx, query)
+}
+
+// ExecContext implements gorm.ConnPool
+func (t *SchemaPerTenant) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	pool, err := t.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements gorm.ConnPool
+func (t *SchemaPerTenant) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	pool, err := t.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext implements gorm.ConnPool. A row cannot be built with an
+// error, so when no pool can be chosen the query runs nowhere: it is given a
+// cancelled context and the row reports context.Canceled.
+func (t *SchemaPerTenant) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	pool, err := t.pool(ctx)
+	if err != nil {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		return t.shared.QueryRowContext(cancelled, query, args...)
+	}
+	return pool.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx implements gorm.TxBeginner, so a transaction runs entirely in the
+// schema of the tenant it was started for
+func (t *SchemaPerTenant) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	pool, err := t.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.BeginTx(ctx, opts)
+}
+
+// GetDBConn implements gorm.GetDBConnector, so db.DB() returns the shared pool
+func (t *SchemaPerTenant) GetDBConn() (*sql.DB, error) {
+	return t.shared, nil
+}
+
+// pool returns the pool of the tenant in ctx, opening it on first use
+func (t *SchemaPerTenant) pool(ctx context.Context) (*sql.DB, error) {
+	var tenant string
+	if t.config.Tenant != nil {
+		tenant = t.config.Tenant(ctx)
+	}
+	if tenant == "" {
+		if t.config.RequireTenant {
+			return nil, ErrNoTenant
+		}
+		return t.shared, nil
+	}
+
+	schema, err := t.config.Schema(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if pool, ok := t.cached(schema); ok {
+		return pool, nil
+	}
+	return t.openTenant(ctx, tenant, schema)
+}
+
+// cached returns the open pool of a schema, recording that it is in use
+func (t *SchemaPerTenant) cached(schema string) (*sql.DB, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	pool, ok := t.pools[schema]
+	if !ok {
+		return nil, false
+	}
+	pool.used.Store(time.Now().UnixNano())
+	return pool.db, true
+}
+
+// openTenant opens the pool of a tenant not used before, provisioning its
+// schema first when AutoProvision is set
+func (t *SchemaPerTenant) openTenant(ctx context.Context, tenant, schema string) (*sql.DB, error) {
+	t.provisioning.Lock()
+	defer t.provisioning.Unlock()
+
+	if pool, ok := t.cached(schema); ok {
+		return pool, nil
+	}
+
+	exists, err := t.schemas.Exists(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if !t.config.AutoProvision {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, tenant)
+		}
+		if status := t.provision(ctx, tenant, schema); status.Err != nil {
+			return nil, status.Err
+		}
+	}
+
+	db, err := t.schemas.Open(schema)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(t.config.MaxOpenConns)
+	db.SetMaxIdleConns(t.config.MaxIdleConns)
+	db.SetConnMaxIdleTime(t.config.ConnMaxIdleTime)
+
+	pool := &tenantPool{db: db}
+	pool.used.Store(time.Now().UnixNano())
+	t.mu.Lock()
+	t.pools[schema] = pool
+	t.mu.Unlock()
+	return db, nil
+}
+
+// Provision creates the schema of a tenant if it does not exist yet and
+// applies the pending migrations to it. Provisioning a tenant again only
+// migrates it.
+func (t *SchemaPerTenant) Provision(ctx context.Context, tenant string) (TenantStatus, error) {
+	schema, err := t.config.Schema(tenant)
+	if err != nil {
+		return TenantStatus{Tenant: tenant, Err: err}, err
+	}
+
+	t.provisioning.Lock()
+	defer t.provisioning.Unlock()
+	status := t.provision(ctx, tenant, schema)
+	return status, status.Err
+}
+
+// provision creates and migrates the schema; the caller holds provisioning
+func (t *SchemaPerTenant) provision(ctx context.Context, tenant, schema string) TenantStatus {
+	if err := t.schemas.Create(ctx, schema); err != nil {
+		return TenantStatus{Tenant: tenant, Schema: schema, Err: err}
+	}
+	return t.schemas.Migrate(ctx, tenant, schema, true)
+}
+
+// Tenants returns the IDs of the provisioned tenants in order, found by the
+// schemas named with the prefix
+func (t *SchemaPerTenant) Tenants(ctx context.Context) ([]string, error) {
+	prefix := t.config.prefix()
+	schemas, err := t.schemas.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []string
+	for _, schema := range schemas {
+		if tenant := schema[len(prefix):]; tenantID.MatchString(tenant) {
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants, nil
+}
+
+// MigrateTenants applies the pending migrations to every tenant schema in
+// turn and reports the state of each. A tenant that fails is reported with
+// its error and does not stop the others.
+func (t *SchemaPerTenant) MigrateTenants(ctx context.Context) ([]TenantStatus, error) {
+	return t.eachTenant(ctx, true)
+}
+
+// TenantStatuses reports the migration state of every tenant schema
+func (t *SchemaPerTenant) TenantStatuses(ctx context.Context) ([]TenantStatus, error) {
+	return t.eachTenant(ctx, false)
+}
+
+func (t *SchemaPerTenant) eachTenant(ctx context.Context, up bool) ([]TenantStatus, error) {
+	tenants, err := t.Tenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]TenantStatus, 0, len(tenants))
+	for _, tenant := range tenants {
+		if err := ctx.Err(); err != nil {
+			return statuses, err
+		}
+		schema, _ := t.config.Schema(tenant)
+		if up {
+			t.provisioning.Lock()
+			statuses = append(statuses, t.schemas.Migrate(ctx, tenant, schema, true))
+			t.provisioning.Unlock()
+		} else {
+			statuses = append(statuses, t.schemas.Migrate(ctx, tenant, schema, false))
+		}
+	}
+	return statuses, nil
+}
+
+// postgresSchemas keeps tenant schemas in the PostgreSQL database of param
+type postgresSchemas struct {
+	param      *psql
+	shared     *sql.DB
+	migrations func() (source.Driver, error)
+}
+
+// newPostgresSchemas manages the tenant schemas next to the shared pool
+func newPostgresSchemas(param *psql, shared *sql.DB, migrations func() (source.Driver, error)) *postgresSchemas {
+	return &postgresSchemas{param: param, shared: shared, migrations: migrations}
+}
+
+// Open implements TenantSchemas
+func (p *postgresSchemas) Open(schema string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", p.param.tenantDSN(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema %s: %w", schema, err)
+	}
+	db.SetConnMaxLifetime(p.param.connMaxLifetime())
+	return db, nil
+}
+
+// Create implements TenantSchemas
+func (p *postgresSchemas) Create(ctx context.Context, schema string) error {
+	// The schema name was validated, so it is safe to quote it as is
+	if _, err := p.shared.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS "`+schema+`"`); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+	return nil
+}
+
+// List implements TenantSchemas
+func (p *postgresSchemas) List(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := p.shared.QueryContext(ctx,
+		"SELECT nspname FROM pg_namespace WHERE left(nspname, $1) = $2", len(prefix), prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// Migrate implements TenantSchemas. It runs on a connection of its own whose search_path is
+// the schema, so the migrations create their tables there. golang-migrate
+// holds an advisory lock per schema meanwhile, so instances migrating the
+// same tenant take turns.
+func (p *postgresSchemas) Migrate(ctx context.Context, tenant, schema string, up bool) TenantStatus {
+	status := TenantStatus{Tenant: tenant, Schema: schema}
+	if p.migrations == nil {
+		status.Err = errors.New("no tenant migrations configured")
+		return status
+	}
+
+	m, err := p.newMigrate(schema)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	defer m.Close()
+
+	if up {
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			status.Err = fmt.Errorf("failed to migrate schema %s: %w", schema, err)
+		}
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		version, err = 0, nil
+	}
+	if err != nil {
+		status.Err = errors.Join(status.Err, err)
+		return status
+	}
+	status.Version, status.Dirty = version, dirty
+
+	pending, err := p.pending(version)
+	if err != nil {
+		status.Err = errors.Join(status.Err, err)
+	}
+	status.Pending = pending
+	return status
+}
+
+// newMigrate creates a migration instance for a schema. Closing it closes the
+// connection it was given.
+func (p *postgresSchemas) newMigrate(schema string) (*migrate.Migrate, error) {
+	src, err := p.migrations()
+	if err != nil {
+		return nil, err
+	}
+	db, err := p.Open(schema)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	driver, err := migratePostgres.WithInstance(db, &migratePostgres.Config{
+		DatabaseName: p.param.DBDatabaseName,
+		SchemaName:   schema,
+	})
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, fmt.Errorf("failed to create migration instance for schema %s: %w", schema, err)
+	}
+	m, err := migrate.NewWithInstance("tenant", src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("failed to create migration instance for schema %s: %w", schema, err)
+	}
+	return m, nil
+}
+
+// pending counts the migrations after version
+func (p *postgresSchemas) pending(version uint) (int, error) {
+	src, err := p.migrations()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	pending := 0
+	next, err := src.First()
+	for err == nil {
+		if next > version {
+			pending++
+		}
+		next, err = src.Next(next)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return pending, err
+	}
+	return pending, nil
+}
+
+// Exists implements TenantSchemas
+func (p *postgresSchemas) Exists(ctx context.Context, schema string) (bool, error) {
+	var exists bool
+	err := p.shared.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up schema %s: %w", schema, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,104 @@
+package queryspec
+
+import (
+	"strings"
+	"time"
+)
+
+// Getter returns the value of a field of the row being evaluated, with the
+// Go type of the field
+type Getter func(field string) interface{}
+
+// Matches reports whether the row passes every filter. It evaluates a spec
+// in memory the way the database adapters do, with contains and starts_with
+// ignoring case like LIKE under the default MySQL collation.
+func (s Spec) Matches(get Getter) bool {
+	for _, filter := range s.Filters {
+		if !filter.matches(get(filter.Field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Less reports whether row a sorts before row b. Rows that compare equal on
+// every sort field are neither less nor greater, so a stable sort keeps their order.
+func (s Spec) Less(a, b Getter) bool {
+	for _, sort := range s.Sort {
+		order := compare(a(sort.Field), b(sort.Field))
+		if order == 0 {
+			continue
+		}
+		if sort.Desc {
+			return order > 0
+		}
+		return order < 0
+	}
+	return false
+}
+
+// matches evaluates the filter against a field value
+func (f Filter) matches(value interface{}) bool {
+	switch f.Operator {
+	case Eq:
+		return compare(value, f.Value) == 0
+	case Ne:
+		return compare(value, f.Value) != 0
+	case Gt:
+		return compare(value, f.Value) > 0
+	case Gte:
+		return compare(value, f.Value) >= 0
+	case Lt:
+		return compare(value, f.Value) < 0
+	case Lte:
+		return compare(value, f.Value) <= 0
+	case Contains:
+		text, _ := value.(string)
+		pattern, _ := f.Value.(string)
+		return strings.Contains(strings.ToLower(text), strings.ToLower(pattern))
+	case StartsWith:
+		text, _ := value.(string)
+		pattern, _ := f.Value.(string)
+		return strings.HasPrefix(strings.ToLower(text), strings.ToLower(pattern))
+	case In:
+		values, _ := f.Value.([]interface{})
+		for _, candidate := range values {
+			if compare(value, candidate) == 0 {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// compare orders two values of the same field type
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case int64:
+		b := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case bool:
+		b := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
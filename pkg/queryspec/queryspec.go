@@ -0,0 +1,258 @@
+// Package queryspec parses the sorting and filtering parameters of list
+// endpoints, e.g. ?sort=-created_at,name&filter[email][contains]=x, into a
+// Spec validated against a per-resource whitelist of fields. Specs only ever
+// name whitelisted columns and carry typed values, so adapters can apply them
+// without building SQL from client input.
+package queryspec
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is wrapped by every parse error so callers can answer with a client error
+var ErrInvalid = errors.New("invalid query")
+
+// Type is the type of a field's values
+type Type int
+
+const (
+	String Type = iota
+	Time
+	Int
+	Bool
+)
+
+// Operator compares a field with a filter value
+type Operator string
+
+const (
+	Eq         Operator = "eq"
+	Ne         Operator = "ne"
+	Gt         Operator = "gt"
+	Gte        Operator = "gte"
+	Lt         Operator = "lt"
+	Lte        Operator = "lte"
+	Contains   Operator = "contains"
+	StartsWith Operator = "starts_with"
+	// In matches any of a comma separated list of values
+	In Operator = "in"
+)
+
+// Field is a whitelisted field of a resource
+type Field struct {
+	// Name is how clients refer to the field
+	Name string
+	// Column is the database column holding the field
+	Column   string
+	Type     Type
+	Sortable bool
+	// Operators lists the filters allowed on the field; none makes it unfilterable
+	Operators []Operator
+}
+
+// Schema is the whitelist of fields a resource can be sorted and filtered by
+type Schema struct {
+	fields     map[string]Field
+	maxSort    int
+	maxFilters int
+}
+
+// NewSchema creates a schema allowing the given fields
+func NewSchema(fields ...Field) *Schema {
+	schema := &Schema{
+		fields:     make(map[string]Field, len(fields)),
+		maxSort:    3,
+		maxFilters: 10,
+	}
+	for _, field := range fields {
+		schema.fields[field.Name] = field
+	}
+	return schema
+}
+
+// Sort orders by one field
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter restricts the results to rows whose field compares to Value. Value
+// has the Go type of the field (string, time.Time, int64 or bool), or is a
+// slice of those for In.
+type Filter struct {
+	Field    string
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+// Spec is a validated sorting and filtering specification
+type Spec struct {
+	Sort    []Sort
+	Filters []Filter
+}
+
+// IsZero reports whether the spec neither sorts nor filters
+func (s Spec) IsZero() bool {
+	return len(s.Sort) == 0 && len(s.Filters) == 0
+}
+
+// Uses reports whether the spec sorts or filters by any of the fields
+func (s Spec) Uses(fields ...string) bool {
+	for _, field := range fields {
+		for _, sort := range s.Sort {
+			if sort.Field == field {
+				return true
+			}
+		}
+		for _, filter := range s.Filters {
+			if filter.Field == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RawFilter is a filter as sent by a client, before validation
+type RawFilter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// ParseFilterKey splits a query parameter such as filter[email][contains] into
+// its field and operator. filter[email] means equality.
+func ParseFilterKey(key string) (field, operator string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "filter[")
+	if !ok {
+		return "", "", false
+	}
+	field, rest, ok = strings.Cut(rest, "]")
+	if !ok || field == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return field, string(Eq), true
+	}
+	operator, ok = strings.CutPrefix(rest, "[")
+	if !ok || !strings.HasSuffix(operator, "]") {
+		return "", "", false
+	}
+	return field, strings.TrimSuffix(operator, "]"), true
+}
+
+// Parse validates a comma separated sort list, where a leading "-" sorts
+// descending, and the filters against the schema
+func (s *Schema) Parse(sort string, filters []RawFilter) (Spec, error) {
+	var spec Spec
+
+	if sort = strings.TrimSpace(sort); sort != "" {
+		seen := make(map[string]bool)
+		for _, item := range strings.Split(sort, ",") {
+			item = strings.TrimSpace(item)
+			name, desc := strings.CutPrefix(item, "-")
+			field, ok := s.fields[name]
+			if !ok || !field.Sortable {
+				return Spec{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalid, name)
+			}
+			if seen[name] {
+				return Spec{}, fmt.Errorf("%w: %q is sorted by twice", ErrInvalid, name)
+			}
+			seen[name] = true
+			spec.Sort = append(spec.Sort, Sort{Field: name, Column: field.Column, Desc: desc})
+		}
+		if len(spec.Sort) > s.maxSort {
+			return Spec{}, fmt.Errorf("%w: at most %d sort fields are allowed", ErrInvalid, s.maxSort)
+		}
+	}
+
+	if len(filters) > s.maxFilters {
+		return Spec{}, fmt.Errorf("%w: at most %d filters are allowed", ErrInvalid, s.maxFilters)
+	}
+	for _, raw := range filters {
+		filter, err := s.parseFilter(raw)
+		if err != nil {
+			return Spec{}, err
+		}
+		spec.Filters = append(spec.Filters, filter)
+	}
+	return spec, nil
+}
+
+// parseFilter validates one filter and converts its value to the field type
+func (s *Schema) parseFilter(raw RawFilter) (Filter, error) {
+	field, ok := s.fields[raw.Field]
+	if !ok {
+		return Filter{}, fmt.Errorf("%w: cannot filter by %q", ErrInvalid, raw.Field)
+	}
+	operator := Operator(raw.Operator)
+	if operator == "" {
+		operator = Eq
+	}
+	if !allows(field.Operators, operator) {
+		return Filter{}, fmt.Errorf("%w: %q does not support the %q filter", ErrInvalid, raw.Field, raw.Operator)
+	}
+
+	filter := Filter{Field: field.Name, Column: field.Column, Operator: operator}
+	if operator == In {
+		var values []interface{}
+		for _, item := range strings.Split(raw.Value, ",") {
+			value, err := parseValue(field, strings.TrimSpace(item))
+			if err != nil {
+				return Filter{}, err
+			}
+			values = append(values, value)
+		}
+		filter.Value = values
+		return filter, nil
+	}
+
+	value, err := parseValue(field, raw.Value)
+	if err != nil {
+		return Filter{}, err
+	}
+	filter.Value = value
+	return filter, nil
+}
+
+// parseValue converts a filter value to the Go type of the field
+func parseValue(field Field, value string) (interface{}, error) {
+	switch field.Type {
+	case Time:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q must be an RFC 3339 timestamp", ErrInvalid, field.Name)
+		}
+		return parsed, nil
+	case Int:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q must be an integer", ErrInvalid, field.Name)
+		}
+		return parsed, nil
+	case Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q must be true or false", ErrInvalid, field.Name)
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// allows reports whether operator is one of operators
+func allows(operators []Operator, operator Operator) bool {
+	for _, allowed := range operators {
+		if allowed == operator {
+			return true
+		}
+	}
+	return false
+}
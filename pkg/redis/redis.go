@@ -16,11 +16,28 @@ type redisConfig struct {
 	MaxIdleTimeOut  int
 	MaxConnLifetime int
 	Wait            bool
+	Timeout         time.Duration
 }
 
 type redisOption func(*redisConfig)
 
+// Connect creates a pool and verifies that Redis answers a PING
 func Connect(host string, port string, password string, options ...redisOption) (*redis.Pool, error) {
+	redisPool := NewPool(host, port, password, options...)
+
+	conn := redisPool.Get()
+	defer conn.Close()
+	_, err := conn.Do("PING")
+	if err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return redisPool, nil
+}
+
+// NewPool creates a pool without connecting, for callers that tolerate Redis
+// being unavailable at startup and reconnect on demand
+func NewPool(host string, port string, password string, options ...redisOption) *redis.Pool {
 	db := &redisConfig{
 		Host:            host,
 		Port:            port,
@@ -30,6 +47,7 @@ func Connect(host string, port string, password string, options ...redisOption)
 		MaxIdleTimeOut:  5,
 		MaxConnLifetime: 10,
 		Wait:            true,
+		Timeout:         2 * time.Second,
 	}
 	if db.Port == "" {
 		db.Port = "6379"
@@ -39,36 +57,34 @@ func Connect(host string, port string, password string, options ...redisOption)
 		o(db)
 	}
 
-	return connect(db)
+	return newPool(db)
 }
 
-func connect(param *redisConfig) (*redis.Pool, error) {
-	redisPool := &redis.Pool{
+func newPool(param *redisConfig) *redis.Pool {
+	return &redis.Pool{
 		MaxIdle:         param.MaxIdle,
 		MaxActive:       param.MaxActive,
 		Wait:            param.Wait,
 		MaxConnLifetime: time.Duration(param.MaxConnLifetime) * time.Minute,
+		IdleTimeout:     time.Duration(param.MaxIdleTimeOut) * time.Minute,
 		Dial: func() (redis.Conn, error) {
-			c, err := redis.Dial("tcp", fmt.Sprintf("%s:%s", param.Host, param.Port))
+			c, err := redis.Dial("tcp", fmt.Sprintf("%s:%s", param.Host, param.Port),
+				redis.DialConnectTimeout(param.Timeout),
+				redis.DialReadTimeout(param.Timeout),
+				redis.DialWriteTimeout(param.Timeout),
+			)
 			if err != nil {
 				return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 			}
 			if len(param.Password) > 0 {
 				if _, err := c.Do("AUTH", param.Password); err != nil {
+					c.Close()
 					return nil, fmt.Errorf("failed to authenticate with Redis: %w", err)
 				}
 			}
 			return c, nil
 		},
 	}
-	conn := redisPool.Get()
-	defer conn.Close()
-	_, err := conn.Do("PING")
-	if err != nil {
-		return nil, fmt.Errorf("failed to ping Redis: %w", err)
-	}
-
-	return redisPool, nil
 }
 
 func SetMaxIdle(conns int) redisOption {
@@ -102,3 +118,12 @@ func SetConnMaxLifetime(timeout int) redisOption {
 		}
 	}
 }
+
+// SetTimeout sets the connect, read and write timeout of each connection
+func SetTimeout(timeout time.Duration) redisOption {
+	return func(c *redisConfig) {
+		if timeout > 0 {
+			c.Timeout = timeout
+		}
+	}
+}
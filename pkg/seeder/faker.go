@@ -0,0 +1,75 @@
+package seeder
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Alice", "Amara", "Ben", "Carla", "Chen", "Dmitri", "Elena", "Farah",
+		"Grace", "Hiro", "Ines", "Jonas", "Kemal", "Lena", "Luis", "Maya", "Nadia", "Omar",
+		"Priya", "Quinn", "Rosa", "Sven", "Tariq", "Uma", "Victor", "Wen", "Yara", "Zoe",
+	}
+	lastNames = []string{
+		"Abe", "Baker", "Costa", "Dubois", "Eriksen", "Fischer", "Garcia", "Hassan", "Ivanova", "Jensen",
+		"Kowalski", "Lopez", "Mbeki", "Nakamura", "Okafor", "Petrov", "Quispe", "Rossi", "Singh", "Tanaka",
+	}
+)
+
+// Faker generates plausible fake values from a seeded random source, so the
+// same seed always yields the same sequence of values
+type Faker struct {
+	rand   *rand.Rand
+	emails int
+}
+
+// NewFaker creates a faker seeded with seed
+func NewFaker(seed uint64) *Faker {
+	return &Faker{rand: rand.New(rand.NewPCG(seed, 0x5eed))}
+}
+
+// IntN returns a number in [0, n)
+func (f *Faker) IntN(n int) int {
+	return f.rand.IntN(n)
+}
+
+// Pick returns one of values
+func (f *Faker) Pick(values ...string) string {
+	return values[f.rand.IntN(len(values))]
+}
+
+// FirstName returns a given name
+func (f *Faker) FirstName() string {
+	return f.Pick(firstNames...)
+}
+
+// LastName returns a family name
+func (f *Faker) LastName() string {
+	return f.Pick(lastNames...)
+}
+
+// Name returns a full name
+func (f *Faker) Name() string {
+	return f.FirstName() + " " + f.LastName()
+}
+
+// Email returns an address at example.com derived from name. Addresses are
+// numbered so a faker never returns the same one twice.
+func (f *Faker) Email(name string) string {
+	f.emails++
+	local := strings.ToLower(strings.Join(strings.Fields(name), "."))
+	return fmt.Sprintf("%s.%d@example.com", local, f.emails)
+}
+
+// UUID returns a random version 4 UUID drawn from the seeded source
+func (f *Faker) UUID() string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(f.rand.UintN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,154 @@
+// Package seeder runs registered seeders that fill a database with reference
+// data and, outside production, with deterministic fake data.
+package seeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Seeder fills part of the database. Seeders must be idempotent: running one
+// again keeps the rows it created before and only adds missing ones.
+type Seeder struct {
+	Name string
+	// Environments lists the environments, such as development, the seeder
+	// runs in. A seeder without any runs everywhere, which is meant for the
+	// reference data production needs.
+	Environments []string
+	// Tables are emptied before seeding in fresh mode
+	Tables []string
+	// Run seeds through repositories whose calls join the transaction carried
+	// by ctx. fake is seeded from the run's seed and the seeder name, so the
+	// data does not change when other seeders are added.
+	Run func(ctx context.Context, fake *Faker) error
+}
+
+// Options selects what a run seeds
+type Options struct {
+	Environment string
+	// Only restricts the run to the named seeders
+	Only []string
+	// Fresh empties the tables of the selected seeders before seeding
+	Fresh bool
+	// Seed makes fake data deterministic; runs with the same seed create the same data
+	Seed uint64
+}
+
+// Registry holds seeders in registration order, which is the order they run in
+type Registry struct {
+	seeders []Seeder
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds seeders that run after the ones registered before them. Names
+// must be unique; registering one twice is a programming error and panics.
+func (r *Registry) Register(seeders ...Seeder) {
+	for _, seeder := range seeders {
+		if r.find(seeder.Name) != nil {
+			panic(fmt.Sprintf("seeder: %q is registered twice", seeder.Name))
+		}
+		r.seeders = append(r.seeders, seeder)
+	}
+}
+
+// Names returns the names of the registered seeders in run order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.seeders))
+	for _, seeder := range r.seeders {
+		names = append(names, seeder.Name)
+	}
+	return names
+}
+
+// Run runs the seeders selected by opts, each in its own transaction on db,
+// and returns the names of those that ran
+func (r *Registry) Run(ctx context.Context, db *gorm.DB, opts Options) ([]string, error) {
+	selected, err := r.selected(opts)
+	if err != nil {
+		return nil, err
+	}
+	transactions := gormpkg.NewTransactionManager(db)
+
+	if opts.Fresh {
+		err := transactions.WithinTransaction(ctx, func(ctx context.Context) error {
+			// Later seeders may depend on the rows of earlier ones, so empty them first
+			for i := len(selected) - 1; i >= 0; i-- {
+				for _, table := range selected[i].Tables {
+					if err := gormpkg.Conn(ctx, db).Exec("DELETE FROM ?", clause.Table{Name: table}).Error; err != nil {
+						return fmt.Errorf("failed to empty %s: %w", table, err)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ran []string
+	for _, seeder := range selected {
+		fake := NewFaker(opts.Seed ^ nameHash(seeder.Name))
+		err := transactions.WithinTransaction(ctx, func(ctx context.Context) error {
+			return seeder.Run(ctx, fake)
+		})
+		if err != nil {
+			return ran, fmt.Errorf("seeder %s failed: %w", seeder.Name, err)
+		}
+		ran = append(ran, seeder.Name)
+	}
+	return ran, nil
+}
+
+// selected returns the seeders that match the environment and Only
+func (r *Registry) selected(opts Options) ([]Seeder, error) {
+	for _, name := range opts.Only {
+		if r.find(name) == nil {
+			return nil, fmt.Errorf("unknown seeder %q", name)
+		}
+	}
+
+	var selected []Seeder
+	for _, seeder := range r.seeders {
+		if len(opts.Only) > 0 && !slices.Contains(opts.Only, seeder.Name) {
+			continue
+		}
+		if len(seeder.Environments) > 0 && !slices.Contains(seeder.Environments, opts.Environment) {
+			continue
+		}
+		selected = append(selected, seeder)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no seeder matches the environment and selection")
+	}
+	return selected, nil
+}
+
+// find returns the seeder called name, or nil
+func (r *Registry) find(name string) *Seeder {
+	for i := range r.seeders {
+		if r.seeders[i].Name == name {
+			return &r.seeders[i]
+		}
+	}
+	return nil
+}
+
+// nameHash derives a per-seeder value to mix into the run's seed
+func nameHash(name string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return hash.Sum64()
+}
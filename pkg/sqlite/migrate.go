@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	nurl "net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"gorm.io/gorm"
+)
+
+// migrationsTable stores the schema version, named like the table of the other golang-migrate drivers
+const migrationsTable = "schema_migrations"
+
+// migrateDriver implements golang-migrate's database.Driver for the pure-Go
+// SQLite driver. golang-migrate's own sqlite driver links modernc.org/sqlite,
+// which registers the same database/sql driver name and cannot coexist with it.
+type migrateDriver struct {
+	db       *sql.DB
+	ownsDB   bool
+	isLocked atomic.Bool
+}
+
+// NewMigrate creates a golang-migrate instance that applies the migrations found
+// at sourceURL, e.g. file://database/migrations/sqlite, to db
+func NewMigrate(db *gorm.DB, sourceURL string) (*migrate.Migrate, error) {
+	driver, err := newMigrateDriver(db)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithDatabaseInstance(sourceURL, "sqlite", driver)
+}
+
+// NewMigrateFromSource creates a golang-migrate instance that applies the
+// migrations read from src, such as embedded ones, to db
+func NewMigrateFromSource(db *gorm.DB, src source.Driver) (*migrate.Migrate, error) {
+	driver, err := newMigrateDriver(db)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithInstance("source", src, "sqlite", driver)
+}
+
+// newMigrateDriver wraps the connection pool of db, which Close leaves open
+func newMigrateDriver(db *gorm.DB) (*migrateDriver, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	driver := &migrateDriver{db: sqlDB}
+	if err := driver.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// Migrate applies all pending migrations found at sourceURL to db
+func Migrate(db *gorm.DB, sourceURL string) error {
+	m, err := NewMigrate(db, sourceURL)
+	if err != nil {
+		return fmt.Errorf("failed to create migration instance: %w", err)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
+
+// Open opens a database from a sqlite://path URL
+func (d *migrateDriver) Open(url string) (database.Driver, error) {
+	parsed, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimPrefix(url, parsed.Scheme+"://")
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := &migrateDriver{db: db, ownsDB: true}
+	if err := driver.ensureVersionTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return driver, nil
+}
+
+// Close only closes connections opened by Open; a shared *gorm.DB stays open
+func (d *migrateDriver) Close() error {
+	if d.ownsDB {
+		return d.db.Close()
+	}
+	return nil
+}
+
+// Lock guards against concurrent migrations within the process. SQLite serialises
+// writers itself, so there is no database level lock to take.
+func (d *migrateDriver) Lock() error {
+	if !d.isLocked.CompareAndSwap(false, true) {
+		return database.ErrLocked
+	}
+	return nil
+}
+
+func (d *migrateDriver) Unlock() error {
+	if !d.isLocked.CompareAndSwap(true, false) {
+		return database.ErrNotLocked
+	}
+	return nil
+}
+
+// Run executes a migration script inside a transaction
+func (d *migrateDriver) Run(migration io.Reader) error {
+	script, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	return d.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(string(script)); err != nil {
+			return database.Error{OrigErr: err, Err: "migration failed", Query: script}
+		}
+		return nil
+	})
+}
+
+func (d *migrateDriver) SetVersion(version int, dirty bool) error {
+	return d.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM " + migrationsTable); err != nil {
+			return err
+		}
+
+		// Like the other drivers, a nil version is only recorded while dirty
+		if version >= 0 || (version == database.NilVersion && dirty) {
+			query := "INSERT INTO " + migrationsTable + " (version, dirty) VALUES (?, ?)"
+			if _, err := tx.Exec(query, version, dirty); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *migrateDriver) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+
+	err := d.db.QueryRow("SELECT version, dirty FROM "+migrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.NilVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
+
+// Drop removes every table, including the version table, and recreates the version table
+func (d *migrateDriver) Drop() error {
+	rows, err := d.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite!_%' ESCAPE '!'")
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := d.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %q", table)); err != nil {
+			return err
+		}
+	}
+	if _, err := d.db.Exec("VACUUM"); err != nil {
+		return err
+	}
+
+	return d.ensureVersionTable()
+}
+
+func (d *migrateDriver) ensureVersionTable() error {
+	_, err := d.db.Exec("CREATE TABLE IF NOT EXISTS " + migrationsTable + " (version INTEGER NOT NULL, dirty BOOLEAN NOT NULL)")
+	return err
+}
+
+func (d *migrateDriver) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	driverSqlite "github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// driverName is the database/sql driver registered by the pure-Go SQLite driver
+const driverName = driverSqlite.DriverName
+
+// InMemory is the path of a private in-memory database
+const InMemory = ":memory:"
+
+type sqlite struct {
+	DBPath        string
+	busyTimeoutMs int
+
+	printLog     bool
+	logLevel     logger.LogLevel
+	logThreshold time.Duration
+	logger       logger.Interface
+
+	maxIdleConnection             int
+	maxOpenConnection             int
+	connectionMaxLifetimeInSecond int
+	namingStrategy                schema.Namer
+}
+
+type sqliteOption func(*sqlite)
+
+// Connect opens the SQLite database file at DBPath, creating it if needed. It
+// uses a pure-Go driver, so no cgo toolchain or database server is required.
+func Connect(DBPath string, options ...sqliteOption) (*gorm.DB, error) {
+	db := &sqlite{
+		DBPath:        DBPath,
+		busyTimeoutMs: 5000,
+
+		printLog:     false,
+		logLevel:     logger.Silent,
+		logThreshold: 200 * time.Millisecond,
+
+		maxIdleConnection:             2,
+		maxOpenConnection:             4,
+		connectionMaxLifetimeInSecond: 0,
+		namingStrategy:                nil,
+	}
+
+	for _, o := range options {
+		o(db)
+	}
+
+	return connect(db)
+}
+
+func connect(param *sqlite) (*gorm.DB, error) {
+	// GORM Config
+	// TranslateError converts driver errors such as duplicate keys into gorm.ErrDuplicatedKey
+	cfg := &gorm.Config{TranslateError: true}
+	if param.logger != nil {
+		cfg.Logger = param.logger
+	} else if param.printLog {
+		cfg.Logger = logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold: param.logThreshold,
+			LogLevel:      param.logLevel,
+			Colorful:      true,
+		})
+	}
+	if param.namingStrategy != nil {
+		cfg.NamingStrategy = param.namingStrategy
+	}
+
+	// Open Database Connection
+	db, err := gorm.Open(driverSqlite.Open(dsn(param)), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Set Connection Pool Settings
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	// Every connection to :memory: opens a separate empty database
+	if param.DBPath == InMemory {
+		param.maxOpenConnection = 1
+		param.maxIdleConnection = 1
+		param.connectionMaxLifetimeInSecond = 0
+	}
+	sqlDB.SetMaxOpenConns(param.maxOpenConnection)
+	sqlDB.SetConnMaxLifetime(time.Duration(param.connectionMaxLifetimeInSecond) * time.Second)
+	sqlDB.SetMaxIdleConns(param.maxIdleConnection)
+
+	return db, nil
+}
+
+// dsn builds the connection string with the pragmas applied to every connection.
+// WAL lets readers proceed while a writer is active and busy_timeout makes
+// concurrent writers wait instead of failing with SQLITE_BUSY.
+func dsn(param *sqlite) string {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", param.busyTimeoutMs))
+	pragmas.Add("_pragma", "foreign_keys(1)")
+	if param.DBPath != InMemory {
+		pragmas.Add("_pragma", "journal_mode(WAL)")
+	}
+
+	separator := "?"
+	if strings.Contains(param.DBPath, "?") {
+		separator = "&"
+	}
+	return param.DBPath + separator + pragmas.Encode()
+}
+
+func SetMaxIdleConns(conns int) sqliteOption {
+	return func(c *sqlite) {
+		if conns > 0 {
+			c.maxIdleConnection = conns
+		}
+	}
+}
+
+func SetMaxOpenConns(conns int) sqliteOption {
+	return func(c *sqlite) {
+		if conns > 0 {
+			c.maxOpenConnection = conns
+		}
+	}
+}
+
+func SetConnMaxLifetime(seconds int) sqliteOption {
+	return func(c *sqlite) {
+		if seconds > 0 {
+			c.connectionMaxLifetimeInSecond = seconds
+		}
+	}
+}
+
+// SetBusyTimeout sets how long a connection waits for a lock held by another writer
+func SetBusyTimeout(timeout time.Duration) sqliteOption {
+	return func(c *sqlite) {
+		if timeout > 0 {
+			c.busyTimeoutMs = int(timeout / time.Millisecond)
+		}
+	}
+}
+
+func SetNamingStrategy(namingStrategy schema.Namer) sqliteOption {
+	return func(c *sqlite) {
+		c.namingStrategy = namingStrategy
+	}
+}
+
+// SetPrintLog level: 1=silent, 2=Error, 3=Warn, 4=Info. latencyThreshold: suggestion 200ms.
+func SetPrintLog(isEnable bool, level logger.LogLevel, latencyThreshold time.Duration) sqliteOption {
+	return func(c *sqlite) {
+		if latencyThreshold > 0 {
+			c.printLog = isEnable
+			c.logLevel = level
+			c.logThreshold = latencyThreshold
+		}
+	}
+}
+
+// SetLogger logs through l instead of the text logger of SetPrintLog
+func SetLogger(l logger.Interface) sqliteOption {
+	return func(c *sqlite) {
+		c.logger = l
+	}
+}
+
+// SetTablePrefix sets the table prefix for all tables
+func SetTablePrefix(prefix string) sqliteOption {
+	return func(c *sqlite) {
+		c.namingStrategy = schema.NamingStrategy{
+			TablePrefix: prefix,
+		}
+	}
+}
@@ -37,17 +37,196 @@ make proto-gen
 
 echo "Generated Go code from proto files"
 
+# Create domain model and repository port
+DOMAIN_FILE="internal/domain/${SERVICE_NAME}.go"
+cat > $DOMAIN_FILE << EOF
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	Err${SERVICE_NAME^}NotFound = errors.New("${SERVICE_NAME} not found")
+)
+
+// ${SERVICE_NAME^} represents a ${SERVICE_NAME} entity. The repository stores it
+// as a row model, so it stays free of GORM types.
+type ${SERVICE_NAME^} struct {
+	ID string \`json:"id"\`
+	// Add other fields here
+
+	// Version is incremented on every update and used for optimistic concurrency control
+	Version   int64      \`json:"version"\`
+	CreatedAt time.Time  \`json:"created_at"\`
+	UpdatedAt time.Time  \`json:"updated_at"\`
+	DeletedAt *time.Time \`json:"-"\`
+}
+
+// ${SERVICE_NAME^}Repository defines the interface for ${SERVICE_NAME} persistence
+type ${SERVICE_NAME^}Repository interface {
+	FindByID(ctx context.Context, id string) (*${SERVICE_NAME^}, error)
+	Store(ctx context.Context, ${SERVICE_NAME} *${SERVICE_NAME^}) error
+	// Update returns ErrVersionConflict when the ${SERVICE_NAME} was changed concurrently
+	Update(ctx context.Context, ${SERVICE_NAME} *${SERVICE_NAME^}) error
+	Delete(ctx context.Context, id string) error
+}
+EOF
+
+echo "Created domain file: $DOMAIN_FILE"
+
+# Create the row model mapping the domain type to its table
+ROW_FILE="internal/repository/${SERVICE_NAME}_row.go"
+cat > $ROW_FILE << EOF
+package repository
+
+import (
+	"time"
+
+	"app-hexagonal/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ${SERVICE_NAME}Row is the GORM model of the ${SERVICE_NAME}s table. It holds what
+// only the database needs, such as the soft delete marker, so
+// domain.${SERVICE_NAME^} stays free of GORM types.
+type ${SERVICE_NAME}Row struct {
+	ID string
+	// Add other columns here
+
+	Version   int64 \`gorm:"not null;default:1"\`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt \`gorm:"index"\`
+}
+
+func (${SERVICE_NAME}Row) TableName() string {
+	return "${SERVICE_NAME}s"
+}
+
+func new${SERVICE_NAME^}Row(${SERVICE_NAME} *domain.${SERVICE_NAME^}) *${SERVICE_NAME}Row {
+	row := &${SERVICE_NAME}Row{
+		ID:        ${SERVICE_NAME}.ID,
+		Version:   ${SERVICE_NAME}.Version,
+		CreatedAt: ${SERVICE_NAME}.CreatedAt,
+		UpdatedAt: ${SERVICE_NAME}.UpdatedAt,
+	}
+	if ${SERVICE_NAME}.DeletedAt != nil {
+		row.DeletedAt = gorm.DeletedAt{Time: *${SERVICE_NAME}.DeletedAt, Valid: true}
+	}
+	return row
+}
+
+func (row *${SERVICE_NAME}Row) toDomain() *domain.${SERVICE_NAME^} {
+	${SERVICE_NAME} := &domain.${SERVICE_NAME^}{
+		ID:        row.ID,
+		Version:   row.Version,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+	if row.DeletedAt.Valid {
+		deletedAt := row.DeletedAt.Time
+		${SERVICE_NAME}.DeletedAt = &deletedAt
+	}
+	return ${SERVICE_NAME}
+}
+EOF
+
+echo "Created row model file: $ROW_FILE"
+
+# Create repository storing the row model through the generic GORM repository
+REPOSITORY_FILE="internal/repository/${SERVICE_NAME}_repository.go"
+cat > $REPOSITORY_FILE << EOF
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"app-hexagonal/internal/domain"
+	gormpkg "app-hexagonal/pkg/gorm"
+
+	"gorm.io/gorm"
+)
+
+// ${SERVICE_NAME^}Repository stores ${SERVICE_NAME}s with GORM as ${SERVICE_NAME}Row. CRUD
+// comes from gormpkg.Repository; add custom queries as methods using rows.Query(ctx).
+type ${SERVICE_NAME^}Repository struct {
+	rows *gormpkg.Repository[${SERVICE_NAME}Row, string]
+}
+
+func New${SERVICE_NAME^}Repository(db *gorm.DB) *${SERVICE_NAME^}Repository {
+	return &${SERVICE_NAME^}Repository{
+		rows: gormpkg.NewRepository[${SERVICE_NAME}Row, string](db).TranslateErrors(translate${SERVICE_NAME^}Error),
+	}
+}
+
+func (r *${SERVICE_NAME^}Repository) FindByID(ctx context.Context, id string) (*domain.${SERVICE_NAME^}, error) {
+	row, err := r.rows.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain(), nil
+}
+
+// Store inserts the ${SERVICE_NAME} and fills in the columns set on insert
+func (r *${SERVICE_NAME^}Repository) Store(ctx context.Context, ${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
+	row := new${SERVICE_NAME^}Row(${SERVICE_NAME})
+	if err := r.rows.Store(ctx, row); err != nil {
+		return err
+	}
+	*${SERVICE_NAME} = *row.toDomain()
+	return nil
+}
+
+// Update writes the ${SERVICE_NAME} and, on success, fills in its new version
+func (r *${SERVICE_NAME^}Repository) Update(ctx context.Context, ${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
+	row := new${SERVICE_NAME^}Row(${SERVICE_NAME})
+	if err := r.rows.Update(ctx, row); err != nil {
+		return err
+	}
+	*${SERVICE_NAME} = *row.toDomain()
+	return nil
+}
+
+func (r *${SERVICE_NAME^}Repository) Delete(ctx context.Context, id string) error {
+	return r.rows.Delete(ctx, id)
+}
+
+// translate${SERVICE_NAME^}Error maps GORM errors to the domain errors promised by
+// domain.${SERVICE_NAME^}Repository
+func translate${SERVICE_NAME^}Error(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.Err${SERVICE_NAME^}NotFound
+	case errors.Is(err, gormpkg.ErrVersionConflict):
+		return domain.ErrVersionConflict
+	default:
+		return err
+	}
+}
+EOF
+
+echo "Created repository file: $REPOSITORY_FILE"
+
 # Create usecase interface and implementation
 USECASE_FILE="internal/usecase/${SERVICE_NAME}.go"
 cat > $USECASE_FILE << EOF
 package usecase
 
-import "app-hexagonal/internal/domain"
+import (
+	"context"
+
+	"app-hexagonal/internal/domain"
+)
 
 // ${SERVICE_NAME^}UsecaseInterface defines the interface for ${SERVICE_NAME} use cases
 type ${SERVICE_NAME^}UsecaseInterface interface {
-	Get${SERVICE_NAME^}ByID(id string) (*domain.${SERVICE_NAME^}, error)
-	Create${SERVICE_NAME^}(${SERVICE_NAME} *domain.${SERVICE_NAME^}) error
+	Get${SERVICE_NAME^}ByID(ctx context.Context, id string) (*domain.${SERVICE_NAME^}, error)
+	Create${SERVICE_NAME^}(ctx context.Context, ${SERVICE_NAME} *domain.${SERVICE_NAME^}) error
 }
 
 // ${SERVICE_NAME^}Usecase handles ${SERVICE_NAME} business logic
@@ -61,13 +240,13 @@ func New${SERVICE_NAME^}Usecase(repo domain.${SERVICE_NAME^}Repository) *${SERVI
 }
 
 // Get${SERVICE_NAME^}ByID retrieves a ${SERVICE_NAME} by ID
-func (uc *${SERVICE_NAME^}Usecase) Get${SERVICE_NAME^}ByID(id string) (*domain.${SERVICE_NAME^}, error) {
-	return uc.repo.FindByID(id)
+func (uc *${SERVICE_NAME^}Usecase) Get${SERVICE_NAME^}ByID(ctx context.Context, id string) (*domain.${SERVICE_NAME^}, error) {
+	return uc.repo.FindByID(ctx, id)
 }
 
 // Create${SERVICE_NAME^} creates a new ${SERVICE_NAME}
-func (uc *${SERVICE_NAME^}Usecase) Create${SERVICE_NAME^}(${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
-	return uc.repo.Store(${SERVICE_NAME})
+func (uc *${SERVICE_NAME^}Usecase) Create${SERVICE_NAME^}(ctx context.Context, ${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
+	return uc.repo.Store(ctx, ${SERVICE_NAME})
 }
 EOF
 
@@ -79,6 +258,8 @@ cat > $APPLICATION_FILE << EOF
 package application
 
 import (
+	"context"
+
 	"app-hexagonal/internal/domain"
 	"app-hexagonal/internal/usecase"
 )
@@ -96,13 +277,13 @@ func New${SERVICE_NAME^}Service(${SERVICE_NAME}Usecase usecase.${SERVICE_NAME^}U
 }
 
 // Get${SERVICE_NAME^}ByID retrieves a ${SERVICE_NAME} by their ID
-func (s *${SERVICE_NAME^}Service) Get${SERVICE_NAME^}ByID(id string) (*domain.${SERVICE_NAME^}, error) {
-	return s.${SERVICE_NAME}Usecase.Get${SERVICE_NAME^}ByID(id)
+func (s *${SERVICE_NAME^}Service) Get${SERVICE_NAME^}ByID(ctx context.Context, id string) (*domain.${SERVICE_NAME^}, error) {
+	return s.${SERVICE_NAME}Usecase.Get${SERVICE_NAME^}ByID(ctx, id)
 }
 
 // Create${SERVICE_NAME^} creates a new ${SERVICE_NAME}
-func (s *${SERVICE_NAME^}Service) Create${SERVICE_NAME^}(${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
-	return s.${SERVICE_NAME}Usecase.Create${SERVICE_NAME^}(${SERVICE_NAME})
+func (s *${SERVICE_NAME^}Service) Create${SERVICE_NAME^}(ctx context.Context, ${SERVICE_NAME} *domain.${SERVICE_NAME^}) error {
+	return s.${SERVICE_NAME}Usecase.Create${SERVICE_NAME^}(ctx, ${SERVICE_NAME})
 }
 EOF
 
@@ -143,7 +324,7 @@ func New${SERVICE_NAME^}ServiceServer(${SERVICE_NAME}Service *application.${SERV
 func (s *${SERVICE_NAME^}ServiceServer) Get${SERVICE_NAME^}(ctx context.Context, req *v1.Get${SERVICE_NAME^}Request) (*v1.Get${SERVICE_NAME^}Response, error) {
 	s.logger.Info("gRPC: Getting ${SERVICE_NAME} by ID", zap.String("${SERVICE_NAME}_id", req.GetId()))
 
-	${SERVICE_NAME}, err := s.${SERVICE_NAME}Service.Get${SERVICE_NAME^}ByID(req.GetId())
+	${SERVICE_NAME}, err := s.${SERVICE_NAME}Service.Get${SERVICE_NAME^}ByID(ctx, req.GetId())
 	if err != nil {
 		s.logger.Error("gRPC: Failed to get ${SERVICE_NAME}", zap.String("${SERVICE_NAME}_id", req.GetId()), zap.Error(err))
 		return &v1.Get${SERVICE_NAME^}Response{
@@ -180,7 +361,7 @@ func (s *${SERVICE_NAME^}ServiceServer) Create${SERVICE_NAME^}(ctx context.Conte
 	}
 
 	// Save ${SERVICE_NAME}
-	err := s.${SERVICE_NAME}Service.Create${SERVICE_NAME^}(${SERVICE_NAME})
+	err := s.${SERVICE_NAME}Service.Create${SERVICE_NAME^}(ctx, ${SERVICE_NAME})
 	if err != nil {
 		s.logger.Error("gRPC: Failed to create ${SERVICE_NAME}", zap.Error(err))
 		return &v1.Create${SERVICE_NAME^}Response{
@@ -212,7 +393,7 @@ echo "Created gRPC service file: $GRPC_FILE"
 echo "API creation completed successfully!"
 echo "Next steps:"
 echo "1. Update the proto file with your specific message definitions"
-echo "2. Update the domain model for ${SERVICE_NAME}"
-echo "3. Update the repository interface and implementation"
+echo "2. Add fields to the domain and row models for ${SERVICE_NAME} and a migration for its table"
+echo "3. Add custom queries to the repository; CRUD comes from gormpkg.Repository"
 echo "4. Register the service in the gRPC server"
 echo "5. Update the main function to create and pass the new services"
\ No newline at end of file
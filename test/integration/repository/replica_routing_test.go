@@ -0,0 +1,128 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+// openReplicated opens a primary and a replica as two separate SQLite files. As
+// nothing copies rows between them, a read shows which database served it.
+func openReplicated(t *testing.T, config gormpkg.ReplicaConfig) (*gorm.DB, *gorm.DB, *gormpkg.ReplicaResolver) {
+	dir := t.TempDir()
+
+	replica, err := sqlite.Connect(filepath.Join(dir, "replica.db"))
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(replica, sqliteMigrations))
+	replicaPool, err := replica.DB()
+	require.NoError(t, err)
+
+	primary, err := sqlite.Connect(filepath.Join(dir, "primary.db"))
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(primary, sqliteMigrations))
+
+	resolver := gormpkg.NewReplicaResolver(config, replicaPool)
+	require.NoError(t, primary.Use(resolver))
+	t.Cleanup(func() {
+		gormpkg.CloseReplicas(primary)
+		if sqlDB, err := primary.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return primary, replica, resolver
+}
+
+func TestReplicaRouting(t *testing.T) {
+	primary, replica, _ := openReplicated(t, gormpkg.ReplicaConfig{StickyWindow: time.Minute})
+	repo := repository.NewUserRepository(primary)
+	ctx := context.Background()
+
+	require.NoError(t, repository.NewUserRepository(replica).Store(ctx, &domain.User{ID: "r", Name: "Replica Only", Email: "replica@example.com"}))
+
+	t.Run("WritesGoToPrimaryAndReadsToReplica", func(t *testing.T) {
+		require.NoError(t, repo.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+
+		_, err := repo.FindByID(ctx, "1")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound, "the replica has not seen the write")
+
+		found, err := repo.FindByID(ctx, "r")
+		require.NoError(t, err)
+		assert.Equal(t, "Replica Only", found.Name)
+	})
+
+	t.Run("ReadYourWrites", func(t *testing.T) {
+		session := gormpkg.WithReadYourWrites(ctx)
+
+		// Before its first write the session still reads from the replica
+		_, err := repo.FindByID(session, "r")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Store(session, &domain.User{ID: "2", Name: "Bob", Email: "bob@example.com"}))
+		found, err := repo.FindByID(session, "2")
+		require.NoError(t, err)
+		assert.Equal(t, "Bob", found.Name)
+
+		// Other sessions are not affected by the write
+		_, err = repo.FindByID(gormpkg.WithReadYourWrites(ctx), "2")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("TransactionsUsePrimary", func(t *testing.T) {
+		err := gormpkg.NewTransactionManager(primary).WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repo.FindByID(ctx, "1")
+			return err
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("WithPrimary", func(t *testing.T) {
+		_, err := repo.FindByID(gormpkg.WithPrimary(ctx), "1")
+		assert.NoError(t, err)
+	})
+}
+
+func TestReplicaRouting_StickyWindowExpires(t *testing.T) {
+	primary, _, _ := openReplicated(t, gormpkg.ReplicaConfig{StickyWindow: 50 * time.Millisecond})
+	repo := repository.NewUserRepository(primary)
+	session := gormpkg.WithReadYourWrites(context.Background())
+
+	require.NoError(t, repo.Store(session, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	_, err := repo.FindByID(session, "1")
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = repo.FindByID(session, "1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestReplicaRouting_DropsFailedReplicas(t *testing.T) {
+	primary, replica, resolver := openReplicated(t, gormpkg.ReplicaConfig{HealthCheckInterval: 10 * time.Millisecond})
+	repo := repository.NewUserRepository(primary)
+	ctx := context.Background()
+	require.Equal(t, 1, resolver.HealthyReplicas())
+
+	require.NoError(t, repo.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+
+	replicaPool, err := replica.DB()
+	require.NoError(t, err)
+	require.NoError(t, replicaPool.Close())
+
+	require.Eventually(t, func() bool {
+		return resolver.HealthyReplicas() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// With no healthy replica, reads fall back to the primary
+	found, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", found.Name)
+}
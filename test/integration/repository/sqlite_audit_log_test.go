@@ -0,0 +1,196 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/encryption"
+	"app-hexagonal/internal/repository"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+// newAuditedDB returns a migrated SQLite database recording changes to users,
+// without the values of the redact columns
+func newAuditedDB(t *testing.T, redact ...string) *gorm.DB {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+	require.NoError(t, db.Use(gormpkg.NewAuditColumns(domain.PrincipalFromContext)))
+	require.NoError(t, db.Use(gormpkg.NewAuditLog(gormpkg.AuditLogConfig{
+		Principal:     domain.PrincipalFromContext,
+		RequestID:     domain.RequestIDFromContext,
+		RedactColumns: redact,
+	}).Register(repository.AuditedModels()...)))
+	return db
+}
+
+func TestSQLiteAuditLog_RecordsUserChanges(t *testing.T) {
+	db := newAuditedDB(t)
+	users := repository.NewUserRepository(db)
+	audit := repository.NewAuditRepository(db)
+
+	ctx := domain.WithRequestID(domain.WithPrincipal(context.Background(), "admin"), "req-1")
+	user := &domain.User{ID: "u1", Name: "Jane", Email: "jane@example.com", Password: "hash-1"}
+	require.NoError(t, users.Store(ctx, user))
+
+	ctx = domain.WithRequestID(domain.WithPrincipal(context.Background(), "editor"), "req-2")
+	user.Name, user.Password = "Jane Doe", "hash-2"
+	require.NoError(t, users.Update(ctx, user))
+
+	// A stale update changes nothing and records nothing
+	stale := *user
+	stale.Version = 1
+	stale.Name = "Stale"
+	require.ErrorIs(t, users.Update(ctx, &stale), domain.ErrVersionConflict)
+
+	require.NoError(t, users.Delete(ctx, user.ID))
+
+	entries, err := audit.EntityEntries(context.Background(), "users", "u1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	created, updated, deleted := entries[0], entries[1], entries[2]
+	assert.Equal(t, domain.AuditActionCreate, created.Action)
+	assert.Equal(t, "admin", created.ActorID)
+	assert.Equal(t, "req-1", created.RequestID)
+	assert.JSONEq(t, `"Jane"`, string(created.Changes["name"].New))
+	assert.Nil(t, created.Changes["name"].Old)
+	assert.Equal(t, domain.AuditChange{Redacted: true}, created.Changes["password"])
+	assert.NotContains(t, created.Changes, "updated_at")
+
+	assert.Equal(t, domain.AuditActionUpdate, updated.Action)
+	assert.Equal(t, "editor", updated.ActorID)
+	assert.Equal(t, "req-2", updated.RequestID)
+	assert.JSONEq(t, `"Jane"`, string(updated.Changes["name"].Old))
+	assert.JSONEq(t, `"Jane Doe"`, string(updated.Changes["name"].New))
+	assert.JSONEq(t, `1`, string(updated.Changes["version"].Old))
+	assert.JSONEq(t, `2`, string(updated.Changes["version"].New))
+	assert.Equal(t, domain.AuditChange{Redacted: true}, updated.Changes["password"])
+	assert.ElementsMatch(t, []string{"name", "version", "password"}, keys(updated.Changes))
+
+	assert.Equal(t, domain.AuditActionDelete, deleted.Action)
+	assert.Empty(t, deleted.Changes)
+
+	// Erasing keeps who changed which fields and when, but not the values
+	require.NoError(t, audit.EraseValues(context.Background(), "users", "u1"))
+	entries, err = audit.EntityEntries(context.Background(), "users", "u1")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		for field, change := range entry.Changes {
+			assert.Nil(t, change.Old, field)
+			assert.Nil(t, change.New, field)
+		}
+	}
+	assert.Equal(t, "editor", entries[1].ActorID)
+	assert.Contains(t, entries[1].Changes, "name")
+}
+
+func TestSQLiteAuditLog_RecordsEncryptedUsersWithoutValues(t *testing.T) {
+	db := newAuditedDB(t, repository.EncryptedUserColumns...)
+	keyManager, err := encryption.NewLocalKeyManager(encryption.KeyFile{
+		Current: "k1",
+		Keys:    map[string]string{"k1": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))},
+	})
+	require.NoError(t, err)
+	index, err := encryption.NewBlindIndex(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	users := repository.NewEncryptedUserRepository(repository.NewUserRepository(db), encryption.NewEnvelope(keyManager, encryption.EnvelopeOptions{}), index, 0)
+	audit := repository.NewAuditRepository(db)
+
+	ctx := context.Background()
+	user := &domain.User{ID: "u1", Name: "Jane", Email: "jane@example.com"}
+	require.NoError(t, users.Store(ctx, user))
+
+	// Unchanged names and emails keep their ciphertext and are not recorded
+	user.AvatarKey = "avatars/u1/a.png"
+	require.NoError(t, users.Update(ctx, user))
+
+	user.Name = "Jane Doe"
+	require.NoError(t, users.Update(ctx, user))
+
+	entries, err := audit.EntityEntries(ctx, "users", "u1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, domain.AuditChange{Redacted: true}, entries[0].Changes["name"])
+	assert.Equal(t, domain.AuditChange{Redacted: true}, entries[0].Changes["email"])
+	assert.ElementsMatch(t, []string{"avatar_key", "version"}, keys(entries[1].Changes))
+	assert.ElementsMatch(t, []string{"name", "version"}, keys(entries[2].Changes))
+	assert.Equal(t, domain.AuditChange{Redacted: true}, entries[2].Changes["name"])
+}
+
+func TestSQLiteAuditLog_RollsBackWithTheChange(t *testing.T) {
+	db := newAuditedDB(t)
+	users := repository.NewUserRepository(db)
+	audit := repository.NewAuditRepository(db)
+
+	failure := errors.New("abort")
+	err := gormpkg.NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, users.Store(ctx, &domain.User{ID: "u1", Name: "Jane", Email: "jane@example.com"}))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	list, err := audit.List(context.Background(), domain.AuditFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, list.Total)
+}
+
+func TestSQLiteAuditLog_ListFiltersByEntityActorAndTime(t *testing.T) {
+	db := newAuditedDB(t)
+	users := repository.NewUserRepository(db)
+	audit := repository.NewAuditRepository(db)
+
+	start := time.Now().Add(-time.Minute)
+	require.NoError(t, users.StoreBatch(domain.WithPrincipal(context.Background(), "importer"), []*domain.User{
+		{ID: "u1", Name: "Jane", Email: "jane@example.com"},
+		{ID: "u2", Name: "John", Email: "john@example.com"},
+	}))
+	require.NoError(t, users.Delete(domain.WithPrincipal(context.Background(), "admin"), "u2"))
+
+	for _, tc := range []struct {
+		name   string
+		filter domain.AuditFilter
+		want   []string
+	}{
+		{"All", domain.AuditFilter{}, []string{"delete u2", "create u2", "create u1"}},
+		{"Entity", domain.AuditFilter{EntityType: "users", EntityID: "u2"}, []string{"delete u2", "create u2"}},
+		{"Actor", domain.AuditFilter{ActorID: "importer"}, []string{"create u2", "create u1"}},
+		{"From", domain.AuditFilter{From: start}, []string{"delete u2", "create u2", "create u1"}},
+		{"To", domain.AuditFilter{To: start}, nil},
+		{"Page", domain.AuditFilter{Page: 2, PageSize: 2}, []string{"create u1"}},
+	} {
+		list, err := audit.List(context.Background(), tc.filter)
+		require.NoError(t, err, tc.name)
+		var got []string
+		for _, entry := range list.Entries {
+			got = append(got, string(entry.Action)+" "+entry.EntityID)
+		}
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+}
+
+func keys(changes map[string]domain.AuditChange) []string {
+	var names []string
+	for name := range changes {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestSQLiteOutboxCommitsWithTheChange(t *testing.T) {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+	outbox := repository.NewOutboxRepository(db)
+	uc := usecase.NewUserUsecase(users, usecase.EventOptions{
+		Transactor: gormpkg.NewTransactionManager(db),
+		Outbox:     outbox,
+	}, usecase.UserOptions{})
+
+	// The user is rolled back when the event cannot be stored
+	require.NoError(t, outbox.Add(ctx, domain.Event{ID: "taken", Type: domain.EventUserCreated, OccurredAt: time.Now().UTC()}))
+	err = gormpkg.NewTransactionManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := users.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+			return err
+		}
+		return outbox.Add(ctx, domain.Event{ID: "taken", Type: domain.EventUserCreated, OccurredAt: time.Now().UTC()})
+	})
+	require.Error(t, err)
+	_, err = users.FindByID(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	require.NoError(t, uc.CreateUser(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}))
+
+	now := time.Now().UTC()
+	claimed, err := outbox.Claim(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, domain.EventUserCreated, claimed[1].EventType)
+	assert.Equal(t, "1", claimed[1].AggregateID)
+	assert.Equal(t, 1, claimed[1].Attempts)
+
+	// Claimed messages are hidden from other relays until the lease runs out
+	again, err := outbox.Claim(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	require.NoError(t, outbox.MarkSent(ctx, claimed[1].ID, now))
+	require.NoError(t, outbox.MarkFailed(ctx, claimed[0].ID, "broker down", now))
+	again, err = outbox.Claim(ctx, now.Add(time.Second), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, "taken", again[0].ID)
+	assert.Equal(t, "broker down", again[0].LastError)
+	assert.Equal(t, 2, again[0].Attempts)
+
+	purged, err := outbox.PurgeSent(ctx, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestSQLitePrivacyRepository(t *testing.T) {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+
+	repo := repository.NewPrivacyRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	request := &domain.PrivacyRequest{
+		ID:           "req-1",
+		UserID:       "1",
+		Type:         domain.PrivacyRequestErasure,
+		Status:       domain.PrivacyRequestScheduled,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(time.Hour),
+	}
+	require.NoError(t, repo.StoreRequest(ctx, request))
+
+	open, err := repo.FindOpenErasure(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", open.ID)
+
+	due, err := repo.DueErasures(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = repo.DueErasures(ctx, now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	request.Status = domain.PrivacyRequestProcessing
+	require.NoError(t, repo.TransitionRequest(ctx, request, domain.PrivacyRequestScheduled))
+	assert.ErrorIs(t, repo.TransitionRequest(ctx, request, domain.PrivacyRequestScheduled), domain.ErrPrivacyRequestState)
+	_, err = repo.FindRequest(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrPrivacyRequestNotFound)
+
+	for i, id := range []string{"rec-1", "rec-2"} {
+		require.NoError(t, repo.AppendErasureRecord(ctx, &domain.ErasureRecord{
+			ID:           id,
+			RequestID:    "req-1",
+			UserID:       "1",
+			Contributors: []string{"avatar", "user"},
+			RequestedAt:  now,
+			ErasedAt:     now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	records, err := repo.ErasureRecords(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, records[0].Hash, records[1].PreviousHash)
+	assert.Equal(t, []string{"avatar", "user"}, records[1].Contributors)
+	require.NoError(t, domain.VerifyErasureRecords(records))
+
+	// The table refuses edits, so the chain can only grow
+	assert.Error(t, db.Exec("UPDATE erasure_records SET user_id = '2'").Error)
+	assert.Error(t, db.Exec("DELETE FROM erasure_records").Error)
+}
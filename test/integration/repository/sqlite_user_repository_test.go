@@ -0,0 +1,148 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/database/migrations"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/encryption"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/repository/repositorytest"
+	"app-hexagonal/pkg/cache"
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+// sqliteMigrations is relative to this package directory, where go test runs
+const sqliteMigrations = "file://../../../database/migrations/sqlite"
+
+func TestSQLiteUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+
+		require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+		require.NoError(t, db.Use(gormpkg.NewAuditColumns(domain.PrincipalFromContext)))
+		return repository.NewUserRepository(db)
+	})
+}
+
+func TestSQLiteEncryptedUserRepository(t *testing.T) {
+	keys, err := encryption.NewLocalKeyManager(encryption.KeyFile{
+		Current: "k1",
+		Keys:    map[string]string{"k1": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))},
+	})
+	require.NoError(t, err)
+	index, err := encryption.NewBlindIndex(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	envelope := encryption.NewEnvelope(keys, encryption.EnvelopeOptions{})
+
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+
+		require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+		require.NoError(t, db.Use(gormpkg.NewAuditColumns(domain.PrincipalFromContext)))
+		return repository.NewEncryptedUserRepository(repository.NewUserRepository(db), envelope, index, 0)
+	})
+}
+
+func TestSQLiteCachedUserRepository_BypassesCacheInTransaction(t *testing.T) {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, sqlite.Migrate(db, sqliteMigrations))
+
+	ctx := context.Background()
+	lru := cache.NewLRUCache(100)
+	rows := repository.NewUserRepository(db)
+	repo := repository.NewCachedUserRepository(rows, lru, time.Minute)
+	user := &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}
+	require.NoError(t, rows.Store(ctx, user))
+
+	// The write goes around the cache, so only the bypass keeps the
+	// uncommitted row out of it
+	rollback := errors.New("rollback")
+	err = gormpkg.NewTransactionManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+		user.Name = "Alicia"
+		require.NoError(t, rows.Update(ctx, user))
+
+		found, err := repo.FindByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "Alicia", found.Name, "the transaction reads its own write")
+		_, err = repo.FindByEmail(ctx, "alice@example.com")
+		require.NoError(t, err)
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+
+	_, err = lru.Get(ctx, "user:id:1")
+	assert.Error(t, err, "nothing read in the transaction was cached")
+	found, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", found.Name)
+}
+
+func TestSQLiteMigrationsRollBack(t *testing.T) {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+
+	m, err := sqlite.NewMigrate(db, sqliteMigrations)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Down())
+
+	require.False(t, db.Migrator().HasTable("users"))
+}
+
+func TestEmbeddedMigrationsMatchAcrossDialects(t *testing.T) {
+	versions := func(dialect string) []string {
+		fsys, err := migrations.FS(dialect)
+		require.NoError(t, err)
+		names, err := fs.Glob(fsys, "*.sql")
+		require.NoError(t, err)
+		return names
+	}
+
+	sqliteNames := versions("sqlite")
+	require.NotEmpty(t, sqliteNames)
+	for _, dialect := range migrations.Dialects {
+		require.Equal(t, sqliteNames, versions(dialect), dialect)
+	}
+
+	// The embedded SQLite migrations apply and roll back completely
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	source, err := migrations.Source("sqlite")
+	require.NoError(t, err)
+	m, err := sqlite.NewMigrateFromSource(db, source)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	require.True(t, db.Migrator().HasTable("outbox_messages"))
+	require.NoError(t, m.Down())
+	require.False(t, db.Migrator().HasTable("users"))
+}
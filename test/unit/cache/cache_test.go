@@ -0,0 +1,90 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/pkg/cache"
+	"app-hexagonal/pkg/redis"
+)
+
+func TestRedisCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	pool := redis.NewPool(server.Host(), server.Port(), "")
+	c := cache.NewRedisCache(pool, "app:")
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "key")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Minute))
+	assert.True(t, server.Exists("app:key"))
+
+	added, err := c.Add(ctx, "key", []byte("other"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+
+	server.FastForward(2 * time.Minute)
+	_, err = c.Get(ctx, "key")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.NewLRUCache(2)
+	ctx := context.Background()
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, err := c.Get(ctx, "b")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+	_, err = c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestFallbackCache_SurvivesRedisOutage(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	pool := redis.NewPool(server.Host(), server.Port(), "", redis.SetTimeout(100*time.Millisecond))
+
+	var failures int
+	c := cache.NewFallbackCache(cache.NewRedisCache(pool, ""), cache.NewLRUCache(10), 50*time.Millisecond, func(error) {
+		failures++
+	})
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "user:1", []byte("v1"), time.Minute))
+
+	server.Close()
+
+	// While Redis is down values are served from memory and invalidations are remembered
+	require.NoError(t, c.Set(ctx, "user:2", []byte("v2"), time.Minute))
+	value, err := c.Get(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(value))
+	require.NoError(t, c.Delete(ctx, "user:1"))
+	assert.Positive(t, failures)
+
+	// Redis comes back with the value that was invalidated during the outage
+	restarted := miniredis.NewMiniRedis()
+	require.NoError(t, restarted.StartAddr(addr))
+	t.Cleanup(restarted.Close)
+	restarted.Set("user:1", "v1")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Get(ctx, "user:1")
+	assert.ErrorIs(t, err, cache.ErrMiss, "invalidations from the outage must be replayed")
+	assert.False(t, restarted.Exists("user:1"))
+}
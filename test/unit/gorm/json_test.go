@@ -0,0 +1,65 @@
+package gorm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+type settings struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	value, err := gormpkg.NewJSON(settings{Theme: "dark", Tags: []string{"a"}}).Value()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"theme":"dark","tags":["a"]}`, value.(string))
+
+	for _, stored := range []interface{}{value, []byte(value.(string))} {
+		var decoded gormpkg.JSON[settings]
+		require.NoError(t, decoded.Scan(stored))
+		assert.Equal(t, settings{Theme: "dark", Tags: []string{"a"}}, decoded.Data)
+	}
+
+	decoded := gormpkg.NewJSON(settings{Theme: "light"})
+	require.NoError(t, decoded.Scan(nil))
+	assert.Equal(t, settings{}, decoded.Data)
+
+	assert.Error(t, decoded.Scan(42))
+	assert.Error(t, decoded.Scan("{not json"))
+}
+
+func TestJSON_EncodesAsBareDocument(t *testing.T) {
+	encoded, err := gormpkg.NewJSON(map[string]int{"a": 1}).MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(encoded))
+
+	var decoded gormpkg.JSON[map[string]int]
+	require.NoError(t, decoded.UnmarshalJSON([]byte(`{"b":2}`)))
+	assert.Equal(t, map[string]int{"b": 2}, decoded.Data)
+}
+
+func TestJSON_ColumnTypePerDialect(t *testing.T) {
+	sqliteDB, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		dialector gorm.Dialector
+		want      string
+	}{
+		{mysql.New(mysql.Config{}), "JSON"},
+		{postgres.New(postgres.Config{}), "JSONB"},
+		{sqliteDB.Dialector, "TEXT"},
+	} {
+		db := &gorm.DB{Config: &gorm.Config{Dialector: tc.dialector}}
+		assert.Equal(t, tc.want, gormpkg.JSON[settings]{}.GormDBDataType(db, nil), tc.dialector.Name())
+	}
+}
@@ -0,0 +1,86 @@
+package gorm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+type score struct {
+	ID     int `gorm:"primaryKey"`
+	Points int
+}
+
+func TestKeysetPagination_DescendingCompositeKeys(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	// Points repeat so the ID has to break ties
+	for id := 1; id <= 7; id++ {
+		require.NoError(t, db.Create(&score{ID: id, Points: id / 2}).Error)
+	}
+
+	keys := []gormpkg.SortKey{{Column: "points", Desc: true}, {Column: "id", Desc: true}}
+	page := func(after, before []interface{}) ([]score, *gormpkg.KeysetResult) {
+		var scores []score
+		result, err := gormpkg.KeysetPagination(db.Model(&score{}), gormpkg.Keyset{Keys: keys, After: after, Before: before, PageSize: 3}, &scores)
+		require.NoError(t, err)
+		return scores, result
+	}
+	keyOf := func(s score) []interface{} { return []interface{}{s.Points, s.ID} }
+	labels := func(scores []score) []string {
+		var got []string
+		for _, s := range scores {
+			got = append(got, fmt.Sprintf("%d:%d", s.Points, s.ID))
+		}
+		return got
+	}
+
+	first, result := page(nil, nil)
+	assert.Equal(t, []string{"3:7", "3:6", "2:5"}, labels(first))
+	assert.False(t, result.HasPrevious)
+	require.True(t, result.HasNext)
+
+	second, result := page(keyOf(first[len(first)-1]), nil)
+	assert.Equal(t, []string{"2:4", "1:3", "1:2"}, labels(second))
+	assert.True(t, result.HasPrevious)
+	require.True(t, result.HasNext)
+
+	last, result := page(keyOf(second[len(second)-1]), nil)
+	assert.Equal(t, []string{"0:1"}, labels(last))
+	assert.False(t, result.HasNext)
+	assert.True(t, result.HasPrevious)
+
+	back, result := page(nil, keyOf(last[0]))
+	assert.Equal(t, []string{"2:4", "1:3", "1:2"}, labels(back))
+	assert.True(t, result.HasNext)
+	assert.True(t, result.HasPrevious)
+
+	back, _ = page(nil, keyOf(back[0]))
+	assert.Equal(t, []string{"3:7", "3:6", "2:5"}, labels(back))
+}
+
+func TestKeysetPagination_RejectsMismatchedBoundaries(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+
+	byID := []gormpkg.SortKey{{Column: "id"}}
+	var scores []score
+	for _, tc := range []struct {
+		name   string
+		keyset gormpkg.Keyset
+	}{
+		{"NoKeys", gormpkg.Keyset{}},
+		{"BothSides", gormpkg.Keyset{Keys: byID, After: []interface{}{1}, Before: []interface{}{2}}},
+		{"WrongArity", gormpkg.Keyset{Keys: byID, After: []interface{}{1, 2}}},
+	} {
+		_, err := gormpkg.KeysetPagination(db.Model(&score{}), tc.keyset, &scores)
+		assert.Error(t, err, tc.name)
+	}
+}
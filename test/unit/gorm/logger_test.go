@@ -0,0 +1,171 @@
+package gorm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm/logger"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+type account struct {
+	ID       int `gorm:"primaryKey"`
+	Email    string
+	Password string
+	Plan     string
+}
+
+type requestIDKey struct{}
+
+func TestZapLogger_LogsQueriesWithContextAndRedactsSensitiveValues(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	queryLogger := gormpkg.NewZapLogger(zap.New(core), gormpkg.ZapLoggerConfig{
+		LogLevel:      logger.Info,
+		RedactColumns: []string{"email", "Password"},
+		ContextFields: func(ctx context.Context) []zap.Field {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return []zap.Field{zap.String("request_id", id)}
+		},
+		Metrics: gormpkg.NewQueryHistogram(),
+	})
+	db, err := sqlite.Connect(sqlite.InMemory, sqlite.SetLogger(queryLogger))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&account{}))
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	logs.TakeAll()
+	metrics := gormpkg.QueryMetrics(db)
+	require.NotNil(t, metrics)
+	selects := metrics.Count("select") // the migration looked the table up
+	require.NoError(t, db.WithContext(ctx).Create(&account{ID: 1, Email: "jane@example.com", Password: "s3cret", Plan: "pro"}).Error)
+	var found account
+	require.NoError(t, db.WithContext(ctx).Where("email = ? AND plan = ?", "jane@example.com", "pro").First(&found).Error)
+	require.NoError(t, db.WithContext(ctx).Model(&found).Updates(map[string]interface{}{"password": "n3w", "plan": "team"}).Error)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 3)
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		assert.Equal(t, "Database query", entry.Message)
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Contains(t, fields["source"], "logger_test.go")
+
+		sql := fields["sql"].(string)
+		for _, secret := range []string{"jane@example.com", "s3cret", "n3w"} {
+			assert.NotContains(t, sql, secret)
+		}
+		assert.Contains(t, sql, "[REDACTED]")
+	}
+	assert.Contains(t, entries[0].ContextMap()["sql"], `"pro"`)
+	assert.Contains(t, entries[1].ContextMap()["sql"], `plan = "pro"`)
+	assert.Contains(t, entries[2].ContextMap()["sql"], `"team"`)
+
+	assert.Equal(t, uint64(1), metrics.Count("insert"))
+	assert.Equal(t, selects+1, metrics.Count("select"))
+	assert.Equal(t, uint64(1), metrics.Count("update"))
+}
+
+func TestZapLogger_FlagsSlowQueriesAndErrors(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	db, err := sqlite.Connect(sqlite.InMemory, sqlite.SetLogger(gormpkg.NewZapLogger(zap.New(core), gormpkg.ZapLoggerConfig{
+		LogLevel:                  logger.Warn,
+		SlowThreshold:             1, // every statement takes longer than a nanosecond
+		IgnoreRecordNotFoundError: true,
+	})))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&account{}))
+	logs.TakeAll()
+
+	var found account
+	require.Error(t, db.First(&found, 1).Error)
+	require.Error(t, db.Exec("SELECT * FROM missing_table").Error)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level, "a missing record is not an error")
+	assert.Equal(t, "Slow database query", entries[0].Message)
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "Database query failed", entries[1].Message)
+	assert.Contains(t, entries[1].ContextMap()["error"], "missing_table")
+}
+
+func TestZapLogger_RedactsPlaceholders(t *testing.T) {
+	queryLogger := gormpkg.NewZapLogger(zap.NewNop(), gormpkg.ZapLoggerConfig{RedactColumns: []string{"email", "name"}})
+
+	for _, tc := range []struct {
+		sql    string
+		params []interface{}
+		want   []interface{}
+	}{
+		{
+			`INSERT INTO "users" ("id","name","email","version") VALUES ($1,$2,$3,$4),($5,$6,$7,$8) ON CONFLICT DO NOTHING`,
+			[]interface{}{"1", "Jane", "jane@example.com", 1, "2", "John", "john@example.com", 1},
+			[]interface{}{"1", "[REDACTED]", "[REDACTED]", 1, "2", "[REDACTED]", "[REDACTED]", 1},
+		},
+		{
+			`UPDATE "users" SET "name"=$1,"version"=$2 WHERE "users"."id" = $3 AND "email" IN ($4,$5)`,
+			[]interface{}{"Jane", 2, "1", "a@example.com", "b@example.com"},
+			[]interface{}{"[REDACTED]", 2, "1", "[REDACTED]", "[REDACTED]"},
+		},
+		{
+			`SELECT * FROM users WHERE (LOWER(name) LIKE ? ESCAPE '!' OR "email" LIKE ?) AND id > ? LIMIT ?`,
+			[]interface{}{"%jan%", "%jan%", "5", 10},
+			[]interface{}{"[REDACTED]", "[REDACTED]", "5", 10},
+		},
+		{
+			`SELECT * FROM users WHERE ? = LOWER(email) AND ? = id LIMIT ? OFFSET ?`,
+			[]interface{}{"jane@example.com", "1", 10, 20},
+			[]interface{}{"[REDACTED]", "[REDACTED]", 10, 20},
+		},
+		{
+			`SELECT * FROM users WHERE ? = id`,
+			[]interface{}{"1"},
+			[]interface{}{"1"},
+		},
+		{
+			`INSERT INTO users VALUES (?, ?)`,
+			[]interface{}{"1", "Jane"},
+			[]interface{}{"[REDACTED]", "[REDACTED]"},
+		},
+		{
+			`UPDATE users SET "email = ?`,
+			[]interface{}{"jane@example.com"},
+			[]interface{}{"[REDACTED]"},
+		},
+	} {
+		_, got := queryLogger.ParamsFilter(context.Background(), tc.sql, tc.params...)
+		assert.Equal(t, tc.want, got, tc.sql)
+	}
+}
+
+func TestQueryHistogram_WritesPrometheusText(t *testing.T) {
+	histogram := gormpkg.NewQueryHistogram(0.01, 0.1)
+	histogram.Observe("select", 5*time.Millisecond)
+	histogram.Observe("select", 50*time.Millisecond)
+	histogram.Observe("update", 500*time.Millisecond)
+
+	var out strings.Builder
+	require.NoError(t, histogram.WritePrometheus(&out, "db_query_duration_seconds"))
+	assert.Equal(t, `# HELP db_query_duration_seconds Duration of SQL statements in seconds.
+# TYPE db_query_duration_seconds histogram
+db_query_duration_seconds_bucket{operation="select",le="0.01"} 1
+db_query_duration_seconds_bucket{operation="select",le="0.1"} 2
+db_query_duration_seconds_bucket{operation="select",le="+Inf"} 2
+db_query_duration_seconds_sum{operation="select"} 0.055
+db_query_duration_seconds_count{operation="select"} 2
+db_query_duration_seconds_bucket{operation="update",le="0.01"} 0
+db_query_duration_seconds_bucket{operation="update",le="0.1"} 0
+db_query_duration_seconds_bucket{operation="update",le="+Inf"} 1
+db_query_duration_seconds_sum{operation="update"} 0.5
+db_query_duration_seconds_count{operation="update"} 1
+`, out.String())
+}
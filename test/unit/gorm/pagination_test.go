@@ -0,0 +1,58 @@
+package gorm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestOffsetPagination_RejectsUnsafeOrder(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	require.NoError(t, db.Create(&score{ID: 1, Points: 1}).Error)
+
+	for _, pagination := range []gormpkg.Pagination{
+		{OrderBy: "id; DROP TABLE scores"},
+		{OrderBy: "(SELECT 1)"},
+		{OrderBy: "points", Sort: "asc, id"},
+	} {
+		var scores []score
+		_, err := gormpkg.OffsetPagination(db.Model(&score{}), &pagination, &scores)
+		assert.ErrorIs(t, err, gormpkg.ErrInvalidOrder, pagination.OrderBy)
+	}
+
+	var scores []score
+	result, err := gormpkg.OffsetPagination(db.Model(&score{}), &gormpkg.Pagination{OrderBy: "scores.points", Sort: "DESC"}, &scores)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.TotalRecords)
+	assert.True(t, db.Migrator().HasTable(&score{}))
+}
+
+func TestPagination_CapsPageSize(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	scores := make([]score, gormpkg.MaxPageSize+1)
+	for i := range scores {
+		scores[i] = score{ID: i + 1}
+	}
+	require.NoError(t, db.CreateInBatches(&scores, 50).Error)
+
+	var page []score
+	result, err := gormpkg.OffsetPagination(db.Model(&score{}), &gormpkg.Pagination{PageSize: 1000}, &page)
+	require.NoError(t, err)
+	assert.Len(t, page, gormpkg.MaxPageSize)
+	assert.Equal(t, gormpkg.MaxPageSize, result.PageSize)
+	assert.True(t, result.HasNext)
+
+	page = nil
+	keyset, err := gormpkg.KeysetPagination(db.Model(&score{}), gormpkg.Keyset{Keys: []gormpkg.SortKey{{Column: "id"}}, PageSize: 1000}, &page)
+	require.NoError(t, err)
+	assert.Len(t, page, gormpkg.MaxPageSize)
+	assert.True(t, keyset.HasNext)
+}
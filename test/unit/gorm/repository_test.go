@@ -0,0 +1,152 @@
+package gorm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/queryspec"
+	"app-hexagonal/pkg/sqlite"
+)
+
+type product struct {
+	SKU       string `gorm:"primaryKey"`
+	Name      string
+	Stock     int
+	Version   uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+type tag struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func newProductRepository(t *testing.T) (*gorm.DB, *gormpkg.Repository[product, string]) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&product{}, &tag{}))
+	return db, gormpkg.NewRepository[product, string](db)
+}
+
+func TestRepository_UpdatesWithOptimisticLocking(t *testing.T) {
+	_, repo := newProductRepository(t)
+	ctx := context.Background()
+
+	p := &product{SKU: "a-1", Name: "Anvil", Stock: 3}
+	require.NoError(t, repo.Store(ctx, p))
+	assert.Equal(t, uint(1), p.Version)
+
+	stale := *p
+	p.Stock = 2
+	require.NoError(t, repo.Update(ctx, p))
+	assert.Equal(t, uint(2), p.Version)
+
+	stale.Stock = 9
+	assert.ErrorIs(t, repo.Update(ctx, &stale), gormpkg.ErrVersionConflict)
+	assert.Equal(t, uint(1), stale.Version, "a failed update leaves the entity as it was")
+
+	found, err := repo.FindByID(ctx, "a-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, found.Stock)
+	assert.Equal(t, p.CreatedAt.Unix(), found.CreatedAt.Unix())
+
+	missing := &product{SKU: "b-1", Version: 1}
+	assert.ErrorIs(t, repo.Update(ctx, missing), gorm.ErrRecordNotFound)
+}
+
+func TestRepository_UpdateUnchangedRowOnMySQL(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&tag{}))
+	repo := gormpkg.NewRepository[tag, uint](db)
+	ctx := context.Background()
+
+	// MySQL reports only the rows an update changed
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:changed_rows", func(db *gorm.DB) {
+		db.RowsAffected = 0
+	}))
+
+	existing := &tag{Name: "tools"}
+	require.NoError(t, repo.Store(ctx, existing))
+	assert.NoError(t, repo.Update(ctx, existing), "an unchanged row is still found")
+	assert.ErrorIs(t, repo.Update(ctx, &tag{ID: existing.ID + 1, Name: "toys"}), gorm.ErrRecordNotFound)
+}
+
+func TestRepository_SoftDeletesAndPurges(t *testing.T) {
+	db, repo := newProductRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.StoreBatch(ctx, []*product{{SKU: "a-1"}, {SKU: "a-2"}}))
+	require.NoError(t, repo.Delete(ctx, "a-1"))
+
+	found, err := repo.FindByID(ctx, "a-1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Nil(t, found)
+	assert.ErrorIs(t, repo.Delete(ctx, "a-1"), gorm.ErrRecordNotFound)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&product{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count, "soft deleted rows are kept")
+
+	require.NoError(t, repo.Purge(ctx, "a-1"))
+	require.NoError(t, db.Unscoped().Model(&product{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRepository_ListsBySpecScopesAndPage(t *testing.T) {
+	_, repo := newProductRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.StoreBatch(ctx, []*product{
+		{SKU: "a-3", Name: "Anvil", Stock: 1},
+		{SKU: "a-1", Name: "Axe", Stock: 5},
+		{SKU: "a-2", Name: "Awl", Stock: 5},
+		{SKU: "b-1", Name: "Bucket", Stock: 0},
+	}))
+
+	spec := queryspec.Spec{
+		Filters: []queryspec.Filter{{Column: "stock", Operator: queryspec.Gt, Value: 0}},
+		Sort:    []queryspec.Sort{{Column: "stock", Desc: true}},
+	}
+	notAnvils := func(db *gorm.DB) *gorm.DB { return db.Where("name <> ?", "Anvil") }
+
+	products, result, err := repo.List(ctx, spec, &gormpkg.Pagination{PageSize: 1}, notAnvils)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.TotalRecords)
+	require.Len(t, products, 1)
+	assert.Equal(t, "a-1", products[0].SKU, "ties are ordered by the primary key")
+
+	products, _, err = repo.List(ctx, queryspec.Spec{}, &gormpkg.Pagination{Page: 2, PageSize: 3})
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, "b-1", products[0].SKU)
+
+	// Without pagination the first page of the defaults is listed
+	products, result, err = repo.List(ctx, queryspec.Spec{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.TotalRecords)
+	assert.Len(t, products, 4)
+}
+
+func TestRepository_BindsPrimaryKeysAsValues(t *testing.T) {
+	db, _ := newProductRepository(t)
+	tags := gormpkg.NewRepository[tag, uint](db)
+	ctx := context.Background()
+
+	require.NoError(t, tags.Store(ctx, &tag{Name: "tools"}))
+	found, err := tags.FindByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "tools", found.Name)
+
+	products := gormpkg.NewRepository[product, string](db)
+	_, err = products.FindByID(ctx, "1 = 1 OR sku")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
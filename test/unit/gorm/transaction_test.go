@@ -0,0 +1,165 @@
+package gorm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	gormpkg "app-hexagonal/pkg/gorm"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestWithinTransaction_NestedCallsUseSavepoints(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	tm := gormpkg.NewTransactionManager(db)
+	ctx := context.Background()
+	failed := errors.New("failed")
+
+	err = tm.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, gormpkg.Conn(ctx, db).Create(&score{ID: 1}).Error)
+
+		// A failing nested unit only undoes its own work
+		err := tm.WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, gormpkg.Conn(ctx, db).Create(&score{ID: 2}).Error)
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		return tm.WithinTransaction(ctx, func(ctx context.Context) error {
+			return gormpkg.Conn(ctx, db).Create(&score{ID: 3}).Error
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, scoreIDs(t, db))
+
+	// A failing outer unit undoes the work of the nested ones
+	err = tm.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, tm.WithinTransaction(ctx, func(ctx context.Context) error {
+			return gormpkg.Conn(ctx, db).Create(&score{ID: 4}).Error
+		}))
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []int{1, 3}, scoreIDs(t, db))
+}
+
+func TestWithinTransaction_HonoursCancellation(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	tm := gormpkg.NewTransactionManager(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = tm.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, gormpkg.Conn(ctx, db).Create(&score{ID: 1}).Error)
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, scoreIDs(t, db))
+
+	err = tm.WithinTransaction(ctx, func(ctx context.Context) error {
+		t.Fatal("a unit of work must not start with a cancelled context")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWithinTransaction_InterruptsRunningStatement(t *testing.T) {
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	tm := gormpkg.NewTransactionManager(db)
+
+	// The insert would run for a long time; cancelling the context while it
+	// runs in a savepoint stops it and rolls the whole unit of work back
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := time.Now()
+	err = tm.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, gormpkg.Conn(ctx, db).Create(&score{ID: 1}).Error)
+		time.AfterFunc(50*time.Millisecond, cancel)
+		return tm.WithinTransaction(ctx, func(ctx context.Context) error {
+			return gormpkg.Conn(ctx, db).Exec(`INSERT INTO scores (id)
+				WITH RECURSIVE n(id) AS (SELECT 2 UNION ALL SELECT id + 1 FROM n WHERE id < 100000000)
+				SELECT id FROM n`).Error
+		})
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Empty(t, scoreIDs(t, db))
+}
+
+func scoreIDs(t *testing.T, db *gorm.DB) []int {
+	var ids []int
+	require.NoError(t, db.Model(&score{}).Order("id").Pluck("id", &ids).Error)
+	return ids
+}
+
+func TestIsRetryableError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want bool
+	}{
+		"MySQLDeadlock":           {&mysql.MySQLError{Number: 1213}, true},
+		"MySQLLockWaitTimeout":    {fmt.Errorf("update: %w", &mysql.MySQLError{Number: 1205}), true},
+		"MySQLDuplicateKey":       {&mysql.MySQLError{Number: 1062, Message: "Deadlock found when trying to get lock"}, false},
+		"PostgresSerialization":   {&pgconn.PgError{Code: "40001"}, true},
+		"PostgresDeadlock":        {&pgconn.PgError{Code: "40P01"}, true},
+		"PostgresUniqueViolation": {&pgconn.PgError{Code: "23505"}, false},
+		"ConnectionRefused":       {&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		"PlainText":               {errors.New("Deadlock found when trying to get lock"), false},
+	} {
+		assert.Equal(t, tc.want, gormpkg.IsRetryableError(tc.err), name)
+	}
+}
+
+func TestWithinTransactionOptions_RetriesAndCountsConflicts(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&score{}))
+	tm := gormpkg.NewTransactionManager(db)
+
+	var retries []int
+	opts := &gormpkg.TransactionOptions{
+		MaxRetries:     3,
+		IsolationLevel: sql.LevelSerializable,
+		OnRetry: func(attempt int, err error) {
+			retries = append(retries, attempt)
+		},
+	}
+	attempts := 0
+	err = tm.WithinTransactionOptions(context.Background(), opts, func(ctx context.Context) error {
+		attempts++
+		if err := gormpkg.Conn(ctx, db).Create(&score{ID: 1}).Error; err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &mysql.MySQLError{Number: 1213}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, retries)
+	assert.Equal(t, []int{1}, scoreIDs(t, db))
+
+	err = tm.WithinTransactionOptions(context.Background(), opts, func(ctx context.Context) error {
+		return &pgconn.PgError{Code: "40001"}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, gormpkg.TransactionStats{Transactions: 2, Retries: 5, Failures: 1}, tm.Stats())
+}
@@ -0,0 +1,45 @@
+package grpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "app-hexagonal/api/v1"
+)
+
+func TestServer_TakesTenantOnlyFromToken(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		tenant string
+		code   codes.Code
+	}{
+		{name: "no token or tenant", code: codes.OK},
+		{name: "tenant without token", tenant: "acme", code: codes.Unauthenticated},
+		{name: "token for the tenant", token: s.login(t, "acme"), tenant: "acme", code: codes.OK},
+		{name: "token for another tenant", token: s.login(t, "acme"), tenant: "globex", code: codes.PermissionDenied},
+		{name: "token without a tenant", token: s.login(t, ""), tenant: "acme", code: codes.PermissionDenied},
+	} {
+		_, err := s.users.GetUser(outgoing(tc.token, tc.tenant), &v1.GetUserRequest{Id: "u-1"})
+		assert.Equal(t, tc.code, status.Code(err), tc.name)
+	}
+}
+
+func TestServer_LoginTakesTenantFromMetadata(t *testing.T) {
+	s := newTestServer(t)
+
+	// Login is how a client without a token gets one for its tenant
+	resp, err := s.sessions.Login(outgoing("", "acme"), &v1.LoginRequest{Credentials: &v1.Credentials{Email: "ada@example.com", Password: "secret"}})
+	require.NoError(t, err)
+	require.False(t, resp.GetError(), resp.GetMessage())
+
+	claims, err := s.auth.ValidateToken(resp.GetData().GetAccessToken())
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.TenantID)
+}
@@ -0,0 +1,80 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "app-hexagonal/api/v1"
+	"app-hexagonal/internal/application"
+	delivery "app-hexagonal/internal/delivery/grpc"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+)
+
+// testServer serves the user and auth services over an in-memory connection
+type testServer struct {
+	users    v1.UserServiceClient
+	sessions v1.AuthServiceClient
+	auth     *application.AuthService
+	repo     domain.UserRepository
+}
+
+func newTestServer(t *testing.T) *testServer {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo := repository.NewMemoryUserRepository()
+	require.NoError(t, repo.Store(context.Background(), &domain.User{ID: "u-1", Name: "Ada", Email: "ada@example.com", Password: string(hashed)}))
+
+	userService := application.NewUserService(usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{CursorKey: []byte("secret")}))
+	authService := application.NewAuthService(usecase.NewAuthUsecase(repo, usecase.EventOptions{}))
+
+	lis := bufconn.Listen(1 << 20)
+	server := delivery.NewServer(zap.NewNop(), "")
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		_ = server.Serve(lis, userService, authService)
+	}()
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = lis.Close()
+		<-served
+	})
+
+	return &testServer{users: v1.NewUserServiceClient(conn), sessions: v1.NewAuthServiceClient(conn), auth: authService, repo: repo}
+}
+
+// login returns an access token for Ada issued for tenant
+func (s *testServer) login(t *testing.T, tenant string) string {
+	tokens, err := s.auth.Login(domain.WithTenant(context.Background(), tenant), &domain.Credentials{Email: "ada@example.com", Password: "secret"})
+	require.NoError(t, err)
+	return tokens.AccessToken
+}
+
+// outgoing returns a context sending the token and tenant, when set, as metadata
+func outgoing(token, tenant string) context.Context {
+	var pairs []string
+	if token != "" {
+		pairs = append(pairs, "authorization", "Bearer "+token)
+	}
+	if tenant != "" {
+		pairs = append(pairs, "x-tenant-id", tenant)
+	}
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(pairs...))
+}
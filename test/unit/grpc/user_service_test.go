@@ -0,0 +1,172 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "app-hexagonal/api/v1"
+	"app-hexagonal/internal/domain"
+)
+
+func TestUserService_RequiresPrincipal(t *testing.T) {
+	s := newTestServer(t)
+	ctx := outgoing("", "")
+
+	_, err := s.users.UpdateUser(ctx, &v1.UpdateUserRequest{Id: "u-1", Name: "Grace"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "UpdateUser")
+	_, err = s.users.DeleteUser(ctx, &v1.DeleteUserRequest{Id: "u-1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "DeleteUser")
+	_, err = s.users.ListUsers(ctx, &v1.ListUsersRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "ListUsers")
+
+	stream, err := s.users.WatchUsers(ctx, &v1.WatchUsersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "WatchUsers")
+
+	user, err := s.repo.FindByID(context.Background(), "u-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", user.Name, "the rejected calls changed nothing")
+}
+
+func TestUserService_GetAndCreateUser(t *testing.T) {
+	s := newTestServer(t)
+	ctx := outgoing("", "")
+
+	got, err := s.users.GetUser(ctx, &v1.GetUserRequest{Id: "u-1"})
+	require.NoError(t, err)
+	assert.False(t, got.GetError())
+	assert.Equal(t, "ada@example.com", got.GetData().GetEmail())
+
+	missing, err := s.users.GetUser(ctx, &v1.GetUserRequest{Id: "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.NotFound), missing.GetCode())
+
+	created, err := s.users.CreateUser(ctx, &v1.CreateUserRequest{Name: "Grace", Email: "grace@example.com"})
+	require.NoError(t, err)
+	assert.False(t, created.GetError(), created.GetMessage())
+	assert.NotEmpty(t, created.GetData().GetId())
+
+	duplicate, err := s.users.CreateUser(ctx, &v1.CreateUserRequest{Name: "Ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.AlreadyExists), duplicate.GetCode())
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	s := newTestServer(t)
+	ctx := outgoing(s.login(t, ""), "")
+
+	updated, err := s.users.UpdateUser(ctx, &v1.UpdateUserRequest{Id: "u-1", Name: "Grace", Version: 1})
+	require.NoError(t, err)
+	assert.False(t, updated.GetError(), updated.GetMessage())
+	assert.Equal(t, "Grace", updated.GetData().GetName())
+	assert.Equal(t, "ada@example.com", updated.GetData().GetEmail(), "unset fields are kept")
+	assert.Equal(t, int64(2), updated.GetData().GetVersion())
+	assert.Equal(t, "u-1", updated.GetData().GetUpdatedBy(), "the principal is recorded")
+
+	stale, err := s.users.UpdateUser(ctx, &v1.UpdateUserRequest{Id: "u-1", Name: "Ada", Version: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.Aborted), stale.GetCode())
+
+	missing, err := s.users.UpdateUser(ctx, &v1.UpdateUserRequest{Id: "missing", Name: "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.NotFound), missing.GetCode())
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	s := newTestServer(t)
+	ctx := outgoing(s.login(t, ""), "")
+
+	deleted, err := s.users.DeleteUser(ctx, &v1.DeleteUserRequest{Id: "u-1"})
+	require.NoError(t, err)
+	assert.False(t, deleted.GetError(), deleted.GetMessage())
+
+	_, err = s.repo.FindByID(context.Background(), "u-1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	again, err := s.users.DeleteUser(ctx, &v1.DeleteUserRequest{Id: "u-1"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.NotFound), again.GetCode())
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	s := newTestServer(t)
+	for _, id := range []string{"u-2", "u-3"} {
+		require.NoError(t, s.repo.Store(context.Background(), &domain.User{ID: id, Name: id, Email: id + "@example.com"}))
+	}
+	ctx := outgoing(s.login(t, ""), "")
+
+	page, err := s.users.ListUsers(ctx, &v1.ListUsersRequest{Page: 1, PageSize: 2})
+	require.NoError(t, err)
+	assert.False(t, page.GetError(), page.GetMessage())
+	assert.Len(t, page.GetData(), 2)
+	assert.Equal(t, int64(3), page.GetPage().GetTotalRecords())
+	assert.Equal(t, int32(2), page.GetPage().GetTotalPages())
+	assert.True(t, page.GetPage().GetHasNext())
+
+	cursor := ""
+	first, err := s.users.ListUsers(ctx, &v1.ListUsersRequest{PageSize: 2, Cursor: &cursor})
+	require.NoError(t, err)
+	assert.Len(t, first.GetData(), 2)
+	require.NotEmpty(t, first.GetPage().GetNextCursor())
+	next := first.GetPage().GetNextCursor()
+	second, err := s.users.ListUsers(ctx, &v1.ListUsersRequest{PageSize: 2, Cursor: &next})
+	require.NoError(t, err)
+	assert.Len(t, second.GetData(), 1)
+
+	invalid := "not-a-cursor"
+	bad, err := s.users.ListUsers(ctx, &v1.ListUsersRequest{PageSize: 2, Cursor: &invalid})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.InvalidArgument), bad.GetCode())
+
+	unsorted, err := s.users.ListUsers(ctx, &v1.ListUsersRequest{Sort: "password"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.InvalidArgument), unsorted.GetCode())
+}
+
+func TestUserService_WatchUsers(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login(t, "")
+
+	ctx, cancel := context.WithTimeout(outgoing(token, ""), 5*time.Second)
+	defer cancel()
+	stream, err := s.users.WatchUsers(ctx, &v1.WatchUsersRequest{})
+	require.NoError(t, err)
+
+	// The watcher is registered once the stream reaches the handler, so keep
+	// creating users until the first change arrives
+	changes := make(chan *v1.WatchUsersResponse)
+	go func() {
+		defer close(changes)
+		for {
+			change, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; ; i++ {
+		_, err := s.users.CreateUser(outgoing("", ""), &v1.CreateUserRequest{Name: "Grace", Email: "grace" + string(rune('a'+i)) + "@example.com"})
+		require.NoError(t, err)
+		select {
+		case change, ok := <-changes:
+			require.True(t, ok, "the stream ended early")
+			assert.Equal(t, v1.UserEventType_USER_EVENT_TYPE_CREATED, change.GetType())
+			assert.Equal(t, "Grace", change.GetData().GetName())
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+		require.Less(t, i, 20, "no change was streamed")
+	}
+}
@@ -0,0 +1,70 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"app-hexagonal/internal/delivery/http/middleware"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+)
+
+func TestAuthMiddleware_TakesTenantFromToken(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo := repository.NewMemoryUserRepository()
+	require.NoError(t, repo.Store(context.Background(), &domain.User{ID: "u-1", Name: "Ada", Email: "ada@example.com", Password: string(hashed)}))
+
+	auth := usecase.NewAuthUsecase(repo, usecase.EventOptions{})
+	tokens, err := auth.Login(domain.WithTenant(context.Background(), "acme"), &domain.Credentials{Email: "ada@example.com", Password: "secret"})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(middleware.RequestContextMiddleware())
+	app.Use(middleware.AuthMiddleware(zap.NewNop(), auth))
+	app.Get("/tenant", func(c *fiber.Ctx) error {
+		return c.SendString(domain.TenantFromContext(c.UserContext()))
+	})
+
+	for _, tc := range []struct {
+		header string
+		status int
+	}{
+		{header: "", status: fiber.StatusOK},
+		{header: "acme", status: fiber.StatusOK},
+		{header: "globex", status: fiber.StatusForbidden},
+	} {
+		req := httptest.NewRequest(fiber.MethodGet, "/tenant", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		if tc.header != "" {
+			req.Header.Set(middleware.TenantHeader, tc.header)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.header)
+		if tc.status == fiber.StatusOK {
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "acme", string(body), tc.header)
+		}
+	}
+
+	// A token issued without a tenant cannot be used for one
+	tokens, err = auth.Login(context.Background(), &domain.Credentials{Email: "ada@example.com", Password: "secret"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(fiber.MethodGet, "/tenant", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+	req.Header.Set(middleware.TenantHeader, "acme")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,133 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	handler "app-hexagonal/internal/delivery/http"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+)
+
+func newUserApp(t *testing.T) (*fiber.App, domain.UserRepository) {
+	repo := repository.NewMemoryUserRepository()
+	require.NoError(t, repo.Store(context.Background(), &domain.User{ID: "u-1", Name: "Ada", Email: "ada@example.com"}))
+
+	uc := usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{})
+	app := fiber.New()
+	handler.NewUserHandler(uc, zap.NewNop(), nil).RegisterRoutes(app)
+	return app, repo
+}
+
+func userRequest(method, ifMatch string) *http.Request {
+	req := httptest.NewRequest(method, "/users/u-1", strings.NewReader(`{"name":"Grace","email":"grace@example.com"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if ifMatch != "" {
+		req.Header.Set(fiber.HeaderIfMatch, ifMatch)
+	}
+	return req
+}
+
+func TestUserHandler_UpdateRequiresIfMatch(t *testing.T) {
+	app, _ := newUserApp(t)
+
+	resp, err := app.Test(userRequest(fiber.MethodPut, ""))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusPreconditionRequired, resp.StatusCode)
+}
+
+func TestUserHandler_UpdateRejectsStaleETag(t *testing.T) {
+	app, repo := newUserApp(t)
+
+	for _, ifMatch := range []string{`"2"`, `W/"1"`, `1`} {
+		resp, err := app.Test(userRequest(fiber.MethodPatch, ifMatch))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode, ifMatch)
+		assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag), "the current tag is returned")
+	}
+
+	user, err := repo.FindByID(context.Background(), "u-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", user.Name)
+}
+
+func TestUserHandler_UpdateWithCurrentETag(t *testing.T) {
+	app, _ := newUserApp(t)
+
+	resp, err := app.Test(userRequest(fiber.MethodPut, `"1"`))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get(fiber.HeaderETag))
+
+	resp, err = app.Test(userRequest(fiber.MethodPut, `"1"`))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode, "the old tag no longer matches")
+}
+
+func TestUserHandler_GetHonoursIfNoneMatch(t *testing.T) {
+	app, _ := newUserApp(t)
+
+	for _, tc := range []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{ifNoneMatch: `"1"`, status: fiber.StatusNotModified},
+		{ifNoneMatch: `W/"1"`, status: fiber.StatusNotModified},
+		{ifNoneMatch: `*`, status: fiber.StatusNotModified},
+		{ifNoneMatch: `"0", W/"1"`, status: fiber.StatusNotModified},
+		{ifNoneMatch: `"a,b" ,"1"`, status: fiber.StatusNotModified},
+		{ifNoneMatch: `"0"`, status: fiber.StatusOK},
+		{ifNoneMatch: `"0", W/"2"`, status: fiber.StatusOK},
+		{ifNoneMatch: `1`, status: fiber.StatusOK},
+		{ifNoneMatch: `"1`, status: fiber.StatusOK},
+	} {
+		req := httptest.NewRequest(fiber.MethodGet, "/users/u-1", nil)
+		req.Header.Set(fiber.HeaderIfNoneMatch, tc.ifNoneMatch)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.ifNoneMatch)
+		assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag), tc.ifNoneMatch)
+	}
+}
+
+func TestUserHandler_UpdateMatchesETagList(t *testing.T) {
+	for _, ifMatch := range []string{`"0", "1"`, `W/"1", "1"`, `*`} {
+		app, _ := newUserApp(t)
+
+		resp, err := app.Test(userRequest(fiber.MethodPatch, ifMatch))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode, ifMatch)
+	}
+}
+
+func TestUserHandler_CreateStoresUser(t *testing.T) {
+	app, repo := newUserApp(t)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/users", strings.NewReader(`{"name":"Grace","email":"grace@example.com"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	location := resp.Header.Get(fiber.HeaderLocation)
+	require.True(t, strings.HasPrefix(location, "/users/"), location)
+	user, err := repo.FindByID(context.Background(), strings.TrimPrefix(location, "/users/"))
+	require.NoError(t, err)
+	assert.Equal(t, "Grace", user.Name)
+	assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag))
+
+	req = httptest.NewRequest(fiber.MethodPost, "/users", strings.NewReader(`{"name":"Ada","email":"ada@example.com"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode, "the email is taken")
+}
@@ -0,0 +1,92 @@
+package http_test
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	handler "app-hexagonal/internal/delivery/http"
+	"app-hexagonal/internal/delivery/http/middleware"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+)
+
+func TestUserImportHandler_StreamsBodiesPastTheLimit(t *testing.T) {
+	const limit = 1024
+	app := fiber.New(fiber.Config{BodyLimit: limit, StreamRequestBody: true})
+	app.Use(middleware.BodyLimitMiddleware(limit, handler.UserImportPath))
+
+	repo := repository.NewMemoryUserRepository()
+	importUsecase := usecase.NewUserImportUsecase(repo, validator.New(), 10)
+	handler.NewUserImportHandler(importUsecase, zap.NewNop(), 0).RegisterRoutes(app)
+	app.Post("/echo", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	var csv strings.Builder
+	csv.WriteString("name,email\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&csv, "User %d,user%d@example.com\n", i, i)
+	}
+	require.Greater(t, csv.Len(), limit)
+
+	req := httptest.NewRequest(fiber.MethodPost, handler.UserImportPath, strings.NewReader(csv.String()))
+	req.Header.Set(fiber.HeaderContentType, "text/csv")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	list, err := repo.List(req.Context(), domain.UserFilter{PageSize: 200})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), list.Total)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/echo", strings.NewReader(csv.String())))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode, "other routes keep the limit")
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/echo", strings.NewReader("small")))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
+
+func TestUserImportHandler_RejectsUploadsOverTheImportLimit(t *testing.T) {
+	const limit = 2048
+	app := fiber.New(fiber.Config{StreamRequestBody: true})
+
+	repo := repository.NewMemoryUserRepository()
+	importUsecase := usecase.NewUserImportUsecase(repo, validator.New(), 10)
+	handler.NewUserImportHandler(importUsecase, zap.NewNop(), limit).RegisterRoutes(app)
+
+	var csv strings.Builder
+	csv.WriteString("name,email\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&csv, "User %d,user%d@example.com\n", i, i)
+	}
+	require.Greater(t, csv.Len(), limit)
+
+	// A declared length over the limit is rejected before the body is read
+	req := httptest.NewRequest(fiber.MethodPost, handler.UserImportPath, strings.NewReader(csv.String()))
+	req.Header.Set(fiber.HeaderContentType, "text/csv")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// A body of unknown length is cut off while it is spooled
+	req = httptest.NewRequest(fiber.MethodPost, handler.UserImportPath, io.MultiReader(strings.NewReader(csv.String())))
+	req.Header.Set(fiber.HeaderContentType, "text/csv")
+	req.TransferEncoding = []string{"chunked"}
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	list, err := repo.List(req.Context(), domain.UserFilter{PageSize: 200})
+	require.NoError(t, err)
+	assert.Zero(t, list.Total)
+}
@@ -0,0 +1,254 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"app-hexagonal/pkg/postgres"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestTenancyConfig_SchemaNamesTenantSchemas(t *testing.T) {
+	schema, err := postgres.TenancyConfig{}.Schema("acme_42")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_acme_42", schema)
+
+	schema, err = postgres.TenancyConfig{SchemaPrefix: "t_"}.Schema("acme")
+	require.NoError(t, err)
+	assert.Equal(t, "t_acme", schema)
+}
+
+func TestTenancyConfig_SchemaRejectsUnsafeTenantIDs(t *testing.T) {
+	for _, tenant := range []string{
+		"",
+		"Acme",
+		"acme-corp",
+		`acme"; DROP SCHEMA public CASCADE; --`,
+		"acme.public",
+		strings.Repeat("a", 64-len("tenant_")),
+	} {
+		_, err := postgres.TenancyConfig{}.Schema(tenant)
+		assert.ErrorIs(t, err, postgres.ErrInvalidTenant, tenant)
+	}
+}
+
+// tenantKey carries the tenant of a test statement
+type tenantKey struct{}
+
+func withTenant(tenant string) context.Context {
+	return context.WithValue(context.Background(), tenantKey{}, tenant)
+}
+
+func tenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// fakeSchemas keeps every schema in a SQLite file of its own
+type fakeSchemas struct {
+	t   *testing.T
+	dir string
+
+	mu       sync.Mutex
+	schemas  map[string]bool
+	created  int
+	migrated int
+	opened   []*sql.DB
+}
+
+func (f *fakeSchemas) connect(schema string) *sql.DB {
+	db, err := sqlite.Connect(filepath.Join(f.dir, schema+".db"))
+	require.NoError(f.t, err)
+	sqlDB, err := db.DB()
+	require.NoError(f.t, err)
+	return sqlDB
+}
+
+func (f *fakeSchemas) Exists(ctx context.Context, schema string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.schemas[schema], nil
+}
+
+func (f *fakeSchemas) Create(ctx context.Context, schema string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.schemas[schema] {
+		f.created++
+	}
+	f.schemas[schema] = true
+	return nil
+}
+
+func (f *fakeSchemas) Migrate(ctx context.Context, tenant, schema string, up bool) postgres.TenantStatus {
+	db := f.connect(schema)
+	defer db.Close()
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS items (name TEXT)")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.migrated++
+	return postgres.TenantStatus{Tenant: tenant, Schema: schema, Version: 1, Err: err}
+}
+
+func (f *fakeSchemas) List(ctx context.Context, prefix string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var schemas []string
+	for schema := range f.schemas {
+		if strings.HasPrefix(schema, prefix) {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas, nil
+}
+
+func (f *fakeSchemas) Open(schema string) (*sql.DB, error) {
+	db := f.connect(schema)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opened = append(f.opened, db)
+	return db, nil
+}
+
+// items returns the names stored in a schema
+func (f *fakeSchemas) items(schema string) []string {
+	db := f.connect(schema)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name FROM items")
+	require.NoError(f.t, err)
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(f.t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newTenantDB returns a database keeping the given tenants apart, whose
+// shared schema lives in shared.db
+func newTenantDB(t *testing.T, config postgres.TenancyConfig, tenants ...string) (*gorm.DB, *postgres.SchemaPerTenant, *fakeSchemas) {
+	schemas := &fakeSchemas{t: t, dir: t.TempDir(), schemas: make(map[string]bool)}
+	for _, tenant := range tenants {
+		schema, err := config.Schema(tenant)
+		require.NoError(t, err)
+		require.NoError(t, schemas.Create(context.Background(), schema))
+		require.NoError(t, schemas.Migrate(context.Background(), tenant, schema, true).Err)
+	}
+	schemas.created, schemas.migrated = 0, 0
+
+	db, err := sqlite.Connect(filepath.Join(schemas.dir, "shared.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE items (name TEXT)").Error)
+
+	config.Tenant = tenantOf
+	tenancy := postgres.NewSchemaPerTenant(config, schemas)
+	require.NoError(t, db.Use(tenancy))
+	t.Cleanup(func() { postgres.CloseTenancy(db) })
+	return db, tenancy, schemas
+}
+
+func insertItem(ctx context.Context, db *gorm.DB, name string) error {
+	return db.WithContext(ctx).Exec("INSERT INTO items (name) VALUES (?)", name).Error
+}
+
+func TestSchemaPerTenant_RunsStatementsInTheTenantSchema(t *testing.T) {
+	db, _, schemas := newTenantDB(t, postgres.TenancyConfig{}, "acme", "globex")
+
+	require.NoError(t, insertItem(withTenant("acme"), db, "anvil"))
+	require.NoError(t, insertItem(withTenant("globex"), db, "globe"))
+	require.NoError(t, insertItem(context.Background(), db, "shared"))
+	err := db.WithContext(withTenant("acme")).Transaction(func(tx *gorm.DB) error {
+		return insertItem(tx.Statement.Context, tx, "axe")
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"anvil", "axe"}, schemas.items("tenant_acme"))
+	assert.Equal(t, []string{"globe"}, schemas.items("tenant_globex"))
+	assert.Equal(t, []string{"shared"}, schemas.items("shared"))
+
+	var count int64
+	require.NoError(t, db.WithContext(withTenant("acme")).Table("items").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	assert.Len(t, schemas.opened, 2, "each tenant pool is opened once")
+
+	assert.ErrorIs(t, insertItem(withTenant("initech"), db, "x"), postgres.ErrUnknownTenant)
+	assert.ErrorIs(t, insertItem(withTenant("Acme"), db, "x"), postgres.ErrInvalidTenant)
+	assert.Zero(t, schemas.created, "unknown tenants are not provisioned")
+}
+
+func TestSchemaPerTenant_RequireTenantRefusesSharedStatements(t *testing.T) {
+	db, _, _ := newTenantDB(t, postgres.TenancyConfig{RequireTenant: true}, "acme")
+
+	assert.ErrorIs(t, insertItem(context.Background(), db, "shared"), postgres.ErrNoTenant)
+	var count int64
+	assert.ErrorIs(t, db.Table("items").Count(&count).Error, postgres.ErrNoTenant)
+	assert.NoError(t, insertItem(withTenant("acme"), db, "anvil"))
+}
+
+func TestSchemaPerTenant_ProvisionsTenantsOnce(t *testing.T) {
+	db, tenancy, schemas := newTenantDB(t, postgres.TenancyConfig{AutoProvision: true}, "acme")
+
+	// Concurrent first statements of a new tenant provision it once
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var count int64
+			errs[i] = db.WithContext(withTenant("initech")).Table("items").Count(&count).Error
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, schemas.created)
+	assert.Equal(t, 1, schemas.migrated)
+	assert.Len(t, schemas.opened, 1)
+
+	// Provisioning again only migrates
+	require.NoError(t, insertItem(withTenant("initech"), db, "stapler"))
+	status, err := tenancy.Provision(context.Background(), "initech")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_initech", status.Schema)
+	assert.Equal(t, 1, schemas.created)
+	assert.Equal(t, []string{"stapler"}, schemas.items("tenant_initech"))
+
+	tenants, err := tenancy.Tenants(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "initech"}, tenants)
+}
+
+func TestSchemaPerTenant_ClosesIdlePools(t *testing.T) {
+	db, _, schemas := newTenantDB(t, postgres.TenancyConfig{PoolIdleTimeout: 20 * time.Millisecond}, "acme")
+
+	require.NoError(t, insertItem(withTenant("acme"), db, "anvil"))
+	require.Len(t, schemas.opened, 1)
+	first := schemas.opened[0]
+	assert.Equal(t, 1, first.Stats().Idle, "one idle connection is kept by default")
+
+	assert.Eventually(t, func() bool {
+		return first.Ping() != nil
+	}, time.Second, 5*time.Millisecond, "the unused pool is closed")
+
+	// The next statement opens the pool again
+	require.NoError(t, insertItem(withTenant("acme"), db, "axe"))
+	assert.Len(t, schemas.opened, 2)
+	assert.Equal(t, []string{"anvil", "axe"}, schemas.items("tenant_acme"))
+}
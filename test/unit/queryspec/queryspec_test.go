@@ -0,0 +1,77 @@
+package queryspec_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/pkg/queryspec"
+)
+
+var schema = queryspec.NewSchema(
+	queryspec.Field{Name: "name", Column: "full_name", Type: queryspec.String, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Eq, queryspec.Contains}},
+	queryspec.Field{Name: "created_at", Column: "created_at", Type: queryspec.Time, Sortable: true,
+		Operators: []queryspec.Operator{queryspec.Gte}},
+	queryspec.Field{Name: "secret", Column: "secret", Type: queryspec.String},
+)
+
+func TestParseFilterKey(t *testing.T) {
+	for key, want := range map[string][2]string{
+		"filter[email][contains]": {"email", "contains"},
+		"filter[email]":           {"email", "eq"},
+	} {
+		field, operator, ok := queryspec.ParseFilterKey(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, [2]string{field, operator}, key)
+	}
+
+	for _, key := range []string{"sort", "filter[]", "filter[email", "filter[email]contains", "filter[email][contains"} {
+		_, _, ok := queryspec.ParseFilterKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestSchema_Parse(t *testing.T) {
+	spec, err := schema.Parse("-created_at, name", []queryspec.RawFilter{
+		{Field: "name", Operator: "contains", Value: "ali"},
+		{Field: "created_at", Operator: "gte", Value: "2024-01-02T03:04:05Z"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []queryspec.Sort{
+		{Field: "created_at", Column: "created_at", Desc: true},
+		{Field: "name", Column: "full_name"},
+	}, spec.Sort)
+	require.Len(t, spec.Filters, 2)
+	assert.Equal(t, "full_name", spec.Filters[0].Column)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), spec.Filters[1].Value)
+
+	assert.True(t, spec.Matches(func(field string) interface{} {
+		if field == "name" {
+			return "Alice"
+		}
+		return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	}))
+}
+
+func TestSchema_ParseRejectsFieldsOutsideTheWhitelist(t *testing.T) {
+	for name, tc := range map[string]struct {
+		sort    string
+		filters []queryspec.RawFilter
+	}{
+		"UnknownSort":       {sort: "password"},
+		"UnsortableField":   {sort: "secret"},
+		"InjectedSort":      {sort: "name desc; DROP TABLE users"},
+		"DuplicateSort":     {sort: "name,-name"},
+		"UnknownFilter":     {filters: []queryspec.RawFilter{{Field: "password", Operator: "eq"}}},
+		"UnfilterableField": {filters: []queryspec.RawFilter{{Field: "secret", Operator: "eq"}}},
+		"UnknownOperator":   {filters: []queryspec.RawFilter{{Field: "name", Operator: "regex"}}},
+		"InvalidValue":      {filters: []queryspec.RawFilter{{Field: "created_at", Operator: "gte", Value: "yesterday"}}},
+	} {
+		_, err := schema.Parse(tc.sort, tc.filters)
+		assert.ErrorIs(t, err, queryspec.ErrInvalid, name)
+	}
+}
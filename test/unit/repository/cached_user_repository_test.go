@@ -0,0 +1,145 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/repository/repositorytest"
+	"app-hexagonal/pkg/cache"
+)
+
+// countingRepository counts and slows down lookups reaching the wrapped repository
+type countingRepository struct {
+	domain.UserRepository
+	lookups atomic.Int32
+	delay   time.Duration
+}
+
+func (r *countingRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	r.lookups.Add(1)
+	time.Sleep(r.delay)
+	return r.UserRepository.FindByID(ctx, id)
+}
+
+func (r *countingRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.lookups.Add(1)
+	time.Sleep(r.delay)
+	return r.UserRepository.FindByEmail(ctx, email)
+}
+
+func TestCachedUserRepository_Conformance(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		return repository.NewCachedUserRepository(repository.NewMemoryUserRepository(), cache.NewLRUCache(100), time.Minute)
+	})
+}
+
+func TestCachedUserRepository_CoalescesMisses(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{UserRepository: repository.NewMemoryUserRepository(), delay: 20 * time.Millisecond}
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	repo := repository.NewCachedUserRepository(inner, cache.NewLRUCache(100), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := repo.FindByID(ctx, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, "Alice", user.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), inner.lookups.Load())
+
+	// Email lookups read the wrapped repository for the password hash, but
+	// concurrent ones still share a single read
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.FindByEmail(ctx, "alice@example.com")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), inner.lookups.Load())
+}
+
+func TestCachedUserRepository_InvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{UserRepository: repository.NewMemoryUserRepository()}
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	repo := repository.NewCachedUserRepository(inner, cache.NewLRUCache(100), time.Minute)
+
+	user, err := repo.FindByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+
+	user.Email = "alice@example.org"
+	require.NoError(t, repo.Update(ctx, user))
+
+	found, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.org", found.Email)
+
+	// The stale email index entry must not resolve to the renamed user
+	_, err = repo.FindByEmail(ctx, "alice@example.com")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	require.NoError(t, repo.Delete(ctx, "1"))
+	_, err = repo.FindByID(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestCachedUserRepository_KeepsTenantsApart(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{UserRepository: repository.NewMemoryUserRepository()}
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	repo := repository.NewCachedUserRepository(inner, cache.NewLRUCache(100), time.Minute)
+
+	_, err := repo.FindByID(domain.WithTenant(ctx, "acme"), "1")
+	require.NoError(t, err)
+	_, err = repo.FindByID(domain.WithTenant(ctx, "globex"), "1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.lookups.Load(), "a user cached for one tenant is not served to another")
+
+	_, err = repo.FindByID(domain.WithTenant(ctx, "acme"), "1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.lookups.Load())
+}
+
+func TestCachedUserRepository_KeepsPasswordOutOfCache(t *testing.T) {
+	ctx := context.Background()
+	inner := repository.NewMemoryUserRepository()
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "$2a$hash"}))
+	lru := cache.NewLRUCache(100)
+	repo := repository.NewCachedUserRepository(inner, lru, time.Minute)
+
+	user, err := repo.FindByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "$2a$hash", user.Password, "authentication reads the hash")
+
+	value, err := lru.Get(ctx, "user:id:1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(value), "$2a$hash")
+
+	cached, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Empty(t, cached.Password)
+
+	// Updating a user read from the cache keeps its hash
+	cached.Name = "Alicia"
+	require.NoError(t, repo.Update(ctx, cached))
+	stored, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alicia", stored.Name)
+	assert.Equal(t, "$2a$hash", stored.Password)
+}
@@ -0,0 +1,166 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/encryption"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/repository/repositorytest"
+	"app-hexagonal/pkg/queryspec"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func newTestEnvelope(t *testing.T, file encryption.KeyFile) *encryption.Envelope {
+	keys, err := encryption.NewLocalKeyManager(file)
+	require.NoError(t, err)
+	return encryption.NewEnvelope(keys, encryption.EnvelopeOptions{})
+}
+
+func newTestBlindIndex(t *testing.T) *encryption.BlindIndex {
+	index, err := encryption.NewBlindIndex(bytes.Repeat([]byte{9}, 32))
+	require.NoError(t, err)
+	return index
+}
+
+func TestEncryptedUserRepository_Conformance(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		envelope := newTestEnvelope(t, encryption.KeyFile{Current: "k1", Keys: map[string]string{"k1": testKey(1)}})
+		return repository.NewEncryptedUserRepository(repository.NewMemoryUserRepository(), envelope, newTestBlindIndex(t), 0)
+	})
+}
+
+func TestEncryptedUserRepository_StoresCiphertext(t *testing.T) {
+	ctx := context.Background()
+	inner := repository.NewMemoryUserRepository()
+	envelope := newTestEnvelope(t, encryption.KeyFile{Current: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	repo := repository.NewEncryptedUserRepository(inner, envelope, newTestBlindIndex(t), 0)
+
+	require.NoError(t, repo.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}))
+
+	stored, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(stored.Name))
+	assert.True(t, encryption.IsEncrypted(stored.EncryptedEmail))
+	assert.NotContains(t, stored.Email, "alice")
+
+	// Lookups by email ignore case, like the MySQL collation
+	found, err := repo.FindByEmail(ctx, "Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", found.Name)
+	assert.Equal(t, "alice@example.com", found.Email)
+	assert.Empty(t, found.EncryptedEmail)
+
+	// A ciphertext copied to another user does not decrypt
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "2", Name: stored.Name, Email: "copy", EncryptedEmail: stored.EncryptedEmail}))
+	_, err = repo.FindByID(ctx, "2")
+	assert.ErrorIs(t, err, domain.ErrDecryptionFailed)
+}
+
+func TestEncryptedUserRepository_Reencrypt(t *testing.T) {
+	ctx := context.Background()
+	inner := repository.NewMemoryUserRepository()
+	index := newTestBlindIndex(t)
+
+	// A user stored before encryption was enabled
+	require.NoError(t, inner.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}))
+
+	oldKeys := encryption.KeyFile{Current: "k1", Keys: map[string]string{"k1": testKey(1)}}
+	repo := repository.NewEncryptedUserRepository(inner, newTestEnvelope(t, oldKeys), index, 0)
+	require.NoError(t, repo.Store(ctx, &domain.User{ID: "2", Name: "Bob", Email: "bob@example.com", Password: "secret"}))
+
+	found, err := repo.FindByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", found.Name)
+
+	rewritten, err := repo.Reencrypt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rewritten)
+
+	stored, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(stored.Name))
+
+	// Rotating the key rewrites every user under the new key
+	newKeys := encryption.KeyFile{Current: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}}
+	rotated := repository.NewEncryptedUserRepository(inner, newTestEnvelope(t, newKeys), index, 0)
+	rewritten, err = rotated.Reencrypt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rewritten)
+
+	rewritten, err = rotated.Reencrypt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, rewritten)
+
+	// The retired key is no longer needed
+	current := repository.NewEncryptedUserRepository(inner, newTestEnvelope(t, encryption.KeyFile{Current: "k2", Keys: map[string]string{"k2": testKey(2)}}), index, 0)
+	list, err := current.List(ctx, domain.UserFilter{Search: "bob"})
+	require.NoError(t, err)
+	require.Len(t, list.Users, 1)
+	assert.Equal(t, "bob@example.com", list.Users[0].Email)
+	assert.Equal(t, int64(2), list.Users[0].Version)
+}
+
+func TestEncryptedUserRepository_CapsSortedSearch(t *testing.T) {
+	ctx := context.Background()
+	envelope := newTestEnvelope(t, encryption.KeyFile{Current: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	repo := repository.NewEncryptedUserRepository(repository.NewMemoryUserRepository(), envelope, newTestBlindIndex(t), 2)
+
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, repo.Store(ctx, &domain.User{ID: id, Name: "User " + id, Email: id + "@example.com"}))
+	}
+
+	sorted := domain.UserFilter{Search: "user", Query: queryspec.Spec{Sort: []queryspec.Sort{{Field: "name", Column: "name", Desc: true}}}}
+	_, err := repo.List(ctx, sorted)
+	assert.ErrorIs(t, err, queryspec.ErrInvalid)
+
+	// Unsorted pages only keep the page in memory
+	list, err := repo.List(ctx, domain.UserFilter{Search: "user", PageSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), list.Total)
+
+	sorted.Search = "user 3"
+	list, err = repo.List(ctx, sorted)
+	require.NoError(t, err)
+	require.Len(t, list.Users, 1)
+	assert.Equal(t, "3", list.Users[0].ID)
+}
+
+func TestEncryptedUserRepository_KeepsUnchangedCiphertext(t *testing.T) {
+	ctx := context.Background()
+	inner := repository.NewMemoryUserRepository()
+	envelope := newTestEnvelope(t, encryption.KeyFile{Current: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	repo := repository.NewEncryptedUserRepository(inner, envelope, newTestBlindIndex(t), 0)
+
+	user := &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}
+	require.NoError(t, repo.Store(ctx, user))
+	before, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+
+	user.AvatarKey = "avatars/1/a.png"
+	require.NoError(t, repo.Update(ctx, user))
+	after, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, before.Name, after.Name)
+	assert.Equal(t, before.EncryptedEmail, after.EncryptedEmail)
+
+	user.Name = "Alice Smith"
+	require.NoError(t, repo.Update(ctx, user))
+	renamed, err := inner.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.NotEqual(t, before.Name, renamed.Name)
+	assert.Equal(t, before.EncryptedEmail, renamed.EncryptedEmail)
+
+	found, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", found.Name)
+}
@@ -0,0 +1,15 @@
+package repository_test
+
+import (
+	"testing"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/repository/repositorytest"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		return repository.NewMemoryUserRepository()
+	})
+}
@@ -0,0 +1,113 @@
+package seeder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/database/migrations"
+	"app-hexagonal/database/seed"
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/pkg/seeder"
+	"app-hexagonal/pkg/sqlite"
+)
+
+func TestFaker_IsDeterministic(t *testing.T) {
+	a, b := seeder.NewFaker(42), seeder.NewFaker(42)
+	for i := 0; i < 10; i++ {
+		name := a.Name()
+		assert.Equal(t, name, b.Name())
+		assert.Equal(t, a.Email(name), b.Email(name))
+		assert.Equal(t, a.UUID(), b.UUID())
+	}
+	assert.NotEqual(t, seeder.NewFaker(1).UUID(), seeder.NewFaker(2).UUID())
+}
+
+func TestRegistry_SeedsUsersByEnvironment(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	source, err := migrations.Source("sqlite")
+	require.NoError(t, err)
+	m, err := sqlite.NewMigrateFromSource(db, source)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+	registry := seed.NewRegistry(users, seed.Options{AdminEmail: "admin@example.com", AdminPassword: "secret", DemoUsers: 5})
+	total := func() int64 {
+		list, err := users.List(ctx, domain.UserFilter{})
+		require.NoError(t, err)
+		return list.Total
+	}
+
+	// Production only gets reference data
+	ran, err := registry.Run(ctx, db, seeder.Options{Environment: "production", Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin-user"}, ran)
+	assert.Equal(t, int64(1), total())
+
+	ran, err = registry.Run(ctx, db, seeder.Options{Environment: "development", Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin-user", "demo-users"}, ran)
+	assert.Equal(t, int64(6), total())
+	first, err := users.List(ctx, domain.UserFilter{PageSize: 10})
+	require.NoError(t, err)
+
+	// Seeders are idempotent
+	_, err = registry.Run(ctx, db, seeder.Options{Environment: "development", Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), total())
+
+	// A fresh run empties the tables and seeds the same demo users again
+	_, err = registry.Run(ctx, db, seeder.Options{Environment: "development", Seed: 1, Fresh: true, Only: []string{"demo-users"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total())
+	for _, user := range first.Users {
+		if user.Email == "admin@example.com" {
+			continue
+		}
+		_, err := users.FindByID(ctx, user.ID)
+		assert.NoError(t, err, user.Email)
+	}
+
+	_, err = registry.Run(ctx, db, seeder.Options{Environment: "development", Only: []string{"roles"}})
+	assert.Error(t, err)
+}
+
+func TestRegistry_KeepsSoftDeletedAdmin(t *testing.T) {
+	db, err := sqlite.Connect(sqlite.InMemory)
+	require.NoError(t, err)
+	source, err := migrations.Source("sqlite")
+	require.NoError(t, err)
+	m, err := sqlite.NewMigrateFromSource(db, source)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+	registry := seed.NewRegistry(users, seed.Options{AdminEmail: "admin@example.com", AdminPassword: "secret"})
+	options := seeder.Options{Environment: "production", Only: []string{"admin-user"}}
+
+	_, err = registry.Run(ctx, db, options)
+	require.NoError(t, err)
+	admin, err := users.FindByEmail(ctx, "admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, users.Delete(ctx, admin.ID))
+
+	// The deleted administrator still holds its email, so a rerun leaves it be
+	_, err = registry.Run(ctx, db, options)
+	require.NoError(t, err)
+	_, err = users.FindByEmail(ctx, "admin@example.com")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	// Once purged, it is seeded again
+	require.NoError(t, users.Purge(ctx, admin.ID))
+	_, err = registry.Run(ctx, db, options)
+	require.NoError(t, err)
+	_, err = users.FindByEmail(ctx, "admin@example.com")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,116 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/storage"
+)
+
+// fakeS3 is a minimal path-style S3-compatible server in the spirit of MinIO
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	presigned := r.URL.Query().Get("X-Amz-Signature") != ""
+	if !presigned && !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.objects[r.URL.Path] = body
+		s.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s.types[r.URL.Path])
+		w.Write(body)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	s3, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "avatars",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+	}, server.Client())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s3.Put(ctx, "users/1/avatar.png", strings.NewReader("image"), 5, "image/png"))
+
+	reader, info, err := s3.Get(ctx, "users/1/avatar.png")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "image", string(body))
+	assert.Equal(t, "image/png", info.ContentType)
+
+	signed, err := s3.SignedURL(ctx, "users/1/avatar.png", time.Minute)
+	require.NoError(t, err)
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/avatars/users/1/avatar.png", parsed.Path)
+	assert.Equal(t, "60", parsed.Query().Get("X-Amz-Expires"))
+	assert.NotEmpty(t, parsed.Query().Get("X-Amz-Signature"))
+
+	require.NoError(t, s3.Delete(ctx, "users/1/avatar.png"))
+	_, _, err = s3.Get(ctx, "users/1/avatar.png")
+	assert.ErrorIs(t, err, domain.ErrObjectNotFound)
+}
+
+func TestLocalStorage_SignedURL(t *testing.T) {
+	local, err := storage.NewLocalStorage(t.TempDir(), "http://localhost/files", []byte("key"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, local.Put(ctx, "avatars/a.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"))
+
+	signed, err := local.SignedURL(ctx, "avatars/a.jpg", time.Minute)
+	require.NoError(t, err)
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+
+	query := parsed.Query()
+	assert.NoError(t, local.Verify("avatars/a.jpg", query.Get("expires"), query.Get("signature")))
+	assert.ErrorIs(t, local.Verify("avatars/b.jpg", query.Get("expires"), query.Get("signature")), storage.ErrInvalidSignature)
+
+	err = local.Put(ctx, "../escape", strings.NewReader("x"), 1, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublisher records published messages and fails while err is set
+type fakePublisher struct {
+	published []domain.OutboxMessage
+	err       error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, message domain.OutboxMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, message)
+	return nil
+}
+
+func TestUserUsecase_RecordsEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := repository.NewMemoryOutboxRepository()
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), usecase.EventOptions{
+		Transactor: repository.MemoryTransactor{},
+		Outbox:     outbox,
+	}, usecase.UserOptions{})
+
+	user := &domain.User{Name: "Alice", Email: "alice@example.com", Password: "secret"}
+	require.NoError(t, uc.CreateUser(ctx, user))
+	require.NoError(t, uc.UpdateUser(ctx, &domain.User{ID: user.ID, Name: "Alice B", Email: user.Email, Version: 1}))
+	require.NoError(t, uc.DeleteUser(ctx, user.ID))
+
+	// A failed change records nothing
+	err := uc.CreateUser(ctx, &domain.User{Name: "Other", Email: "alice@example.com"})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEmail)
+
+	messages := outbox.Messages()
+	require.Len(t, messages, 3)
+	assert.Equal(t, domain.EventUserCreated, messages[0].EventType)
+	assert.Equal(t, domain.EventUserUpdated, messages[1].EventType)
+	assert.Equal(t, domain.EventUserDeleted, messages[2].EventType)
+
+	var event struct {
+		ID          string               `json:"id"`
+		AggregateID string               `json:"aggregate_id"`
+		Data        domain.UserEventData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(messages[1].Payload), &event))
+	assert.Equal(t, messages[1].ID, event.ID)
+	assert.Equal(t, user.ID, event.AggregateID)
+	assert.Equal(t, int64(2), event.Data.Version)
+	assert.NotContains(t, messages[0].Payload, "alice@example.com")
+}
+
+func TestOutboxRelay_RelayPending(t *testing.T) {
+	ctx := context.Background()
+	outbox := repository.NewMemoryOutboxRepository()
+	require.NoError(t, outbox.Add(ctx,
+		domain.Event{ID: "e1", Type: domain.EventUserCreated, AggregateID: "1", OccurredAt: time.Now().UTC().Add(-time.Second)},
+		domain.Event{ID: "e2", Type: domain.EventUserUpdated, AggregateID: "1", OccurredAt: time.Now().UTC()},
+	))
+
+	publisher := &fakePublisher{err: errors.New("broker down")}
+	relay := usecase.NewOutboxRelay(outbox, publisher, usecase.RelayOptions{MinBackoff: time.Hour})
+
+	sent, err := relay.RelayPending(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+
+	messages := outbox.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, 1, messages[0].Attempts)
+	assert.Equal(t, "broker down", messages[0].LastError)
+	assert.True(t, messages[0].NextAttemptAt.After(time.Now().Add(50*time.Minute)))
+
+	// Nothing is due until the backoff has passed
+	publisher.err = nil
+	sent, err = relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	for _, message := range messages {
+		require.NoError(t, outbox.MarkFailed(ctx, message.ID, message.LastError, time.Now().UTC()))
+	}
+	sent, err = relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	require.Len(t, publisher.published, 2)
+	assert.Equal(t, "e1", publisher.published[0].ID)
+	assert.Equal(t, 2, publisher.published[0].Attempts)
+
+	for _, message := range outbox.Messages() {
+		assert.NotNil(t, message.SentAt)
+	}
+	purged, err := outbox.PurgeSent(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+}
+
+// tenantOutbox keeps an outbox per tenant, like a schema per tenant database
+type tenantOutbox map[string]*repository.MemoryOutboxRepository
+
+func (o tenantOutbox) of(ctx context.Context) (*repository.MemoryOutboxRepository, error) {
+	outbox, ok := o[domain.TenantFromContext(ctx)]
+	if !ok {
+		return nil, errors.New("unknown tenant")
+	}
+	return outbox, nil
+}
+
+func (o tenantOutbox) Add(ctx context.Context, events ...domain.Event) error {
+	outbox, err := o.of(ctx)
+	if err != nil {
+		return err
+	}
+	return outbox.Add(ctx, events...)
+}
+
+func (o tenantOutbox) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	outbox, err := o.of(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return outbox.Claim(ctx, now, limit, lease)
+}
+
+func (o tenantOutbox) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	outbox, err := o.of(ctx)
+	if err != nil {
+		return err
+	}
+	return outbox.MarkSent(ctx, id, sentAt)
+}
+
+func (o tenantOutbox) MarkFailed(ctx context.Context, id string, lastError string, retryAt time.Time) error {
+	outbox, err := o.of(ctx)
+	if err != nil {
+		return err
+	}
+	return outbox.MarkFailed(ctx, id, lastError, retryAt)
+}
+
+func (o tenantOutbox) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	outbox, err := o.of(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return outbox.PurgeSent(ctx, before)
+}
+
+// tenantPublisher records the tenant each message was published for
+type tenantPublisher map[string]string
+
+func (p tenantPublisher) Publish(ctx context.Context, message domain.OutboxMessage) error {
+	p[message.ID] = domain.TenantFromContext(ctx)
+	return nil
+}
+
+func TestOutboxRelay_RunRelaysEveryTenant(t *testing.T) {
+	outbox := tenantOutbox{"": repository.NewMemoryOutboxRepository(), "acme": repository.NewMemoryOutboxRepository()}
+	now := time.Now().UTC()
+	require.NoError(t, outbox.Add(context.Background(), domain.Event{ID: "shared", Type: domain.EventUserCreated, AggregateID: "1", OccurredAt: now}))
+	require.NoError(t, outbox.Add(domain.WithTenant(context.Background(), "acme"), domain.Event{ID: "acme", Type: domain.EventUserCreated, AggregateID: "1", OccurredAt: now}))
+
+	// The second round stops the relay
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rounds := 0
+	tenants := func(ctx context.Context) ([]string, error) {
+		rounds++
+		if rounds > 1 {
+			cancel()
+			return nil, nil
+		}
+		return []string{"", "acme"}, nil
+	}
+
+	publisher := tenantPublisher{}
+	relay := usecase.NewOutboxRelay(outbox, publisher, usecase.RelayOptions{Tenants: tenants})
+	var errs []error
+	relay.Run(ctx, time.Millisecond, func(err error) {
+		errs = append(errs, err)
+	})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, tenantPublisher{"shared": "", "acme": "acme"}, publisher)
+	for _, tenant := range []string{"", "acme"} {
+		for _, message := range outbox[tenant].Messages() {
+			assert.NotNil(t, message.SentAt, tenant)
+		}
+	}
+}
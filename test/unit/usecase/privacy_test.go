@@ -0,0 +1,183 @@
+package usecase_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeContributor holds one note per user and can be told to fail erasures
+type fakeContributor struct {
+	notes     map[string]string
+	eraseErr  error
+	erasedIDs []string
+}
+
+func (c *fakeContributor) Name() string {
+	return "notes"
+}
+
+func (c *fakeContributor) ExportPersonalData(ctx context.Context, userID string) (*domain.PersonalData, error) {
+	note, ok := c.notes[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &domain.PersonalData{
+		Data: map[string]string{"note": note},
+		Files: []domain.PersonalDataFile{{
+			Name: "note.txt",
+			Open: func(ctx context.Context) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(note)), nil
+			},
+		}},
+	}, nil
+}
+
+func (c *fakeContributor) ErasePersonalData(ctx context.Context, userID string) error {
+	if c.eraseErr != nil {
+		return c.eraseErr
+	}
+	delete(c.notes, userID)
+	c.erasedIDs = append(c.erasedIDs, userID)
+	return nil
+}
+
+func newPrivacyFixture(t *testing.T, grace time.Duration) (*usecase.PrivacyUsecase, *repository.MemoryUserRepository, *repository.MemoryPrivacyRepository, *fakeContributor) {
+	users := repository.NewMemoryUserRepository()
+	require.NoError(t, users.Store(context.Background(), &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}))
+
+	privacy := repository.NewMemoryPrivacyRepository()
+	contributor := &fakeContributor{notes: map[string]string{"1": "likes tea"}}
+
+	uc := usecase.NewPrivacyUsecase(users, privacy, usecase.PrivacyOptions{ErasureGracePeriod: grace})
+	uc.RegisterContributor(contributor)
+	return uc, users, privacy, contributor
+}
+
+func TestPrivacyUsecase_ExportPersonalData(t *testing.T) {
+	uc, _, _, _ := newPrivacyFixture(t, time.Hour)
+	ctx := context.Background()
+
+	var archive bytes.Buffer
+	require.NoError(t, uc.ExportPersonalData(ctx, "1", domain.PrivacyExportZIP, &archive))
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		contents[file.Name] = string(data)
+	}
+
+	assert.Equal(t, "likes tea", contents["files/notes/note.txt"])
+
+	var document struct {
+		UserID string                     `json:"user_id"`
+		Data   map[string]json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(contents["personal_data.json"]), &document))
+	assert.Equal(t, "1", document.UserID)
+	assert.Contains(t, string(document.Data["user"]), "alice@example.com")
+	assert.NotContains(t, string(document.Data["user"]), "secret")
+	assert.JSONEq(t, `{"note":"likes tea"}`, string(document.Data["notes"]))
+
+	err = uc.ExportPersonalData(ctx, "missing", domain.PrivacyExportJSON, io.Discard)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestPrivacyUsecase_ErasureGracePeriodAndCancellation(t *testing.T) {
+	uc, users, _, contributor := newPrivacyFixture(t, time.Hour)
+	ctx := context.Background()
+
+	request, err := uc.RequestErasure(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PrivacyRequestScheduled, request.Status)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), request.ScheduledFor, time.Minute)
+
+	_, err = uc.RequestErasure(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrErasureAlreadyRequested)
+
+	// Nothing is erased during the grace period
+	completed, err := uc.ProcessDueErasures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, completed)
+
+	cancelled, err := uc.CancelErasure(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PrivacyRequestCancelled, cancelled.Status)
+
+	_, err = uc.CancelErasure(ctx, request.ID)
+	assert.ErrorIs(t, err, domain.ErrPrivacyRequestState)
+
+	_, err = users.FindByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.Empty(t, contributor.erasedIDs)
+}
+
+func TestPrivacyUsecase_ProcessDueErasures(t *testing.T) {
+	uc, users, privacy, contributor := newPrivacyFixture(t, time.Hour)
+	ctx := context.Background()
+
+	request, err := uc.RequestErasure(ctx, "1")
+	require.NoError(t, err)
+
+	// Let the grace period run out
+	request.ScheduledFor = time.Now().Add(-time.Second)
+	require.NoError(t, privacy.StoreRequest(ctx, request))
+
+	// A failing contributor keeps the request scheduled for the next run
+	contributor.eraseErr = errors.New("notes unavailable")
+	completed, err := uc.ProcessDueErasures(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 0, completed)
+	stored, err := uc.GetRequest(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PrivacyRequestScheduled, stored.Status)
+	assert.Contains(t, stored.Error, "notes unavailable")
+
+	contributor.eraseErr = nil
+	completed, err = uc.ProcessDueErasures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+
+	stored, err = uc.GetRequest(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PrivacyRequestCompleted, stored.Status)
+	assert.Equal(t, []string{"1"}, contributor.erasedIDs)
+
+	// The user row is gone and its email is free again
+	_, err = users.FindByID(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	existing, err := users.ExistingEmails(ctx, []string{"alice@example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, existing)
+
+	records, err := uc.ErasureRecords(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, request.ID, records[0].RequestID)
+	assert.Equal(t, []string{"notes", "user"}, records[0].Contributors)
+
+	// A changed record breaks the hash chain
+	raw, err := privacy.ErasureRecords(ctx)
+	require.NoError(t, err)
+	raw[0].UserID = "2"
+	assert.ErrorIs(t, domain.VerifyErasureRecords(raw), domain.ErrErasureRecordTampered)
+}
@@ -0,0 +1,154 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps objects in memory and can be told to fail deletions
+type fakeStorage struct {
+	objects   map[string][]byte
+	deleteErr error
+}
+
+func (s *fakeStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *fakeStorage) Get(ctx context.Context, key string) (io.ReadCloser, *domain.ObjectInfo, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, nil, domain.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), &domain.ObjectInfo{Size: int64(len(data))}, nil
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, key string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *fakeStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "https://storage.test/" + key, nil
+}
+
+func newAvatarFixture(t *testing.T, options usecase.AvatarOptions) (*usecase.UserAvatarUsecase, *repository.MemoryUserRepository, *fakeStorage) {
+	users := repository.NewMemoryUserRepository()
+	require.NoError(t, users.Store(context.Background(), &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "secret"}))
+	storage := &fakeStorage{objects: make(map[string][]byte)}
+	return usecase.NewUserAvatarUsecase(users, storage, options), users, storage
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestUserAvatarUsecase_StoresImageAndThumbnail(t *testing.T) {
+	uc, users, storage := newAvatarFixture(t, usecase.AvatarOptions{ThumbnailSize: 32})
+	data := pngImage(t, 200, 100)
+
+	avatar, err := uc.UploadAvatar(context.Background(), "1", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	user, err := users.FindByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Regexp(t, `^avatars/1/[0-9a-f-]+\.png$`, user.AvatarKey)
+	assert.Regexp(t, `^avatars/1/[0-9a-f-]+_thumb\.jpg$`, user.AvatarThumbnailKey)
+	assert.Equal(t, "https://storage.test/"+user.AvatarKey, avatar.URL)
+	assert.Equal(t, "https://storage.test/"+user.AvatarThumbnailKey, avatar.ThumbnailURL)
+	assert.Equal(t, data, storage.objects[user.AvatarKey])
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(storage.objects[user.AvatarThumbnailKey]))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 32), thumbnail.Bounds())
+}
+
+func TestUserAvatarUsecase_RejectsInvalidUploads(t *testing.T) {
+	uc, users, storage := newAvatarFixture(t, usecase.AvatarOptions{MaxSize: 4096, MaxDimension: 64})
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "too large", data: bytes.Repeat([]byte{0}, 4097), err: domain.ErrFileTooLarge},
+		{name: "not an image", data: []byte("name,email\nAlice,alice@example.com\n"), err: domain.ErrInvalidImage},
+		{name: "too wide", data: pngImage(t, 65, 10), err: domain.ErrInvalidImage},
+		{name: "too tall", data: pngImage(t, 10, 65), err: domain.ErrInvalidImage},
+		{name: "truncated", data: pngImage(t, 16, 16)[:64], err: domain.ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.UploadAvatar(context.Background(), "1", bytes.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	user, err := users.FindByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Empty(t, user.AvatarKey)
+	assert.Empty(t, storage.objects)
+}
+
+func TestUserAvatarUsecase_ReplacesPreviousImages(t *testing.T) {
+	var orphans []string
+	uc, users, storage := newAvatarFixture(t, usecase.AvatarOptions{
+		OnOrphan: func(key string, err error) {
+			orphans = append(orphans, key)
+		},
+	})
+
+	_, err := uc.UploadAvatar(context.Background(), "1", bytes.NewReader(pngImage(t, 16, 16)))
+	require.NoError(t, err)
+	first, err := users.FindByID(context.Background(), "1")
+	require.NoError(t, err)
+	firstAvatar, firstThumbnail := first.AvatarKey, first.AvatarThumbnailKey
+
+	_, err = uc.UploadAvatar(context.Background(), "1", bytes.NewReader(pngImage(t, 16, 16)))
+	require.NoError(t, err)
+
+	assert.Len(t, storage.objects, 2)
+	assert.NotContains(t, storage.objects, firstAvatar)
+	assert.NotContains(t, storage.objects, firstThumbnail)
+	assert.Empty(t, orphans)
+
+	// A failed deletion does not fail the upload but is reported
+	storage.deleteErr = errors.New("storage unavailable")
+	second, err := users.FindByID(context.Background(), "1")
+	require.NoError(t, err)
+
+	_, err = uc.UploadAvatar(context.Background(), "1", bytes.NewReader(pngImage(t, 16, 16)))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{second.AvatarKey, second.AvatarThumbnailKey}, orphans)
+}
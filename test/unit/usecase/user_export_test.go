@@ -0,0 +1,130 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserExportUsecase_Export(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	exportUsecase := usecase.NewUserExportUsecase(mockRepo, usecase.ExportOptions{Dir: t.TempDir()})
+
+	filter := domain.UserFilter{Search: "doe"}
+	mockRepo.On("Iterate", mock.Anything, filter, mock.Anything).Return([]domain.User{
+		{ID: "1", Name: "John Doe", Email: "john@example.com", Version: 2},
+		{ID: "2", Name: "Jane, Doe", Email: "jane@example.com", Version: 1},
+	}, nil)
+
+	columns, err := usecase.ParseExportColumns("email, name")
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	exported, err := exportUsecase.Export(context.Background(), domain.ExportFormatCSV, filter, columns, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, exported)
+	assert.Equal(t, "email,name\njohn@example.com,John Doe\njane@example.com,\"Jane, Doe\"\n", out.String())
+	mockRepo.AssertExpectations(t)
+
+	_, err = usecase.ParseExportColumns("password")
+	assert.Error(t, err)
+}
+
+func TestUserExportUsecase_NeutralisesFormulas(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	exportUsecase := usecase.NewUserExportUsecase(mockRepo, usecase.ExportOptions{Dir: t.TempDir()})
+
+	mockRepo.On("Iterate", mock.Anything, mock.Anything, mock.Anything).Return([]domain.User{
+		{ID: "1", Name: "=HYPERLINK(\"http://evil\")", Email: "@john@example.com"},
+		{ID: "2", Name: "Jane-Doe", Email: "+jane@example.com"},
+	}, nil)
+
+	var csv bytes.Buffer
+	_, err := exportUsecase.Export(context.Background(), domain.ExportFormatCSV, domain.UserFilter{}, []string{"name", "email"}, &csv)
+	assert.NoError(t, err)
+	assert.Equal(t, "name,email\n\"'=HYPERLINK(\"\"http://evil\"\")\",'@john@example.com\nJane-Doe,'+jane@example.com\n", csv.String())
+
+	var ndjson bytes.Buffer
+	_, err = exportUsecase.Export(context.Background(), domain.ExportFormatNDJSON, domain.UserFilter{}, []string{"email"}, &ndjson)
+	assert.NoError(t, err)
+	assert.Contains(t, ndjson.String(), `"email":"@john@example.com"`, "NDJSON keeps values as they are")
+}
+
+func TestUserExportUsecase_RemovesExpiredExports(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	dir := t.TempDir()
+	exportUsecase := usecase.NewUserExportUsecase(mockRepo, usecase.ExportOptions{Dir: dir, Retention: time.Hour})
+
+	stale := filepath.Join(dir, "stale.csv")
+	recent := filepath.Join(dir, "recent.csv")
+	for _, path := range []string{stale, recent} {
+		assert.NoError(t, os.WriteFile(path, []byte("id\n"), 0o644))
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(stale, old, old))
+
+	mockRepo.On("Iterate", mock.Anything, mock.Anything, mock.Anything).Return([]domain.User{}, nil)
+	job, err := exportUsecase.StartExportJob(context.Background(), domain.ExportFormatCSV, domain.UserFilter{}, []string{"id"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		job, err := exportUsecase.GetExportJob(context.Background(), job.ID)
+		return err == nil && job.Status == domain.JobCompleted
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, recent)
+}
+
+func TestUserExportUsecase_JobKeepsTheRequestTenant(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	exportUsecase := usecase.NewUserExportUsecase(mockRepo, usecase.ExportOptions{Dir: t.TempDir()})
+
+	forAcme := mock.MatchedBy(func(ctx context.Context) bool {
+		return domain.TenantFromContext(ctx) == "acme" && ctx.Err() == nil
+	})
+	mockRepo.On("Iterate", forAcme, mock.Anything, mock.Anything).Return([]domain.User{{ID: "1"}}, nil)
+
+	// The request is over before the job runs
+	ctx, cancel := context.WithCancel(domain.WithTenant(context.Background(), "acme"))
+	job, err := exportUsecase.StartExportJob(ctx, domain.ExportFormatCSV, domain.UserFilter{}, []string{"id"})
+	cancel()
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		job, err := exportUsecase.GetExportJob(ctx, job.ID)
+		return err == nil && job.Status == domain.JobCompleted && job.Exported == 1
+	}, time.Second, 10*time.Millisecond)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserExportUsecase_OnlyTheStarterSeesTheJob(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	exportUsecase := usecase.NewUserExportUsecase(mockRepo, usecase.ExportOptions{Dir: t.TempDir()})
+	mockRepo.On("Iterate", mock.Anything, mock.Anything, mock.Anything).Return([]domain.User{}, nil)
+
+	ctx := domain.WithPrincipal(domain.WithTenant(context.Background(), "acme"), "u-1")
+	job, err := exportUsecase.StartExportJob(ctx, domain.ExportFormatCSV, domain.UserFilter{}, []string{"id"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		job, err := exportUsecase.GetExportJob(ctx, job.ID)
+		return err == nil && job.Status == domain.JobCompleted
+	}, time.Second, 10*time.Millisecond)
+
+	for name, other := range map[string]context.Context{
+		"other principal": domain.WithPrincipal(domain.WithTenant(context.Background(), "acme"), "u-2"),
+		"other tenant":    domain.WithPrincipal(domain.WithTenant(context.Background(), "globex"), "u-1"),
+		"anonymous":       context.Background(),
+	} {
+		_, err := exportUsecase.GetExportJob(other, job.ID)
+		assert.ErrorIs(t, err, domain.ErrExportJobNotFound, name)
+	}
+}
@@ -0,0 +1,111 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/usecase"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserImportUsecase_Import(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		importUsecase := usecase.NewUserImportUsecase(mockRepo, validator.New(), 10)
+
+		input := strings.Join([]string{
+			"name,email",
+			"John Doe,john@example.com",
+			"Jane Doe,jane@example.com",
+			"Duplicate,JOHN@example.com",
+			"X,not-an-email",
+		}, "\n")
+
+		mockRepo.On("ExistingEmails", mock.Anything, []string{"john@example.com", "jane@example.com"}).
+			Return(map[string]bool{"jane@example.com": true}, nil)
+		mockRepo.On("StoreBatch", mock.Anything, mock.MatchedBy(func(users []*domain.User) bool {
+			return len(users) == 1 && users[0].Email == "john@example.com" && users[0].ID != ""
+		})).Return(nil)
+
+		report, err := importUsecase.Import(context.Background(), domain.ImportFormatCSV, strings.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 2, report.Skipped)
+		assert.Equal(t, 1, report.Failed)
+		assert.Len(t, report.Rows, 4)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		importUsecase := usecase.NewUserImportUsecase(mockRepo, validator.New(), 1)
+
+		input := `{"name":"John Doe","email":"john@example.com"}
+
+{not json}
+{"name":"Jane Doe","email":"jane@example.com"}`
+
+		mockRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+		mockRepo.On("StoreBatch", mock.Anything, mock.Anything).Return(nil).Twice()
+
+		report, err := importUsecase.Import(context.Background(), domain.ImportFormatNDJSON, strings.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 3, report.Rows[1].Row)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserImportUsecase_RetriesRowsAfterDuplicateRace(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	importUsecase := usecase.NewUserImportUsecase(mockRepo, validator.New(), 10)
+
+	input := "name,email\nJohn Doe,john@example.com\nJane Doe,jane@example.com\n"
+
+	mockRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	mockRepo.On("StoreBatch", mock.Anything, mock.Anything).Return(domain.ErrDuplicateEmail)
+	mockRepo.On("Store", mock.Anything, mock.MatchedBy(func(user *domain.User) bool {
+		return user.Email == "john@example.com"
+	})).Return(nil)
+	mockRepo.On("Store", mock.Anything, mock.MatchedBy(func(user *domain.User) bool {
+		return user.Email == "jane@example.com"
+	})).Return(domain.ErrDuplicateEmail)
+
+	report, err := importUsecase.Import(context.Background(), domain.ImportFormatCSV, strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 0, report.Failed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserImportUsecase_OnlyTheStarterSeesTheJob(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	importUsecase := usecase.NewUserImportUsecase(mockRepo, validator.New(), 10)
+
+	ctx := domain.WithPrincipal(domain.WithTenant(context.Background(), "acme"), "u-1")
+	job := importUsecase.StartImportJob(ctx, domain.ImportFormatCSV, io.NopCloser(strings.NewReader("name,email\n")))
+	assert.Equal(t, "u-1", job.CreatedBy)
+
+	_, err := importUsecase.GetImportJob(ctx, job.ID)
+	assert.NoError(t, err)
+
+	for name, other := range map[string]context.Context{
+		"other principal": domain.WithPrincipal(domain.WithTenant(context.Background(), "acme"), "u-2"),
+		"other tenant":    domain.WithPrincipal(domain.WithTenant(context.Background(), "globex"), "u-1"),
+		"anonymous":       context.Background(),
+	} {
+		_, err := importUsecase.GetImportJob(other, job.ID)
+		assert.ErrorIs(t, err, domain.ErrImportJobNotFound, name)
+	}
+}
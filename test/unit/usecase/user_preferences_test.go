@@ -0,0 +1,63 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
+	"app-hexagonal/internal/usecase"
+)
+
+func TestUserPreferencesUsecase_PatchMergesDocument(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	ctx := context.Background()
+	require.NoError(t, users.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	uc := usecase.NewUserPreferencesUsecase(users, usecase.PreferencesOptions{})
+
+	user, err := uc.GetPreferences(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserPreferences{}, user.Preferences)
+
+	_, err = uc.ReplacePreferences(ctx, "1", domain.UserPreferences{
+		"theme":         "dark",
+		"language":      "en",
+		"notifications": map[string]interface{}{"email": true, "sms": true},
+	}, 0)
+	require.NoError(t, err)
+
+	user, err = uc.PatchPreferences(ctx, "1", map[string]interface{}{
+		"language":      nil,
+		"notifications": map[string]interface{}{"sms": false},
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserPreferences{
+		"theme":         "dark",
+		"notifications": map[string]interface{}{"email": true, "sms": false},
+	}, user.Preferences)
+
+	// A stale version is rejected instead of merged
+	_, err = uc.PatchPreferences(ctx, "1", map[string]interface{}{"theme": "light"}, user.Version-1)
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	_, err = uc.PatchPreferences(ctx, "missing", map[string]interface{}{}, 0)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestUserPreferencesUsecase_RejectsLargeDocuments(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	ctx := context.Background()
+	require.NoError(t, users.Store(ctx, &domain.User{ID: "1", Name: "Alice", Email: "alice@example.com"}))
+	uc := usecase.NewUserPreferencesUsecase(users, usecase.PreferencesOptions{MaxSize: 64})
+
+	_, err := uc.PatchPreferences(ctx, "1", map[string]interface{}{"note": strings.Repeat("x", 64)}, 0)
+	assert.ErrorIs(t, err, domain.ErrPreferencesTooLarge)
+
+	user, err := uc.GetPreferences(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.Version)
+}
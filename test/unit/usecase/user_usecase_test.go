@@ -1,10 +1,14 @@
 package usecase_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"app-hexagonal/internal/domain"
+	"app-hexagonal/internal/repository"
 	"app-hexagonal/internal/usecase"
 
 	"github.com/stretchr/testify/assert"
@@ -16,8 +20,8 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) FindByID(id string) (*domain.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -25,8 +29,8 @@ func (m *MockUserRepository) FindByID(id string) (*domain.User, error) {
 	return user, args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*domain.User, error) {
-	args := m.Called(email)
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -34,24 +38,63 @@ func (m *MockUserRepository) FindByEmail(email string) (*domain.User, error) {
 	return user, args.Error(1)
 }
 
-func (m *MockUserRepository) Store(user *domain.User) error {
-	args := m.Called(user)
+func (m *MockUserRepository) List(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	list := args.Get(0).(*domain.UserList)
+	return list, args.Error(1)
+}
+
+func (m *MockUserRepository) Iterate(ctx context.Context, filter domain.UserFilter, fn func(user *domain.User) error) error {
+	args := m.Called(ctx, filter, fn)
+	if users, ok := args.Get(0).([]domain.User); ok {
+		for i := range users {
+			if err := fn(&users[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockUserRepository) Store(ctx context.Context, user *domain.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) StoreBatch(ctx context.Context, users []*domain.User) error {
+	args := m.Called(ctx, users)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Update(user *domain.User) error {
-	args := m.Called(user)
+func (m *MockUserRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	args := m.Called(ctx, emails)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id string) error {
-	args := m.Called(id)
+func (m *MockUserRepository) Purge(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
 func TestUserUsecase_GetUserByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userUsecase := usecase.NewUserUsecase(mockRepo)
+	userUsecase := usecase.NewUserUsecase(mockRepo, usecase.EventOptions{}, usecase.UserOptions{})
 
 	t.Run("Success", func(t *testing.T) {
 		expectedUser := &domain.User{
@@ -60,9 +103,9 @@ func TestUserUsecase_GetUserByID(t *testing.T) {
 			Email: "john@example.com",
 		}
 
-		mockRepo.On("FindByID", "1").Return(expectedUser, nil)
+		mockRepo.On("FindByID", mock.Anything, "1").Return(expectedUser, nil)
 
-		user, err := userUsecase.GetUserByID("1")
+		user, err := userUsecase.GetUserByID(context.Background(), "1")
 
 		assert.NoError(t, err)
 		assert.Equal(t, expectedUser, user)
@@ -70,12 +113,124 @@ func TestUserUsecase_GetUserByID(t *testing.T) {
 	})
 
 	t.Run("NotFound", func(t *testing.T) {
-		mockRepo.On("FindByID", "999").Return((*domain.User)(nil), errors.New("user not found"))
+		mockRepo.On("FindByID", mock.Anything, "999").Return((*domain.User)(nil), errors.New("user not found"))
 
-		user, err := userUsecase.GetUserByID("999")
+		user, err := userUsecase.GetUserByID(context.Background(), "999")
 
 		assert.Error(t, err)
 		assert.Nil(t, user)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserUsecase_CreateUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userUsecase := usecase.NewUserUsecase(mockRepo, usecase.EventOptions{}, usecase.UserOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := userUsecase.WatchUsers(ctx)
+
+	user := &domain.User{Name: "John Doe", Email: "john@example.com"}
+	mockRepo.On("Store", mock.Anything, user).Return(nil)
+
+	err := userUsecase.CreateUser(context.Background(), user)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, user.ID)
+	mockRepo.AssertExpectations(t)
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, domain.UserChangeCreated, change.Type)
+		assert.Equal(t, user.ID, change.User.ID)
+	case <-time.After(time.Second):
+		t.Fatal("expected a created change to be published")
+	}
+}
+
+func TestUserUsecase_WatchUsersClosesSlowWatchers(t *testing.T) {
+	repo := repository.NewMemoryUserRepository()
+	userUsecase := usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := userUsecase.WatchUsers(ctx)
+
+	for i := 0; i < 65; i++ {
+		id := fmt.Sprintf("u-%d", i)
+		assert.NoError(t, userUsecase.CreateUser(context.Background(), &domain.User{ID: id, Name: id, Email: id + "@example.com"}))
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, 64, received, "the buffered changes are delivered before the channel closes")
+	assert.NoError(t, ctx.Err(), "the watcher was closed while still watching")
+
+	live := userUsecase.WatchUsers(ctx)
+	assert.NoError(t, userUsecase.DeleteUser(context.Background(), "u-0"))
+	select {
+	case change := <-live:
+		assert.Equal(t, domain.UserChangeDeleted, change.Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected other watchers to keep receiving changes")
+	}
+}
+
+func TestUserUsecase_WatchUsersOnlySeesItsTenant(t *testing.T) {
+	repo := repository.NewMemoryUserRepository()
+	userUsecase := usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	acme := userUsecase.WatchUsers(domain.WithTenant(ctx, "acme"))
+	shared := userUsecase.WatchUsers(ctx)
+
+	assert.NoError(t, userUsecase.CreateUser(domain.WithTenant(context.Background(), "globex"), &domain.User{ID: "g-1", Name: "Globex", Email: "g@example.com"}))
+	assert.NoError(t, userUsecase.CreateUser(domain.WithTenant(context.Background(), "acme"), &domain.User{ID: "a-1", Name: "Acme", Email: "a@example.com"}))
+
+	select {
+	case change := <-acme:
+		assert.Equal(t, "a-1", change.User.ID, "changes of other tenants are not delivered")
+		assert.Equal(t, "acme", change.Tenant)
+	case <-time.After(time.Second):
+		t.Fatal("expected the change of the watched tenant to be published")
+	}
+	select {
+	case change := <-shared:
+		t.Fatalf("the shared schema watcher received a change of %q", change.Tenant)
+	default:
+	}
+}
+
+func TestUserUsecase_ListUsersByCursor(t *testing.T) {
+	repo := repository.NewMemoryUserRepository()
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, repo.Store(ctx, &domain.User{ID: id, Name: id, Email: id + "@example.com"}))
+	}
+	userUsecase := usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{CursorKey: []byte("secret")})
+
+	first, err := userUsecase.ListUsersByCursor(ctx, domain.UserFilter{PageSize: 2}, "")
+	assert.NoError(t, err)
+	assert.Len(t, first.Users, 2)
+	assert.NotEmpty(t, first.NextCursor)
+	assert.Empty(t, first.PreviousCursor)
+
+	second, err := userUsecase.ListUsersByCursor(ctx, domain.UserFilter{PageSize: 2}, first.NextCursor)
+	assert.NoError(t, err)
+	assert.Len(t, second.Users, 1)
+	assert.Empty(t, second.NextCursor)
+	assert.NotEmpty(t, second.PreviousCursor)
+
+	back, err := userUsecase.ListUsersByCursor(ctx, domain.UserFilter{PageSize: 2}, second.PreviousCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Users, back.Users)
+
+	// Cursors signed with another key are rejected
+	other := usecase.NewUserUsecase(repo, usecase.EventOptions{}, usecase.UserOptions{CursorKey: []byte("other")})
+	_, err = other.ListUsersByCursor(ctx, domain.UserFilter{PageSize: 2}, first.NextCursor)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}